@@ -0,0 +1,86 @@
+// import-postal は日本郵便の郵便番号データ（KEN_ALL.CSV）を postal_codes テーブルに取り込む。
+// 既存のデータは全件入れ替える。ダウンロードした ken_all.zip をそのまま指定してもよい。
+//
+//	go run ./cmd/import-postal -file ./KEN_ALL.CSV
+//	go run ./cmd/import-postal -file ./ken_all.zip
+//
+// 接続先はサーバーと同じ環境変数（DB_HOST など）で指定する。
+package main
+
+import (
+    "archive/zip"
+    "flag"
+    "fmt"
+    "io"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "salon-app/backend/internal/db"
+    "salon-app/backend/internal/model"
+    "salon-app/backend/internal/postal"
+)
+
+func main() {
+    file := flag.String("file", "", "KEN_ALL.CSV または ken_all.zip のパス")
+    flag.Parse()
+    if *file == "" {
+        flag.Usage()
+        os.Exit(2)
+    }
+
+    r, closeFn, err := openKenAll(*file)
+    if err != nil {
+        fail(err)
+    }
+    rows, err := postal.ParseKenAll(r)
+    closeFn()
+    if err != nil {
+        fail(err)
+    }
+    if len(rows) == 0 {
+        fail(fmt.Errorf("%s に郵便番号のデータがありません", *file))
+    }
+
+    db.InitDB()
+    if err := db.DB.AutoMigrate(&model.PostalCode{}); err != nil {
+        fail(err)
+    }
+    if err := postal.Replace(db.DB, rows); err != nil {
+        fail(err)
+    }
+    fmt.Printf("郵便番号のデータを %d 件取り込みました\n", len(rows))
+}
+
+// openKenAll は CSV を開く。zip なら中の最初の .csv を開く
+func openKenAll(path string) (io.Reader, func(), error) {
+    if !strings.EqualFold(filepath.Ext(path), ".zip") {
+        f, err := os.Open(path)
+        if err != nil {
+            return nil, nil, err
+        }
+        return f, func() { f.Close() }, nil
+    }
+
+    zr, err := zip.OpenReader(path)
+    if err != nil {
+        return nil, nil, err
+    }
+    for _, zf := range zr.File {
+        if strings.EqualFold(filepath.Ext(zf.Name), ".csv") {
+            f, err := zf.Open()
+            if err != nil {
+                zr.Close()
+                return nil, nil, err
+            }
+            return f, func() { f.Close(); zr.Close() }, nil
+        }
+    }
+    zr.Close()
+    return nil, nil, fmt.Errorf("%s に CSV ファイルがありません", path)
+}
+
+func fail(err error) {
+    fmt.Fprintln(os.Stderr, "郵便番号のデータの取り込みに失敗しました:", err)
+    os.Exit(1)
+}
@@ -6,6 +6,8 @@
 package main // ← 必ず1行目！
 
 import (
+    "os"
+    "strings"
     "time"
 
     "github.com/gin-contrib/cors"
@@ -16,22 +18,46 @@ import (
     // 自前パッケージはすべて salon-app/backend に統一
     "salon-app/backend/internal/db"
     "salon-app/backend/internal/handler"
+    "salon-app/backend/internal/job"
     "salon-app/backend/internal/middleware"
     "salon-app/backend/internal/model"
+    "salon-app/backend/internal/permission"
     _ "salon-app/backend/docs"
 )
 func main() {
     db.InitDB()
-    db.DB.AutoMigrate(&model.User{}, &model.Store{}, &model.Customer{}, &model.Course{}, &model.Visit{}, &model.Ticket{})
+    db.DB.AutoMigrate(&model.User{}, &model.Store{}, &model.Customer{}, &model.Course{}, &model.Visit{}, &model.Ticket{}, &model.TicketAdjustment{}, &model.TicketTransfer{},
+        &model.RefreshToken{}, &model.RevokedToken{}, &model.LoginThrottle{}, &model.RecoveryCode{},
+        &model.Reservation{}, &model.StoreHoliday{}, &model.StaffShift{},
+        &model.Sale{}, &model.SaleLine{}, &model.SalePayment{}, &model.SaleTax{},
+        &model.RegisterSession{}, &model.RegisterMovement{}, &model.PostalCode{})
+    // 全回数を返金・譲渡したチケット（規定回数0）が消化中のまま残っていたものを使い切りにそろえる
+    db.DB.Model(&model.Ticket{}).Where("current_count >= total_count AND is_completed = ?", false).Update("is_completed", true)
+    if err := db.MigrateCustomerSearch(); err != nil { // 顧客検索のインデックス（pg_trgm を作成できなければ LIKE のみで起動）
+        panic("顧客検索のインデックスの作成に失敗しました: " + err.Error())
+    }
+
+    job.StartTicketExpiry(time.Hour) // 有効期限を過ぎたチケットの期限切れ処理
+
+    r := newRouter()
+    r.Run(":8080")
+}
 
+// newRouter はミドルウェアとすべてのルートを登録したルーターを返す（DBの初期化は呼び出し側で行う）
+func newRouter() *gin.Engine {
     r := gin.Default()
+    // X-Forwarded-For を信用するのは TRUSTED_PROXIES のプロキシから来たリクエストだけ。
+    // 未設定なら接続元のアドレスをクライアントIPとする（ログインのロック・レート制限のキーを偽装させない）
+    if err := r.SetTrustedProxies(trustedProxies()); err != nil {
+        panic("TRUSTED_PROXIES が正しくありません: " + err.Error())
+    }
 
     r.Use(cors.New(cors.Config{
         AllowOrigins:     []string{"*"},
         AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
         // Headersに "Authorization" と "X-Requested-With" を追加
-        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-        ExposeHeaders:    []string{"Content-Length"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Store-ID", "Idempotency-Key"},
+        ExposeHeaders:    []string{"Content-Length", "X-Next-Cursor", "X-Total-Count"},
         MaxAge:           12 * time.Hour,
     }))
 
@@ -41,33 +67,119 @@ func main() {
     {
         v0.GET("/ping", handler.PingHandler)
         v0.POST("/login", handler.LoginHandler)   // ログイン
+        v0.POST("/login/2fa", handler.LoginMFAHandler) // 二段階認証コードの確認
+        v0.POST("/refresh", handler.RefreshHandler) // トークン再発行
+    }
+
+    // 二段階認証の設定（必須ロールで未登録の場合は、ログイン時の登録用トークンでも呼べる）
+    mfa := r.Group("/api/v1/2fa")
+    mfa.Use(middleware.EnrollmentAuthRequired())
+    {
+        mfa.POST("/setup", handler.SetupMFAHandler)
+        mfa.POST("/enable", handler.EnableMFAHandler)
+        mfa.POST("/disable", handler.DisableMFAHandler)
+        mfa.POST("/recovery-codes", handler.RegenerateRecoveryCodesHandler)
+    }
+
+    // オンライン予約（認証なし）。IPごとにリクエスト数を制限し、予約の申し込みはさらに厳しくする
+    public := r.Group("/api/public")
+    public.Use(middleware.RateLimit(60, 30))
+    {
+        bookingLimit := middleware.RateLimit(5, 3)
+        public.GET("/stores/:store_id/courses", handler.GetPublicCoursesHandler) // 予約可能なコース一覧
+        public.GET("/stores/:store_id/availability", handler.GetPublicAvailabilityHandler) // 空き枠
+        public.POST("/stores/:store_id/bookings", bookingLimit, handler.CreatePublicBookingHandler) // 予約申し込み
+        public.GET("/bookings/:token", handler.GetPublicBookingHandler) // 予約確認
+        public.POST("/bookings/:token/cancel", bookingLimit, handler.CancelPublicBookingHandler) // キャンセル
+        public.POST("/bookings/:token/reschedule", bookingLimit, handler.ReschedulePublicBookingHandler) // 日時変更
     }
 
     v1 := r.Group("/api/v1")
-    v1.Use(middleware.AuthRequired())
+    v1.Use(middleware.AuthRequired(), middleware.TenantScope()) // 認証 → 店舗スコープの決定
+    // 各ルートは必要な権限を RequirePermission で宣言する（ロールと権限の対応は internal/permission）
     {
-        v1.GET("/store", handler.GetStoreHandler)//店舗一覧
-        v1.GET("/customer", handler.GetCustomerHandler)//顧客一覧
-        v1.GET("/courses", handler.GetCourseHandler)//コース一覧
-        v1.GET("/ticket", handler.GetTicketHandler)//チケット一覧
-        v1.GET("/visit", handler.GetVisitHandler)//来店履歴一覧
-        v1.GET("/users", handler.GetUserListHandler)//スタッフ一覧
-        v1.POST("/signup", handler.SignUpHandler) // 新規登録
-        v1.POST("/store-registration", handler.StoreRegistrationHandler) // 店舗登録
-        v1.POST("/course-registration", handler.CourseRegistrationHandler) // コース登録
-        v1.POST("/visit-registration", handler.VisitRegistrationHandler) // 来店登録
-        v1.POST("/customer-registration", handler.CustomerRegistrationHandler) // 顧客登録
-        v1.POST("/customer-search", handler.GetCustomerSearchHandler)//顧客検索(苗字：ひらがな)
-        v1.PUT("/store/:id", handler.UpdateStoreHandler)//店舗更新
-        v1.PUT("/users/:id", handler.UpdateUserHandler)//スタッフ更新
-        v1.PUT("/customer/:id", handler.UpdateCustomerHandler)//顧客更新
-        v1.PUT("/visit/:id", handler.UpdateVisitHandler)//来店履歴更新
-        v1.PUT("/course/:id", handler.UpdateCourseHandler)//コース更新
-        v1.PUT("/ticket/:id", handler.UpdateTicketHandler)//チケット更新
-        v1.DELETE("/course/:id", handler.DeleteCourseHandler)//コース削除
-        v1.DELETE("/customer/:id", handler.DeleteCustomerHandler)//顧客削除
+        v1.POST("/logout", handler.LogoutHandler) // ログアウト（ログイン済みなら誰でも可）
+        v1.GET("/store", middleware.RequirePermission(permission.StoreRead), handler.GetStoreHandler)//店舗一覧
+        v1.GET("/customer", middleware.RequirePermission(permission.CustomerRead), handler.GetCustomerHandler)//顧客一覧
+        v1.GET("/courses", middleware.RequirePermission(permission.CourseRead), handler.GetCourseHandler)//コース一覧
+        v1.GET("/ticket", middleware.RequirePermission(permission.TicketRead), handler.GetTicketHandler)//チケット一覧
+        v1.GET("/ticket/:id/history", middleware.RequirePermission(permission.TicketRead), handler.GetTicketHistoryHandler)//チケットの消化履歴
+        v1.POST("/tickets", middleware.RequirePermission(permission.TicketSell), handler.CreateTicketHandler)//回数券の購入
+        v1.GET("/tickets/expiring", middleware.RequirePermission(permission.TicketRead), handler.GetExpiringTicketsHandler)//期限切れ間近のチケット
+        v1.GET("/visit", middleware.RequirePermission(permission.VisitRead), handler.GetVisitHandler)//来店履歴一覧
+        v1.GET("/users", middleware.RequirePermission(permission.UserRead), handler.GetUserListHandler)//スタッフ一覧
+        v1.POST("/signup", middleware.RequirePermission(permission.UserManage), handler.SignUpHandler) // 新規登録
+        v1.POST("/store-registration", middleware.RequirePermission(permission.StoreManage), handler.StoreRegistrationHandler) // 店舗登録
+        v1.POST("/course-registration", middleware.RequirePermission(permission.CourseWrite), handler.CourseRegistrationHandler) // コース登録
+        v1.POST("/visit-registration", middleware.RequirePermission(permission.VisitWrite), handler.VisitRegistrationHandler) // 来店登録
+        v1.POST("/customer-registration", middleware.RequirePermission(permission.CustomerWrite), handler.CustomerRegistrationHandler) // 顧客登録
+        v1.GET("/customer-search", middleware.RequirePermission(permission.CustomerRead), handler.GetCustomerSearchHandler)//顧客検索（氏名・フリガナ・電話番号・メール）
+        v1.POST("/customer-search", middleware.RequirePermission(permission.CustomerRead), handler.GetCustomerSearchHandler)//顧客検索（本文で検索語を渡す旧形式）
+        v1.GET("/postal/:zip", middleware.RequirePermission(permission.CustomerRead), handler.GetPostalCodeHandler)//郵便番号から住所を検索
+        v1.PUT("/store/:id", middleware.RequirePermission(permission.StoreManage), handler.UpdateStoreHandler)//店舗更新
+        v1.PUT("/users/:id", middleware.RequirePermission(permission.UserManage), handler.UpdateUserHandler)//スタッフ更新
+        v1.GET("/login-locks", middleware.RequirePermission(permission.UserSecurity), handler.GetLoginLocksHandler)//ログインロック一覧
+        v1.POST("/login-locks/unlock", middleware.RequirePermission(permission.UserSecurity), handler.UnlockLoginHandler)//ログインロック解除
+        v1.POST("/users/:id/2fa/reset", middleware.RequirePermission(permission.UserSecurity), handler.ResetUserMFAHandler)//二段階認証リセット
+        v1.POST("/users/:id/revoke-sessions", middleware.RequirePermission(permission.UserSecurity), handler.RevokeUserSessionsHandler)//全セッション無効化
+        v1.PUT("/customer/:id", middleware.RequirePermission(permission.CustomerWrite), handler.UpdateCustomerHandler)//顧客更新
+        v1.PUT("/visit/:id", middleware.RequirePermission(permission.VisitWrite), handler.UpdateVisitHandler)//来店履歴更新
+        v1.POST("/visit/:id/void", middleware.RequirePermission(permission.VisitWrite), handler.VoidVisitHandler)//来店取消
+        v1.PUT("/course/:id", middleware.RequirePermission(permission.CourseWrite), handler.UpdateCourseHandler)//コース更新
+        v1.PUT("/ticket/:id", middleware.RequirePermission(permission.TicketWrite), handler.UpdateTicketHandler)//チケット更新
+        v1.POST("/ticket/:id/adjustments", middleware.RequirePermission(permission.TicketWrite), handler.AdjustTicketHandler)//チケット調整
+        v1.GET("/ticket/:id/adjustments", middleware.RequirePermission(permission.TicketRead), handler.GetTicketAdjustmentsHandler)//チケット調整履歴
+        v1.POST("/ticket/:id/transfer", middleware.RequirePermission(permission.TicketWrite), handler.TransferTicketHandler)//チケット譲渡
+        v1.POST("/ticket/:id/members", middleware.RequirePermission(permission.TicketWrite), handler.AddTicketMemberHandler)//チケット共有メンバー追加
+        v1.DELETE("/ticket/:id/members/:customer_id", middleware.RequirePermission(permission.TicketWrite), handler.RemoveTicketMemberHandler)//チケット共有メンバー削除
+        v1.GET("/customer/:id/history", middleware.RequirePermission(permission.CustomerRead), handler.GetCustomerHistoryHandler)//顧客の履歴
+        v1.GET("/sales", middleware.RequirePermission(permission.SaleRead), handler.GetSaleListHandler)//売上一覧
+        v1.GET("/sales/:id", middleware.RequirePermission(permission.SaleRead), handler.GetSaleHandler)//売上詳細
+        v1.GET("/sales/:id/receipt", middleware.RequirePermission(permission.SaleRead), handler.GetSaleReceiptHandler)//領収書
+        v1.GET("/sales/:id/receipt.pdf", middleware.RequirePermission(permission.SaleRead), handler.GetSaleReceiptPDFHandler)//領収書PDF
+        v1.POST("/sales", middleware.RequirePermission(permission.SaleWrite), handler.CreateSaleHandler)//会計
+        v1.POST("/sales/:id/void", middleware.RequirePermission(permission.SaleVoid), handler.VoidSaleHandler)//売上取消
+        v1.GET("/register-sessions", middleware.RequirePermission(permission.RegisterRead), handler.GetRegisterSessionListHandler)//レジセッション一覧
+        v1.GET("/register-sessions/:id", middleware.RequirePermission(permission.RegisterRead), handler.GetRegisterSessionHandler)//締めレポート
+        v1.POST("/register-sessions", middleware.RequirePermission(permission.RegisterOperate), handler.OpenRegisterHandler)//レジ開局
+        v1.POST("/register-sessions/:id/movements", middleware.RequirePermission(permission.RegisterOperate), handler.AddRegisterMovementHandler)//レジ入出金
+        v1.POST("/register-sessions/:id/close", middleware.RequirePermission(permission.RegisterClose), handler.CloseRegisterHandler)//レジ締め
+        v1.GET("/analytics/revenue", middleware.RequirePermission(permission.AnalyticsRead), handler.GetRevenueAnalyticsHandler)//売上・来店数の推移
+        v1.GET("/analytics/customers", middleware.RequirePermission(permission.AnalyticsRead), handler.GetCustomerMixAnalyticsHandler)//新規・リピーター
+        v1.GET("/analytics/courses", middleware.RequirePermission(permission.AnalyticsRead), handler.GetCoursePopularityAnalyticsHandler)//コース別の人気
+        v1.GET("/analytics/staff", middleware.RequirePermission(permission.AnalyticsRead), handler.GetStaffProductivityAnalyticsHandler)//スタッフ別の実績
+        v1.GET("/analytics/tickets", middleware.RequirePermission(permission.AnalyticsRead), handler.GetTicketSellThroughAnalyticsHandler)//回数券の消化率
+        v1.GET("/analytics/liability", middleware.RequirePermission(permission.AnalyticsRead), handler.GetTicketLiabilityAnalyticsHandler)//回数券の未消化残高
+        v1.GET("/analytics/rfm", middleware.RequirePermission(permission.AnalyticsRead), handler.GetRFMAnalyticsHandler)//顧客の離反分析
+        v1.GET("/analytics/overdue", middleware.RequirePermission(permission.AnalyticsRead), handler.GetOverdueCustomersHandler)//来店遅れの顧客
+        v1.GET("/reservation", middleware.RequirePermission(permission.ReservationRead), handler.GetReservationListHandler)//予約一覧
+        v1.GET("/reservation/:id", middleware.RequirePermission(permission.ReservationRead), handler.GetReservationHandler)//予約詳細
+        v1.POST("/reservation", middleware.RequirePermission(permission.ReservationWrite), handler.CreateReservationHandler)//予約登録
+        v1.PUT("/reservation/:id", middleware.RequirePermission(permission.ReservationWrite), handler.UpdateReservationHandler)//予約変更
+        v1.PUT("/reservation/:id/status", middleware.RequirePermission(permission.ReservationWrite), handler.UpdateReservationStatusHandler)//予約ステータス変更
+        v1.GET("/availability", middleware.RequirePermission(permission.ReservationRead), handler.GetAvailabilityHandler)//空き枠検索
+        v1.PUT("/store/:id/business-hours", middleware.RequirePermission(permission.ScheduleManage), handler.UpdateBusinessHoursHandler)//営業時間更新
+        v1.GET("/store/:id/holidays", middleware.RequirePermission(permission.ScheduleRead), handler.GetStoreHolidaysHandler)//臨時休業日一覧
+        v1.POST("/store/:id/holidays", middleware.RequirePermission(permission.ScheduleManage), handler.CreateStoreHolidayHandler)//臨時休業日登録
+        v1.DELETE("/store/:id/holidays/:holiday_id", middleware.RequirePermission(permission.ScheduleManage), handler.DeleteStoreHolidayHandler)//臨時休業日削除
+        v1.GET("/shift", middleware.RequirePermission(permission.ScheduleRead), handler.GetShiftListHandler)//シフト一覧
+        v1.POST("/shift", middleware.RequirePermission(permission.ScheduleManage), handler.CreateShiftHandler)//シフト登録
+        v1.DELETE("/shift/:id", middleware.RequirePermission(permission.ScheduleManage), handler.DeleteShiftHandler)//シフト削除
+        v1.DELETE("/course/:id", middleware.RequirePermission(permission.CourseDelete), handler.DeleteCourseHandler)//コース削除
+        v1.DELETE("/customer/:id", middleware.RequirePermission(permission.CustomerDelete), handler.DeleteCustomerHandler)//顧客削除
     }
 
     r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-    r.Run(":8080")
-}
\ No newline at end of file
+    return r
+}
+
+// trustedProxies は環境変数 TRUSTED_PROXIES（カンマ区切りのIPアドレスまたはCIDR）を返す。未設定なら nil（どのプロキシも信用しない）
+func trustedProxies() []string {
+    var proxies []string
+    for _, p := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+        if p = strings.TrimSpace(p); p != "" {
+            proxies = append(proxies, p)
+        }
+    }
+    return proxies
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/permission"
+	"salon-app/backend/internal/utils"
+)
+
+// routeAuth はルートの認証の種類
+type routeAuth int
+
+const (
+	authNone       routeAuth = iota // 認証なし（/api/v0, /api/public）
+	authLoggedIn                    // ログインしていれば誰でも（権限の指定なし）
+	authPermission                  // RequirePermission で権限を要求する
+)
+
+// routeCase はルーターに登録したルートと必要な権限。ルートを追加したらここにも追加する
+// （TestRouteTableCoversRouter で漏れを検出する）。
+type routeCase struct {
+	method string
+	path   string
+	auth   routeAuth
+	perm   permission.Permission
+}
+
+var routeTable = []routeCase{
+	{"GET", "/api/v0/ping", authNone, ""},
+	{"POST", "/api/v0/login", authNone, ""},
+	{"POST", "/api/v0/login/2fa", authNone, ""},
+	{"POST", "/api/v0/refresh", authNone, ""},
+
+	{"POST", "/api/v1/2fa/setup", authLoggedIn, ""},
+	{"POST", "/api/v1/2fa/enable", authLoggedIn, ""},
+	{"POST", "/api/v1/2fa/disable", authLoggedIn, ""},
+	{"POST", "/api/v1/2fa/recovery-codes", authLoggedIn, ""},
+
+	{"GET", "/api/public/stores/:store_id/courses", authNone, ""},
+	{"GET", "/api/public/stores/:store_id/availability", authNone, ""},
+	{"POST", "/api/public/stores/:store_id/bookings", authNone, ""},
+	{"GET", "/api/public/bookings/:token", authNone, ""},
+	{"POST", "/api/public/bookings/:token/cancel", authNone, ""},
+	{"POST", "/api/public/bookings/:token/reschedule", authNone, ""},
+
+	{"POST", "/api/v1/logout", authLoggedIn, ""},
+	{"GET", "/api/v1/store", authPermission, permission.StoreRead},
+	{"GET", "/api/v1/customer", authPermission, permission.CustomerRead},
+	{"GET", "/api/v1/courses", authPermission, permission.CourseRead},
+	{"GET", "/api/v1/ticket", authPermission, permission.TicketRead},
+	{"GET", "/api/v1/ticket/:id/history", authPermission, permission.TicketRead},
+	{"POST", "/api/v1/tickets", authPermission, permission.TicketSell},
+	{"GET", "/api/v1/tickets/expiring", authPermission, permission.TicketRead},
+	{"GET", "/api/v1/visit", authPermission, permission.VisitRead},
+	{"GET", "/api/v1/users", authPermission, permission.UserRead},
+	{"POST", "/api/v1/signup", authPermission, permission.UserManage},
+	{"POST", "/api/v1/store-registration", authPermission, permission.StoreManage},
+	{"POST", "/api/v1/course-registration", authPermission, permission.CourseWrite},
+	{"POST", "/api/v1/visit-registration", authPermission, permission.VisitWrite},
+	{"POST", "/api/v1/customer-registration", authPermission, permission.CustomerWrite},
+	{"GET", "/api/v1/customer-search", authPermission, permission.CustomerRead},
+	{"POST", "/api/v1/customer-search", authPermission, permission.CustomerRead},
+	{"GET", "/api/v1/postal/:zip", authPermission, permission.CustomerRead},
+	{"PUT", "/api/v1/store/:id", authPermission, permission.StoreManage},
+	{"PUT", "/api/v1/users/:id", authPermission, permission.UserManage},
+	{"GET", "/api/v1/login-locks", authPermission, permission.UserSecurity},
+	{"POST", "/api/v1/login-locks/unlock", authPermission, permission.UserSecurity},
+	{"POST", "/api/v1/users/:id/2fa/reset", authPermission, permission.UserSecurity},
+	{"POST", "/api/v1/users/:id/revoke-sessions", authPermission, permission.UserSecurity},
+	{"PUT", "/api/v1/customer/:id", authPermission, permission.CustomerWrite},
+	{"PUT", "/api/v1/visit/:id", authPermission, permission.VisitWrite},
+	{"POST", "/api/v1/visit/:id/void", authPermission, permission.VisitWrite},
+	{"PUT", "/api/v1/course/:id", authPermission, permission.CourseWrite},
+	{"PUT", "/api/v1/ticket/:id", authPermission, permission.TicketWrite},
+	{"POST", "/api/v1/ticket/:id/adjustments", authPermission, permission.TicketWrite},
+	{"GET", "/api/v1/ticket/:id/adjustments", authPermission, permission.TicketRead},
+	{"POST", "/api/v1/ticket/:id/transfer", authPermission, permission.TicketWrite},
+	{"POST", "/api/v1/ticket/:id/members", authPermission, permission.TicketWrite},
+	{"DELETE", "/api/v1/ticket/:id/members/:customer_id", authPermission, permission.TicketWrite},
+	{"GET", "/api/v1/customer/:id/history", authPermission, permission.CustomerRead},
+	{"GET", "/api/v1/sales", authPermission, permission.SaleRead},
+	{"GET", "/api/v1/sales/:id", authPermission, permission.SaleRead},
+	{"GET", "/api/v1/sales/:id/receipt", authPermission, permission.SaleRead},
+	{"GET", "/api/v1/sales/:id/receipt.pdf", authPermission, permission.SaleRead},
+	{"POST", "/api/v1/sales", authPermission, permission.SaleWrite},
+	{"POST", "/api/v1/sales/:id/void", authPermission, permission.SaleVoid},
+	{"GET", "/api/v1/register-sessions", authPermission, permission.RegisterRead},
+	{"GET", "/api/v1/register-sessions/:id", authPermission, permission.RegisterRead},
+	{"POST", "/api/v1/register-sessions", authPermission, permission.RegisterOperate},
+	{"POST", "/api/v1/register-sessions/:id/movements", authPermission, permission.RegisterOperate},
+	{"POST", "/api/v1/register-sessions/:id/close", authPermission, permission.RegisterClose},
+	{"GET", "/api/v1/analytics/revenue", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/analytics/customers", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/analytics/courses", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/analytics/staff", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/analytics/tickets", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/analytics/liability", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/analytics/rfm", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/analytics/overdue", authPermission, permission.AnalyticsRead},
+	{"GET", "/api/v1/reservation", authPermission, permission.ReservationRead},
+	{"GET", "/api/v1/reservation/:id", authPermission, permission.ReservationRead},
+	{"POST", "/api/v1/reservation", authPermission, permission.ReservationWrite},
+	{"PUT", "/api/v1/reservation/:id", authPermission, permission.ReservationWrite},
+	{"PUT", "/api/v1/reservation/:id/status", authPermission, permission.ReservationWrite},
+	{"GET", "/api/v1/availability", authPermission, permission.ReservationRead},
+	{"PUT", "/api/v1/store/:id/business-hours", authPermission, permission.ScheduleManage},
+	{"GET", "/api/v1/store/:id/holidays", authPermission, permission.ScheduleRead},
+	{"POST", "/api/v1/store/:id/holidays", authPermission, permission.ScheduleManage},
+	{"DELETE", "/api/v1/store/:id/holidays/:holiday_id", authPermission, permission.ScheduleManage},
+	{"GET", "/api/v1/shift", authPermission, permission.ScheduleRead},
+	{"POST", "/api/v1/shift", authPermission, permission.ScheduleManage},
+	{"DELETE", "/api/v1/shift/:id", authPermission, permission.ScheduleManage},
+	{"DELETE", "/api/v1/course/:id", authPermission, permission.CourseDelete},
+	{"DELETE", "/api/v1/customer/:id", authPermission, permission.CustomerDelete},
+}
+
+var roles = []string{model.RoleAdmin, model.RoleManager, model.RoleStaff}
+
+// paramPattern はルートのパスパラメータ（:id など）
+var paramPattern = regexp.MustCompile(`:[a-z_]+`)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+// TestRouteTableCoversRouter はルーターのすべてのルートが routeTable にあり、routeTable のルートがすべて登録されていることを確認する
+func TestRouteTableCoversRouter(t *testing.T) {
+	registered := map[string]bool{}
+	for _, r := range newRouter().Routes() {
+		if r.Path == "/swagger/*any" {
+			continue
+		}
+		registered[r.Method+" "+r.Path] = true
+	}
+	listed := map[string]bool{}
+	for _, rc := range routeTable {
+		key := rc.method + " " + rc.path
+		if listed[key] {
+			t.Errorf("routeTable に重複があります: %s", key)
+		}
+		listed[key] = true
+		if !registered[key] {
+			t.Errorf("routeTable のルートが登録されていません: %s", key)
+		}
+	}
+	for key := range registered {
+		if !listed[key] {
+			t.Errorf("ルートが routeTable にありません（必要な権限を追加してください）: %s", key)
+		}
+	}
+}
+
+// TestRoutePermissions はロール × ルートごとに、権限のないロールには共通の 403 が返り、
+// 権限のあるロールは権限のチェックを通ることを実際のルーターで確認する。
+// ハンドラーのDBアクセスは sqlmock で失敗させる（権限のチェックより後のステータスは問わない）。
+func TestRoutePermissions(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	r := newRouter()
+
+	for _, rc := range routeTable {
+		path := paramPattern.ReplaceAllString(rc.path, "1")
+
+		if rc.auth != authNone {
+			t.Run(rc.method+" "+rc.path+" without token", func(t *testing.T) {
+				mockDB(t)
+				w := serve(r, rc.method, path, "")
+				if w.Code != http.StatusUnauthorized {
+					t.Errorf("status = %d, want 401", w.Code)
+				}
+			})
+		}
+
+		for _, role := range roles {
+			t.Run(rc.method+" "+rc.path+" as "+role, func(t *testing.T) {
+				token, err := utils.GenerateToken(1, "test", role, 1, "test store", 0)
+				if err != nil {
+					t.Fatal(err)
+				}
+				mockDB(t)
+				w := serve(r, rc.method, path, token)
+
+				var body map[string]interface{}
+				_ = json.Unmarshal(w.Body.Bytes(), &body)
+				denied := w.Code == http.StatusForbidden && body["permission"] != nil
+
+				if rc.auth != authPermission || permission.Has(role, rc.perm) {
+					if denied {
+						t.Errorf("権限があるのに拒否されました: %d %s", w.Code, w.Body.String())
+					}
+					if rc.auth != authNone && w.Code == http.StatusUnauthorized {
+						t.Errorf("認証に失敗しました: %s", w.Body.String())
+					}
+					return
+				}
+				want := map[string]interface{}{"error": "この操作を行う権限がありません", "permission": string(rc.perm)}
+				if w.Code != http.StatusForbidden || !jsonEqual(body, want) {
+					t.Errorf("got %d %s, want 403 %v", w.Code, w.Body.String(), want)
+				}
+			})
+		}
+	}
+}
+
+// mockDB は db.DB を sqlmock に差し替え、認証ミドルウェアの失効チェック（失効リストなし・token_version 0）だけに応答させる
+func mockDB(t *testing.T) {
+	t.Helper()
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	g, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.DB = g
+	mock.ExpectQuery(`FROM "revoked_tokens"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id", "token_version"}).AddRow(1, 0))
+}
+
+func serve(r http.Handler, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func jsonEqual(got, want map[string]interface{}) bool {
+	a, _ := json.Marshal(got)
+	b, _ := json.Marshal(want)
+	return string(a) == string(b)
+}
+
+// TestClientIPTrustedProxies は X-Forwarded-For を TRUSTED_PROXIES のプロキシからのリクエストでだけ使うことを確認する
+// （ログインのロック・レート制限のキーをヘッダーで偽装できないように）。
+func TestClientIPTrustedProxies(t *testing.T) {
+	cases := []struct {
+		name    string
+		proxies string
+		want    string
+	}{
+		{"未設定なら接続元", "", "203.0.113.5"},
+		{"信用しないプロキシからなら接続元", "10.0.0.0/8", "203.0.113.5"},
+		{"信用するプロキシからなら転送元", "203.0.113.0/24", "198.51.100.7"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tc.proxies)
+			r := newRouter()
+			r.GET("/client-ip", func(c *gin.Context) { c.String(200, c.ClientIP()) })
+
+			req := httptest.NewRequest("GET", "/client-ip", nil)
+			req.RemoteAddr = "203.0.113.5:40000"
+			req.Header.Set("X-Forwarded-For", "198.51.100.7")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if got := w.Body.String(); got != tc.want {
+				t.Errorf("ClientIP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// normalize-kana は既存の顧客のフリガナを正規の形（全角ひらがな、余分な空白なし）にそろえる一回限りの移行コマンド。
+// ひらがなにできなかった顧客は一覧を出力して終了コード 1 で終わる（その顧客は変更しない）。
+//
+//	go run ./cmd/normalize-kana            # 変換して保存
+//	go run ./cmd/normalize-kana -dry-run   # 変換結果を表示するだけ
+//
+// 接続先はサーバーと同じ環境変数（DB_HOST など）で指定する。
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+
+    "gorm.io/gorm"
+
+    "salon-app/backend/internal/db"
+    "salon-app/backend/internal/model"
+    "salon-app/backend/internal/utils"
+)
+
+func main() {
+    dryRun := flag.Bool("dry-run", false, "保存せずに変換結果だけを表示する")
+    flag.Parse()
+
+    db.InitDB()
+
+    var checked, updated int
+    var failures []string
+    var customers []model.Customer
+    // 削除済みの顧客も履歴に表示されるので対象にする
+    err := db.DB.Unscoped().FindInBatches(&customers, 500, func(*gorm.DB, int) error {
+        for i := range customers {
+            cu := &customers[i]
+            checked++
+            last, okLast := utils.NormalizeKana(cu.LastNameKana)
+            first, okFirst := utils.NormalizeKana(cu.FirstNameKana)
+            if !okLast || !okFirst {
+                failures = append(failures, fmt.Sprintf("id=%d store_id=%d %s %s: last_name_kana=%q first_name_kana=%q",
+                    cu.ID, cu.StoreID, cu.LastName, cu.FirstName, cu.LastNameKana, cu.FirstNameKana))
+                continue
+            }
+            if last == cu.LastNameKana && first == cu.FirstNameKana {
+                continue
+            }
+            fmt.Printf("id=%d: %q %q → %q %q\n", cu.ID, cu.LastNameKana, cu.FirstNameKana, last, first)
+            updated++
+            if *dryRun {
+                continue
+            }
+            cu.LastNameKana, cu.FirstNameKana = last, first
+            cu.RefreshSearchColumns()
+            // UpdateColumns は updated_at を変えない（データの整形でスタッフの更新日時を動かさない）
+            if err := db.DB.Unscoped().Model(cu).UpdateColumns(map[string]interface{}{
+                "last_name_kana":  cu.LastNameKana,
+                "first_name_kana": cu.FirstNameKana,
+                "search_name":     cu.SearchName,
+            }).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    }).Error
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "フリガナの変換に失敗しました:", err)
+        os.Exit(2)
+    }
+
+    verb := "変換しました"
+    if *dryRun {
+        verb = "変換します（-dry-run のため保存していません）"
+    }
+    fmt.Printf("%d 件中 %d 件を%s\n", checked, updated, verb)
+    if len(failures) > 0 {
+        fmt.Printf("ひらがなにできなかった顧客が %d 件あります（変更していません。顧客の編集画面で修正してください）:\n", len(failures))
+        for _, f := range failures {
+            fmt.Println("  " + f)
+        }
+        os.Exit(1)
+    }
+}
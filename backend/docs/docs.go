@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/course": {
-            "get": {
-                "description": "コース一覧",
+        "/2fa/disable": {
+            "post": {
+                "description": "認証コード（またはリカバリーコード）を確認して二段階認証を無効にします。必須ロールでは無効にできません。\n二段階認証ログインと同じく、コードを続けて間違えると一定時間ロックされます（429）",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,20 +25,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "2fa"
+                ],
+                "summary": "二段階認証無効化",
+                "parameters": [
+                    {
+                        "description": "認証コード",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.MFACodeRequest"
+                        }
+                    }
                 ],
-                "summary": "コース一覧",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Course"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "コース一覧",
+            }
+        },
+        "/2fa/enable": {
+            "post": {
+                "description": "認証アプリのコードを確認して二段階認証を有効にし、リカバリーコードを返します（リカバリーコードが表示されるのはこの時だけです）",
                 "consumes": [
                     "application/json"
                 ],
@@ -46,22 +62,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "2fa"
+                ],
+                "summary": "二段階認証有効化",
+                "parameters": [
+                    {
+                        "description": "認証コード",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.MFACodeRequest"
+                        }
+                    }
                 ],
-                "summary": "コース一覧",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Course"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/course-registration": {
+        "/2fa/recovery-codes": {
             "post": {
-                "description": "コース登録",
+                "description": "認証コードを確認して、リカバリーコードを作り直します（古いコードは使えなくなります）。\n二段階認証ログインと同じく、コードを続けて間違えると一定時間ロックされます（429）",
                 "consumes": [
                     "application/json"
                 ],
@@ -69,25 +97,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "2fa"
+                ],
+                "summary": "リカバリーコード再発行",
+                "parameters": [
+                    {
+                        "description": "認証コード",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.MFACodeRequest"
+                        }
+                    }
                 ],
-                "summary": "コース登録",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/course/:id": {
-            "put": {
-                "description": "コース更新",
+        "/2fa/setup": {
+            "post": {
+                "description": "TOTPの秘密鍵を発行し、認証アプリ登録用のURI（QRコード用）を返します。有効化は /2fa/enable で行います",
                 "consumes": [
                     "application/json"
                 ],
@@ -95,22 +132,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "2fa"
                 ],
-                "summary": "コース更新",
+                "summary": "二段階認証セットアップ",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Course"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/course/{id}": {
-            "delete": {
-                "description": "指定したIDのコースを論理削除します",
+        "/analytics/courses": {
+            "get": {
+                "description": "コースごとの来店数・顧客数と、期間中に販売した回数券の枚数・金額（来店数の多い順）",
                 "consumes": [
                     "application/json"
                 ],
@@ -118,34 +158,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "course"
+                    "analytics"
                 ],
-                "summary": "コース削除",
+                "summary": "コース別の人気",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD（省略時は30日前）",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD（省略時は今日）",
+                        "name": "to",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "Course ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_handler.CoursePopularityRow"
                             }
                         }
                     }
                 }
             }
         },
-        "/customer": {
+        "/analytics/customers": {
             "get": {
-                "description": "顧客一覧取得",
+                "description": "店舗・期間ごとに来店した顧客を、その期間に初来店した新規と、それ以前にも来店があるリピーターに分けて数えます",
                 "consumes": [
                     "application/json"
                 ],
@@ -153,22 +204,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "analytics"
+                ],
+                "summary": "新規・リピーター",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "day / week / month（既定）",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD（省略時は30日前）",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD（省略時は今日）",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "顧客一覧",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_handler.CustomerMixRow"
+                            }
                         }
                     }
                 }
             }
         },
-        "/customer-registration": {
-            "post": {
-                "description": "顧客登録",
+        "/analytics/liability": {
+            "get": {
+                "description": "現時点で消化できる回数券（期限内で残り回数がある）の残り回数と金額（前受金）を店舗・コースごとに集計します。期間の指定は使いません",
                 "consumes": [
                     "application/json"
                 ],
@@ -176,25 +256,31 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "analytics"
+                ],
+                "summary": "回数券の未消化残高",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "顧客登録",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/customer-search": {
+        "/analytics/overdue": {
             "get": {
-                "description": "顧客検索(苗字：ひらがな)",
+                "description": "いつもの来店間隔（店舗の設定の割合）を過ぎても来店がない顧客を、遅れの大きい順に返します。離反（lapsed）の顧客は含めません",
                 "consumes": [
                     "application/json"
                 ],
@@ -202,22 +288,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "analytics"
+                ],
+                "summary": "来店遅れの顧客",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "顧客検索(苗字：ひらがな)",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_handler.RetentionRow"
+                            }
                         }
                     }
                 }
             }
         },
-        "/customer/:id": {
-            "put": {
-                "description": "顧客更新",
+        "/analytics/revenue": {
+            "get": {
+                "description": "店舗・期間（日・週・月）ごとの売上（取り消した売上を除く）と来店数（取り消した来店を除く）",
                 "consumes": [
                     "application/json"
                 ],
@@ -225,22 +322,51 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "analytics"
+                ],
+                "summary": "売上・来店数の推移",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "day（既定）/ week / month",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD（省略時は30日前）",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD（省略時は今日）",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "顧客更新",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_handler.RevenueRow"
+                            }
                         }
                     }
                 }
             }
         },
-        "/customer/{id}": {
-            "delete": {
-                "description": "指定したIDの顧客を論理削除します",
+        "/analytics/rfm": {
+            "get": {
+                "description": "来店履歴から顧客ごとの R（最終来店からの日数）・F（来店回数）・M（会計の合計）と店舗内の5段階スコアを計算し、\n店舗のしきい値で loyal / active / new / at_risk / lapsed に分けます。segment で絞り込めます",
                 "consumes": [
                     "application/json"
                 ],
@@ -248,16 +374,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "customer"
+                    "analytics"
                 ],
-                "summary": "顧客削除",
+                "summary": "顧客の離反分析（RFM）",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Customer ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "loyal / active / new / at_risk / lapsed",
+                        "name": "segment",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -265,17 +396,15 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
                         }
                     }
                 }
             }
         },
-        "/login": {
-            "post": {
-                "description": "ログイン",
+        "/analytics/staff": {
+            "get": {
+                "description": "スタッフごとの担当来店数・顧客数、会計した売上、販売した回数券の枚数",
                 "consumes": [
                     "application/json"
                 ],
@@ -283,25 +412,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "analytics"
+                ],
+                "summary": "スタッフ別の実績",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD（省略時は30日前）",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD（省略時は今日）",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "ログイン",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_handler.StaffProductivityRow"
                             }
                         }
                     }
                 }
             }
         },
-        "/ping": {
+        "/analytics/tickets": {
             "get": {
-                "description": "サーバーの生存確認用",
+                "description": "期間中に販売した回数券（譲渡で作成したものを除く）のコースごとの消化状況。sell_through は消化した回数 / 販売した回数",
                 "consumes": [
                     "application/json"
                 ],
@@ -309,25 +458,45 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "analytics"
+                ],
+                "summary": "回数券の消化率",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD（省略時は30日前）",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD（省略時は今日）",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "疎通確認",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_handler.TicketSellThroughRow"
                             }
                         }
                     }
                 }
             }
         },
-        "/signup": {
-            "post": {
-                "description": "新規登録",
+        "/availability": {
+            "get": {
+                "description": "コース・店舗・期間・担当スタッフ（任意）を指定して、予約可能な開始時刻を返します。admin は store_id の指定が必要です",
                 "consumes": [
                     "application/json"
                 ],
@@ -335,25 +504,55 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "reservation"
+                ],
+                "summary": "空き枠検索",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "コースID",
+                        "name": "course_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID（admin のみ）",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD（省略時は今日）",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD（省略時は開始日から1週間、最大31日）",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "担当スタッフID",
+                        "name": "staff_id",
+                        "in": "query"
+                    }
                 ],
-                "summary": "新規登録",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/internal_handler.Slot"
                         }
                     }
                 }
             }
         },
-        "/store": {
-            "get": {
-                "description": "店舗一覧",
+        "/course": {
+            "put": {
+                "description": "コース一覧",
                 "consumes": [
                     "application/json"
                 ],
@@ -363,23 +562,20 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "店舗一覧",
+                "summary": "コース一覧",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Course"
                         }
                     }
                 }
             }
         },
-        "/store-registration": {
+        "/course-registration": {
             "post": {
-                "description": "店舗登録",
+                "description": "コース登録",
                 "consumes": [
                     "application/json"
                 ],
@@ -389,7 +585,7 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "店舗登録",
+                "summary": "コース登録",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -403,9 +599,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/ticket": {
-            "get": {
-                "description": "チケット一覧",
+        "/course/:id": {
+            "put": {
+                "description": "コース更新",
                 "consumes": [
                     "application/json"
                 ],
@@ -415,23 +611,20 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "チケット一覧",
+                "summary": "コース更新",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Course"
                         }
                     }
                 }
             }
         },
-        "/ticket/:id": {
-            "put": {
-                "description": "チケット更新",
+        "/course/{id}": {
+            "delete": {
+                "description": "指定したIDのコースを論理削除します（権限: course.delete）",
                 "consumes": [
                     "application/json"
                 ],
@@ -439,22 +632,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "course"
+                ],
+                "summary": "コース削除",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Course ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "チケット更新",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Ticket"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/users": {
+        "/courses": {
             "get": {
-                "description": "スタッフ一覧取得",
+                "description": "コース一覧",
                 "consumes": [
                     "application/json"
                 ],
@@ -464,20 +669,59 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "スタッフ一覧 (ユーザー一覧)",
+                "summary": "コース一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id（既定）/ name / price。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.User"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.Course"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
                         }
                     }
                 }
             }
         },
-        "/users/:id": {
-            "put": {
-                "description": "スタッフ更新",
+        "/customer": {
+            "get": {
+                "description": "顧客一覧取得",
                 "consumes": [
                     "application/json"
                 ],
@@ -487,20 +731,97 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "スタッフ更新",
+                "summary": "顧客一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "登録日 YYYY-MM-DD 以降",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "登録日 YYYY-MM-DD まで",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id（既定）/ created_at / last_name / last_name_kana。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.User"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
                         }
                     }
                 }
             }
         },
-        "/visit": {
+        "/customer-registration": {
+            "post": {
+                "description": "顧客登録",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "顧客登録",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/customer-search": {
             "get": {
-                "description": "来店履歴一覧取得",
+                "description": "氏名（漢字）・フリガナ（ひらがな／カタカナ、全角／半角を区別しない）・電話番号（ハイフンを無視）・メールアドレスで顧客を探します。\n完全一致 → 前方一致 → 部分一致の順に、同じなら最終来店の新しい順に返します。POST の場合は本文の q（旧形式の last_name_kana も可）で検索します",
                 "consumes": [
                     "application/json"
                 ],
@@ -510,20 +831,134 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "来店履歴一覧",
+                "summary": "顧客検索",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "検索語",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "件数（既定 20、最大 50）",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/internal_handler.CustomerSearchResult"
+                            }
                         }
                     }
                 }
             }
         },
-        "/visit-registration": {
+        "/customer/:id": {
+            "put": {
+                "description": "顧客更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "顧客更新",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{id}": {
+            "delete": {
+                "description": "指定したIDの顧客を論理削除します（権限: customer.delete）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer"
+                ],
+                "summary": "顧客削除",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/customer/{id}/history": {
+            "get": {
+                "description": "顧客の来店記録・チケット（共有メンバーのものを含む）・チケット譲渡の履歴（譲渡元・譲渡先どちらの場合も）を返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "customer"
+                ],
+                "summary": "顧客の履歴",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
             "post": {
-                "description": "来店登録",
+                "description": "ログイン",
                 "consumes": [
                     "application/json"
                 ],
@@ -533,7 +968,7 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "来店登録",
+                "summary": "ログイン",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -547,9 +982,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/visit/:id": {
-            "put": {
-                "description": "来店履歴更新",
+        "/login-locks": {
+            "get": {
+                "description": "現在ロック中のアカウント・IPアドレスの一覧（権限: user.security）",
                 "consumes": [
                     "application/json"
                 ],
@@ -559,20 +994,126 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "来店履歴更新",
+                "summary": "ログインロック一覧",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                            "$ref": "#/definitions/salon-app_backend_internal_model.LoginThrottle"
                         }
                     }
                 }
             }
         },
-        "/visit/search": {
+        "/login-locks/unlock": {
+            "post": {
+                "description": "メールアドレスまたはIPアドレスのログイン失敗回数とロックを解除します（権限: user.security）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "ログインロック解除",
+                "parameters": [
+                    {
+                        "description": "解除対象",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.LoginUnlockRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/login/2fa": {
+            "post": {
+                "description": "ログインで返されたチャレンジトークンと、認証アプリのコード（またはリカバリーコード）を検証してトークンを発行します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "二段階認証ログイン",
+                "parameters": [
+                    {
+                        "description": "チャレンジトークンとコード",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.MFALoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/logout": {
+            "post": {
+                "description": "現在のアクセストークンと、指定されたリフレッシュトークンを失効させます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "ログアウト",
+                "parameters": [
+                    {
+                        "description": "リフレッシュトークン",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.LogoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ping": {
             "get": {
-                "description": "来店履歴検索",
+                "description": "サーバーの生存確認用",
                 "consumes": [
                     "application/json"
                 ],
@@ -582,34 +1123,3985 @@ const docTemplate = `{
                 "tags": [
                     "system"
                 ],
-                "summary": "来店履歴検索",
+                "summary": "疎通確認",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "gorm.DeletedAt": {
+        },
+        "/postal/{zip}": {
+            "get": {
+                "description": "郵便番号（ハイフン・全角数字可）から都道府県・市区町村・町域を返します。1つの郵便番号に複数の町域がある場合は町域ごとに返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "郵便番号検索",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "郵便番号（例: 1000001 / 100-0001）",
+                        "name": "zip",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.PostalCode"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/public/bookings/{token}": {
+            "get": {
+                "description": "確認トークンで予約内容を取得します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "オンライン予約: 予約確認",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "確認トークン",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/public/bookings/{token}/cancel": {
+            "post": {
+                "description": "確認トークンで予約をキャンセルします（予約開始時刻まで）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "オンライン予約: キャンセル",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "確認トークン",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/public/bookings/{token}/reschedule": {
+            "post": {
+                "description": "確認トークンで予約の日時を変更します（予約開始時刻まで。オンライン予約の対象から外れたコースは変更できません）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "オンライン予約: 日時変更",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "確認トークン",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "変更後の日時",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.PublicRescheduleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/public/stores/{store_id}/availability": {
+            "get": {
+                "description": "コースの予約可能な開始時刻一覧",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "オンライン予約: 空き枠",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Store ID",
+                        "name": "store_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "コースID",
+                        "name": "course_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/public/stores/{store_id}/bookings": {
+            "post": {
+                "description": "予約を申し込みます。電話番号・フリガナで既存顧客と照合し、見つからなければ仮登録の顧客を作成します。確認・キャンセル・変更用のトークンを返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "オンライン予約: 予約申し込み",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Store ID",
+                        "name": "store_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "予約内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.PublicBookingRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/public/stores/{store_id}/courses": {
+            "get": {
+                "description": "店舗のオンライン予約可能なコース一覧",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "public"
+                ],
+                "summary": "オンライン予約: コース一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Store ID",
+                        "name": "store_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/refresh": {
+            "post": {
+                "description": "リフレッシュトークンを使ってアクセストークンを再発行します。リフレッシュトークンは使用のたびに新しいものへ切り替わります",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "トークン再発行",
+                "parameters": [
+                    {
+                        "description": "リフレッシュトークン",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/register-sessions": {
+            "get": {
+                "description": "レジセッション（締めレポート）の一覧を新しい順に返します。from / to は開局日で絞り込みます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "register"
+                ],
+                "summary": "レジセッション一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "open / closed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "開始日 YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日 YYYY-MM-DD（この日を含む）",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "opened_at（既定は -opened_at）。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.RegisterSession"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "店舗のレジを釣銭準備金を指定して開きます。1店舗で同時に開けるレジセッションは1つです",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "register"
+                ],
+                "summary": "レジ開局",
+                "parameters": [
+                    {
+                        "description": "開局内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.RegisterOpenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.RegisterSession"
+                        }
+                    }
+                }
+            }
+        },
+        "/register-sessions/{id}": {
+            "get": {
+                "description": "締めたセッションは締めの時点で確定した集計を返します。開いているセッションは現時点までの集計を計算して返します（保存はしません）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "register"
+                ],
+                "summary": "レジセッション詳細（締めレポート）",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Register session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.RegisterSession"
+                        }
+                    }
+                }
+            }
+        },
+        "/register-sessions/{id}/close": {
+            "post": {
+                "description": "数えた現金を入力してレジを締めます。期間中の現金の売上（会計を通さずに登録した回数券を含む）・取消・返金と入出金から理論上の現金残高を計算し、過不足とともに保存します。\n締めた後はセッションを変更できません",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "register"
+                ],
+                "summary": "レジ締め",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Register session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "締めの内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.RegisterCloseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.RegisterSession"
+                        }
+                    }
+                }
+            }
+        },
+        "/register-sessions/{id}/movements": {
+            "post": {
+                "description": "売上以外のレジの現金の出し入れ（両替用の補充・小口の支払など）を記録します。締めたセッションには記録できません",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "register"
+                ],
+                "summary": "レジ入出金",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Register session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "入出金内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.RegisterMovementRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.RegisterMovement"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservation": {
+            "get": {
+                "description": "予約一覧取得。from / to（RFC3339 または YYYY-MM-DD）、staff_id、customer_id、status で絞り込めます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reservation"
+                ],
+                "summary": "予約一覧",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "開始日時(以降)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日時(より前)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "担当スタッフID",
+                        "name": "staff_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "顧客ID",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ステータス",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "start_at（既定）/ created_at / id。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.Reservation"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "予約を登録します。担当スタッフの重複・店舗の席数超過がある場合は 409 を返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reservation"
+                ],
+                "summary": "予約登録",
+                "parameters": [
+                    {
+                        "description": "予約内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Reservation"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservation/{id}": {
+            "get": {
+                "description": "予約詳細取得",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reservation"
+                ],
+                "summary": "予約詳細",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Reservation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Reservation"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "予約の日時・担当・コース・メモを変更します（受付中・確定済みの予約のみ）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reservation"
+                ],
+                "summary": "予約変更",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Reservation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "予約内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Reservation"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservation/{id}/status": {
+            "put": {
+                "description": "予約のステータスを変更します。completed にすると来店記録を作成し、ticket_id で指定した回数券を消化します（null なら都度払い）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reservation"
+                ],
+                "summary": "予約ステータス変更",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Reservation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "変更後のステータス",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ReservationStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Reservation"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales": {
+            "get": {
+                "description": "売上一覧と、絞り込んだ売上のうち取り消されていないものの合計（支払方法別の内訳付き。合計はページングの前の全件が対象）。\ndate・from・to をどれも指定しなければ今日の売上",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sale"
+                ],
+                "summary": "売上一覧",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "日付 YYYY-MM-DD（1日分）",
+                        "name": "date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "会計日 YYYY-MM-DD 以降",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "会計日 YYYY-MM-DD まで",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "顧客ID",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "会計したスタッフID",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "completed / voided",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "sold_at（既定）/ id / total。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "明細（回数券・施術・物販）と支払（現金・カード・QR・その他、複数で併用払い）を登録します。\n回数券の明細で ticket_id がなければその場で回数券を発行します。支払の合計は会計金額と一致する必要があります",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sale"
+                ],
+                "summary": "会計（売上登録）",
+                "parameters": [
+                    {
+                        "description": "会計内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.SaleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Sale"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales/{id}": {
+            "get": {
+                "description": "売上詳細（明細・支払付き）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sale"
+                ],
+                "summary": "売上詳細",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sale ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Sale"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales/{id}/receipt": {
+            "get": {
+                "description": "売上の領収書（適格簡易請求書）。登録番号・税率ごとの対象額と消費税額を含みます。format=text でレシートプリンター向けのテキストを返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/plain"
+                ],
+                "tags": [
+                    "sale"
+                ],
+                "summary": "領収書",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sale ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json（既定）/ text",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.Receipt"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales/{id}/receipt.pdf": {
+            "get": {
+                "description": "売上の領収書（type=invoice なら請求書）をPDFで返します。会計時に保存した内容から作るため、同じ売上からは常に同じPDFになります",
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "sale"
+                ],
+                "summary": "領収書PDF",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sale ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "receipt（既定）/ invoice",
+                        "name": "type",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    }
+                }
+            }
+        },
+        "/sales/{id}/void": {
+            "post": {
+                "description": "売上を取り消します（記録は理由とともに残ります）。この売上で発行した回数券は、未使用であれば無効にします（発行済みの回数券の代金を受け取った明細のチケットはそのまま残ります）。発行した回数券に消化・返金・調整・譲渡があった売上は取り消せないので、チケット調整の refund を使ってください",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sale"
+                ],
+                "summary": "売上取消",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Sale ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "取消理由",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.SaleVoidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Sale"
+                        }
+                    }
+                }
+            }
+        },
+        "/shift": {
+            "get": {
+                "description": "スタッフの勤務シフト一覧。from / to（YYYY-MM-DD）、staff_id で絞り込めます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "シフト一覧",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "開始日",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "終了日",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "スタッフID",
+                        "name": "staff_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "date（既定）。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.StaffShift"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "スタッフの勤務シフトを登録します（同じスタッフ・同じ日付が登録済みの場合は上書き）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "シフト登録",
+                "parameters": [
+                    {
+                        "description": "シフト",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ShiftRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.StaffShift"
+                        }
+                    }
+                }
+            }
+        },
+        "/shift/{id}": {
+            "delete": {
+                "description": "勤務シフトを削除します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "シフト削除",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Shift ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/signup": {
+            "post": {
+                "description": "新規登録",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "新規登録",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/store": {
+            "get": {
+                "description": "店舗一覧",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "店舗一覧",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/store-registration": {
+            "post": {
+                "description": "店舗登録",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "店舗登録",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/store/{id}/business-hours": {
+            "put": {
+                "description": "店舗の営業時間・予約枠の刻み・定休日を更新します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "営業時間更新",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Store ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "営業時間",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.BusinessHoursRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Store"
+                        }
+                    }
+                }
+            }
+        },
+        "/store/{id}/holidays": {
+            "get": {
+                "description": "店舗の臨時休業日一覧",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "臨時休業日一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Store ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.StoreHoliday"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "臨時休業日を登録します（同じ日付が登録済みの場合は理由を上書き）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "臨時休業日登録",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Store ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "休業日",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.StoreHolidayRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.StoreHoliday"
+                        }
+                    }
+                }
+            }
+        },
+        "/store/{id}/holidays/{holiday_id}": {
+            "delete": {
+                "description": "臨時休業日を削除します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "schedule"
+                ],
+                "summary": "臨時休業日削除",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Store ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Holiday ID",
+                        "name": "holiday_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket": {
+            "get": {
+                "description": "チケット一覧（顧客・コース付き）。残り回数と状態（active / completed / expired）を含みます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケット一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "顧客ID（共有メンバーになっているチケットも含む）",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "コースID",
+                        "name": "course_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "状態 (active / completed / expired)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "購入日 YYYY-MM-DD 以降",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "購入日 YYYY-MM-DD まで",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id / purchased_at（既定は -id）。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.TicketView"
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket/{id}": {
+            "put": {
+                "description": "チケットの規定回数を修正します。変更は理由コード付きで調整履歴（correction）に記録されます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケット更新",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "修正内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.TicketUpdateRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Ticket"
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket/{id}/adjustments": {
+            "get": {
+                "description": "チケットの調整履歴を古い順に返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケット調整履歴",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.TicketAdjustment"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "来店以外でチケットの回数を変更し、理由コード・メモ・操作者を調整履歴に記録します。\ncomplimentary: sessions 回を無料で追加 / correction: 消化回数を sessions 回修正（マイナス可）/\nrefund: 未使用の sessions 回分を返金（省略時は残り全部、金額の省略時は返金済みを除いた支払額を按分。返金額は支払額 - 返金済みの額まで）/\nreopen: 使い切り・期限切れのチケットを再開（sessions 回追加、expires_at で期限を延長）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケット調整",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "調整内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.TicketAdjustmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket/{id}/history": {
+            "get": {
+                "description": "チケットを消化した来店記録を消化した順（何回目か）に返します。譲渡の履歴も含みます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケットの消化履歴",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket/{id}/members": {
+            "post": {
+                "description": "チケットを共有する顧客（家族など）を追加します。共有メンバーも来店登録でこのチケットを消化できます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケット共有メンバー追加",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "追加する顧客",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.TicketMemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Ticket"
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket/{id}/members/{customer_id}": {
+            "delete": {
+                "description": "チケットの共有メンバーを外します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケット共有メンバー削除",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Customer ID",
+                        "name": "customer_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Ticket"
+                        }
+                    }
+                }
+            }
+        },
+        "/ticket/{id}/transfer": {
+            "post": {
+                "description": "チケットの残り回数を別の顧客へ譲渡します。譲渡先には同じ内容（コース・有効期限）の新しいチケットを作成し、支払額（返金済みの額を除く）も回数で按分して移します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "チケット譲渡",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Ticket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "譲渡内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.TicketTransferRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets": {
+            "post": {
+                "description": "コースの内容（コース名・価格・回数・有効期間）をスナップショットとして回数券を発行し、購入日時・支払金額・支払方法を記録します。同じ顧客・コースで複数の回数券を持てます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "回数券の購入",
+                "parameters": [
+                    {
+                        "description": "購入内容",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.TicketPurchaseRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Ticket"
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/expiring": {
+            "get": {
+                "description": "days 日以内に有効期限を迎える、消化中のチケットを期限の近い順に返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ticket"
+                ],
+                "summary": "期限切れ間近のチケット一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "何日以内か（省略時は30、最大365）",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.TicketView"
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "description": "スタッフ一覧取得",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "スタッフ一覧 (ユーザー一覧)",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "admin / manager / staff",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "id（既定）/ name / created_at。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.User"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/:id": {
+            "put": {
+                "description": "スタッフ更新",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "スタッフ更新",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.User"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/2fa/reset": {
+            "post": {
+                "description": "認証アプリを紛失したスタッフの二段階認証を解除し、全セッションを無効化します（権限: user.security）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "二段階認証リセット",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/revoke-sessions": {
+            "post": {
+                "description": "指定したスタッフのログイン中セッションをすべて無効化します（権限: user.security）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "全セッション無効化",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/visit": {
+            "get": {
+                "description": "来店履歴一覧取得（新しい順）。取り消した来店記録も voided_at 付きで含みます",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "来店履歴一覧",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "店舗ID",
+                        "name": "store_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "顧客ID",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "コースID",
+                        "name": "course_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "担当スタッフID",
+                        "name": "staff_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "チケットID",
+                        "name": "ticket_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "来店日 YYYY-MM-DD 以降",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "来店日 YYYY-MM-DD まで",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created_at / id（既定は -created_at）。- を付けると降順",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "1ページの件数（既定 100、最大 500）",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "次のページのカーソル（前のレスポンスの X-Next-Cursor）",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                            }
+                        },
+                        "headers": {
+                            "X-Next-Cursor": {
+                                "type": "string",
+                                "description": "次のページのカーソル（最後のページではなし）"
+                            },
+                            "X-Total-Count": {
+                                "type": "integer",
+                                "description": "絞り込み後の全件数"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/visit-registration": {
+            "post": {
+                "description": "来店登録。ticket_id を指定するとその回数券を1回分消化し（同じトランザクションで行います）、null なら都度払いとして登録します。Idempotency-Key ヘッダーを付けると、同じキーでの再送は二重に登録せず最初の結果を返します",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "来店登録",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "再送時の二重登録防止キー",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/visit/:id": {
+            "put": {
+                "description": "来店履歴更新。顧客・コース・チケットを変更すると、元のチケットの消化を戻して新しいチケットで消化し直します（同じトランザクションで行います）。\nticket_id を省略するとチケットは変更しません。都度払いに変えるには clear_ticket を true にします",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "来店履歴更新",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                        }
+                    }
+                }
+            }
+        },
+        "/visit/search": {
+            "get": {
+                "description": "来店履歴検索（パラメータは来店履歴一覧と同じ）",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "来店履歴検索",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/visit/{id}/void": {
+            "post": {
+                "description": "来店記録を取り消し、消化したチケットの1回分を戻します。取り消した来店記録は理由とともに残ります",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "来店取消",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Visit ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "取消理由",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.VisitVoidRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "gorm.DeletedAt": {
+            "type": "object",
+            "properties": {
+                "time": {
+                    "type": "string"
+                },
+                "valid": {
+                    "description": "Valid is true if Time is not NULL",
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_handler.BusinessHoursRequest": {
+            "type": "object",
+            "required": [
+                "close_time",
+                "open_time",
+                "slot_minutes"
+            ],
+            "properties": {
+                "close_time": {
+                    "type": "string"
+                },
+                "open_time": {
+                    "type": "string"
+                },
+                "regular_holidays": {
+                    "description": "0=日〜6=土",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "slot_minutes": {
+                    "type": "integer",
+                    "maximum": 120,
+                    "minimum": 5
+                }
+            }
+        },
+        "internal_handler.CoursePopularityRow": {
+            "type": "object",
+            "properties": {
+                "course_id": {
+                    "type": "integer"
+                },
+                "course_name": {
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "ticket_revenue": {
+                    "type": "integer"
+                },
+                "tickets_sold": {
+                    "type": "integer"
+                },
+                "unique_customers": {
+                    "type": "integer"
+                },
+                "visits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.CustomerMixRow": {
+            "type": "object",
+            "properties": {
+                "new": {
+                    "description": "この期間に初めて来店した顧客",
+                    "type": "integer"
+                },
+                "period": {
+                    "type": "string"
+                },
+                "returning": {
+                    "description": "それ以前にも来店がある顧客",
+                    "type": "integer"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "visits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.CustomerSearchResult": {
+            "type": "object",
+            "properties": {
+                "address1": {
+                    "description": "市区町村・町域",
+                    "type": "string"
+                },
+                "address2": {
+                    "description": "ビル・マンション名",
+                    "type": "string"
+                },
+                "birth_date": {
+                    "description": "日付型がおすすめ",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "first_name_kana": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_provisional": {
+                    "description": "オンライン予約で既存顧客と照合できなかった場合に作成される仮登録の顧客",
+                    "type": "boolean"
+                },
+                "last_name": {
+                    "description": "名前（フリガナ含め分けているのはGood!）",
+                    "type": "string"
+                },
+                "last_name_kana": {
+                    "type": "string"
+                },
+                "last_visit_at": {
+                    "description": "取り消していない最後の来店。来店がなければ null",
+                    "type": "string"
+                },
+                "phone": {
+                    "description": "uintからstringへ変更",
+                    "type": "string"
+                },
+                "pref_name": {
+                    "description": "文字列で持つパターン",
+                    "type": "string"
+                },
+                "sex": {
+                    "description": "属性情報",
+                    "type": "string"
+                },
+                "store": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Store"
+                },
+                "store_id": {
+                    "description": "店舗紐付け",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "zip_code": {
+                    "description": "住所（ポストくん構成）",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.LoginUnlockRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.LogoutRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.MFACodeRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.MFALoginRequest": {
+            "type": "object",
+            "required": [
+                "challenge_token",
+                "code"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "description": "認証アプリの6桁コード、またはリカバリーコード",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.PublicBookingRequest": {
+            "type": "object",
+            "required": [
+                "course_id",
+                "email",
+                "first_name",
+                "first_name_kana",
+                "last_name",
+                "last_name_kana",
+                "phone",
+                "start_at"
+            ],
+            "properties": {
+                "course_id": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "first_name": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "first_name_kana": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "last_name": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "last_name_kana": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "phone": {
+                    "type": "string",
+                    "maxLength": 20
+                },
+                "start_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.PublicRescheduleRequest": {
+            "type": "object",
+            "required": [
+                "start_at"
+            ],
+            "properties": {
+                "start_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.Receipt": {
+            "type": "object",
+            "properties": {
+                "customer_name": {
+                    "type": "string"
+                },
+                "discount": {
+                    "type": "integer"
+                },
+                "invoice_number": {
+                    "description": "未登録の店舗では空（その場合は適格請求書にならない）",
+                    "type": "string"
+                },
+                "issuer_name": {
+                    "type": "string"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_handler.ReceiptLine"
+                    }
+                },
+                "notes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "payments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_handler.ReceiptPayment"
+                    }
+                },
+                "qualified": {
+                    "description": "適格請求書の要件を満たすか（登録番号があるか）",
+                    "type": "boolean"
+                },
+                "sale_id": {
+                    "type": "integer"
+                },
+                "sold_at": {
+                    "type": "string"
+                },
+                "subtotal": {
+                    "type": "integer"
+                },
+                "tax_mode": {
+                    "type": "string"
+                },
+                "tax_total": {
+                    "type": "integer"
+                },
+                "taxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/internal_handler.ReceiptTax"
+                    }
+                },
+                "title": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "voided": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "internal_handler.ReceiptLine": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "discount": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "reduced": {
+                    "description": "軽減税率の対象（品名の後に ※ を付ける）",
+                    "type": "boolean"
+                },
+                "tax_rate": {
+                    "type": "integer"
+                },
+                "ticket_remaining": {
+                    "description": "会計時点のチケットの残り回数",
+                    "type": "integer"
+                },
+                "unit_price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.ReceiptPayment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.ReceiptTax": {
+            "type": "object",
+            "properties": {
+                "label": {
+                    "description": "例: \"10%対象\"",
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "integer"
+                },
+                "tax": {
+                    "type": "integer"
+                },
+                "taxable": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.RefreshRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.RegisterCloseRequest": {
+            "type": "object",
+            "required": [
+                "counted_cash"
+            ],
+            "properties": {
+                "counted_cash": {
+                    "description": "数えた現金",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "note": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "internal_handler.RegisterMovementRequest": {
+            "type": "object",
+            "required": [
+                "amount",
+                "kind",
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "in",
+                        "out"
+                    ]
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "internal_handler.RegisterOpenRequest": {
+            "type": "object",
+            "required": [
+                "store_id"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "opening_float": {
+                    "description": "釣銭準備金",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "store_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.ReservationRequest": {
+            "type": "object",
+            "required": [
+                "course_id",
+                "customer_id",
+                "staff_id",
+                "start_at",
+                "store_id"
+            ],
+            "properties": {
+                "course_id": {
+                    "type": "integer"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "end_at": {
+                    "description": "省略時はコースの施術時間から計算",
+                    "type": "string"
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "staff_id": {
+                    "type": "integer"
+                },
+                "start_at": {
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.ReservationStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "memo": {
+                    "description": "完了時は来店記録のメモになる",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "booked",
+                        "confirmed",
+                        "arrived",
+                        "completed",
+                        "cancelled",
+                        "no_show"
+                    ]
+                },
+                "ticket_id": {
+                    "description": "完了時に消化する回数券。null なら都度払い",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.RetentionRow": {
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "integer"
+                },
+                "f_score": {
+                    "type": "integer"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "frequency": {
+                    "type": "integer"
+                },
+                "interval_days": {
+                    "description": "いつもの来店間隔（日）。来店1回なら null",
+                    "type": "integer"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "last_visit_at": {
+                    "type": "string"
+                },
+                "m_score": {
+                    "type": "integer"
+                },
+                "monetary": {
+                    "type": "integer"
+                },
+                "overdue": {
+                    "description": "いつもの間隔を過ぎても来店がない",
+                    "type": "boolean"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "r_score": {
+                    "type": "integer"
+                },
+                "recency_days": {
+                    "type": "integer"
+                },
+                "segment": {
+                    "description": "Segment* 定数のいずれか",
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.RevenueRow": {
+            "type": "object",
+            "properties": {
+                "net_revenue": {
+                    "description": "売上（税抜）",
+                    "type": "integer"
+                },
+                "period": {
+                    "description": "期間の開始日 YYYY-MM-DD",
+                    "type": "string"
+                },
+                "revenue": {
+                    "description": "売上（税込）",
+                    "type": "integer"
+                },
+                "sales_count": {
+                    "type": "integer"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "tax_total": {
+                    "description": "うち消費税",
+                    "type": "integer"
+                },
+                "visits": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.SaleLineRequest": {
+            "type": "object",
+            "required": [
+                "kind"
+            ],
+            "properties": {
+                "course_id": {
+                    "description": "ticket: 販売するコース（ticket_id がなければ回数券を発行する）/ treatment: 施術したコース",
+                    "type": "integer"
+                },
+                "description": {
+                    "description": "省略時はコース名（retail は必須）",
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "discount_amount": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "ticket",
+                        "treatment",
+                        "retail"
+                    ]
+                },
+                "quantity": {
+                    "description": "省略時は1",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "tax_category": {
+                    "description": "省略時はコースの税率区分（コースがなければ標準税率）",
+                    "type": "string",
+                    "enum": [
+                        "standard",
+                        "reduced"
+                    ]
+                },
+                "ticket_id": {
+                    "description": "ticket: 発行済みの回数券の代金を受け取る場合",
+                    "type": "integer"
+                },
+                "unit_price": {
+                    "description": "省略時はコースの販売価格（retail は必須）",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "visit_id": {
+                    "description": "treatment: 支払対象の来店記録",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.SalePaymentRequest": {
+            "type": "object",
+            "required": [
+                "method"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "method": {
+                    "type": "string",
+                    "enum": [
+                        "cash",
+                        "card",
+                        "qr",
+                        "other"
+                    ]
+                }
+            }
+        },
+        "internal_handler.SaleRequest": {
+            "type": "object",
+            "required": [
+                "lines",
+                "payments",
+                "store_id"
+            ],
+            "properties": {
+                "customer_id": {
+                    "type": "integer"
+                },
+                "discount_amount": {
+                    "description": "会計全体の値引き",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "lines": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/internal_handler.SaleLineRequest"
+                    }
+                },
+                "memo": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "payments": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/internal_handler.SalePaymentRequest"
+                    }
+                },
+                "sold_at": {
+                    "description": "省略時は現在時刻",
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.SaleVoidRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "internal_handler.ShiftRequest": {
+            "type": "object",
+            "required": [
+                "date",
+                "end_time",
+                "start_time",
+                "user_id"
+            ],
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "end_time": {
+                    "type": "string"
+                },
+                "start_time": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.Slot": {
+            "type": "object",
+            "properties": {
+                "end_at": {
+                    "type": "string"
+                },
+                "staff_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "start_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.StaffProductivityRow": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "revenue": {
+                    "description": "会計した売上（税込）",
+                    "type": "integer"
+                },
+                "sales_count": {
+                    "description": "会計した件数",
+                    "type": "integer"
+                },
+                "tickets_sold": {
+                    "description": "販売した回数券",
+                    "type": "integer"
+                },
+                "unique_customers": {
+                    "description": "担当した顧客の人数",
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                },
+                "visits": {
+                    "description": "担当した来店",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.StoreHolidayRequest": {
+            "type": "object",
+            "required": [
+                "date"
+            ],
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.TicketAdjustmentRequest": {
+            "type": "object",
+            "required": [
+                "kind",
+                "reason_code"
+            ],
+            "properties": {
+                "expires_at": {
+                    "description": "reopen のみ。新しい有効期限",
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "complimentary",
+                        "correction",
+                        "refund",
+                        "reopen"
+                    ]
+                },
+                "note": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "reason_code": {
+                    "type": "string",
+                    "enum": [
+                        "apology",
+                        "campaign",
+                        "input_error",
+                        "customer_request",
+                        "migration",
+                        "other"
+                    ]
+                },
+                "refund_amount": {
+                    "description": "refund のみ。省略時は返金済みを除いた支払額を回数で按分（上限は支払額 - 返金済みの額）",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "refund_method": {
+                    "description": "refund のみ。返金方法（省略時は cash）",
+                    "type": "string",
+                    "enum": [
+                        "cash",
+                        "card",
+                        "qr",
+                        "other"
+                    ]
+                },
+                "sessions": {
+                    "description": "追加・修正する回数（kind ごとの意味は AdjustTicketHandler を参照）",
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.TicketMemberRequest": {
+            "type": "object",
+            "required": [
+                "customer_id"
+            ],
+            "properties": {
+                "customer_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.TicketPurchaseRequest": {
+            "type": "object",
+            "required": [
+                "course_id",
+                "customer_id",
+                "payment_method",
+                "store_id"
+            ],
+            "properties": {
+                "course_id": {
+                    "type": "integer"
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "payment_method": {
+                    "type": "string",
+                    "enum": [
+                        "cash",
+                        "card",
+                        "qr",
+                        "other"
+                    ]
+                },
+                "price_paid": {
+                    "description": "省略時はコースの販売価格",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "purchased_at": {
+                    "description": "省略時は現在時刻",
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.TicketSellThroughRow": {
+            "type": "object",
+            "properties": {
+                "completed": {
+                    "description": "使い切ったチケット",
+                    "type": "integer"
+                },
+                "course_id": {
+                    "type": "integer"
+                },
+                "course_name": {
+                    "type": "string"
+                },
+                "expired": {
+                    "description": "期限切れになったチケット",
+                    "type": "integer"
+                },
+                "expired_sessions": {
+                    "description": "期限切れで失効した回数",
+                    "type": "integer"
+                },
+                "revenue": {
+                    "type": "integer"
+                },
+                "sell_through": {
+                    "description": "SessionsUsed / SessionsSold",
+                    "type": "number"
+                },
+                "sessions_sold": {
+                    "type": "integer"
+                },
+                "sessions_used": {
+                    "type": "integer"
+                },
+                "tickets_sold": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.TicketTransferRequest": {
+            "type": "object",
+            "required": [
+                "reason_code",
+                "to_customer_id"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "reason_code": {
+                    "type": "string",
+                    "enum": [
+                        "apology",
+                        "campaign",
+                        "input_error",
+                        "customer_request",
+                        "migration",
+                        "other"
+                    ]
+                },
+                "sessions": {
+                    "description": "省略時は残り回数すべて",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "to_customer_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.TicketUpdateRequest": {
+            "type": "object",
+            "required": [
+                "reason_code",
+                "total_count"
+            ],
+            "properties": {
+                "note": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "reason_code": {
+                    "type": "string",
+                    "enum": [
+                        "apology",
+                        "campaign",
+                        "input_error",
+                        "customer_request",
+                        "migration",
+                        "other"
+                    ]
+                },
+                "total_count": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "internal_handler.TicketView": {
+            "type": "object",
+            "properties": {
+                "course": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Course"
+                },
+                "course_id": {
+                    "description": "どのコースのチケットか",
+                    "type": "integer"
+                },
+                "course_name": {
+                    "description": "購入時のコースのスナップショット（後からコースを変更・削除しても購入内容は変わらない）",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "current_count": {
+                    "description": "現在までに消化した回数 (来店ごとにインクリメント)",
+                    "type": "integer"
+                },
+                "customer": {
+                    "description": "リレーション",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                        }
+                    ]
+                },
+                "customer_id": {
+                    "description": "購入した顧客のID",
+                    "type": "integer"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "expired_at": {
+                    "description": "期限切れ処理（job.ExpireTickets）の結果。未使用の残回数と、その金額（支払額を回数で按分）をレポート用に記録する",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "有効期限。ExpiresAt を過ぎたチケットは消化できない。nil なら無期限",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_completed": {
+                    "description": "全回数を使い切ったかどうかのフラグ",
+                    "type": "boolean"
+                },
+                "list_price": {
+                    "description": "購入時のコースの販売価格",
+                    "type": "integer"
+                },
+                "payment_method": {
+                    "description": "支払方法 (Payment* 定数のいずれか)",
+                    "type": "string"
+                },
+                "price_paid": {
+                    "description": "実際に支払った金額（値引き後）",
+                    "type": "integer"
+                },
+                "purchased_at": {
+                    "description": "購入日時",
+                    "type": "string"
+                },
+                "refunded_amount": {
+                    "description": "返金済みの金額の合計（調整の refund）",
+                    "type": "integer"
+                },
+                "remaining": {
+                    "type": "integer"
+                },
+                "shared_with": {
+                    "description": "家族などで共有する場合の利用者。購入者(CustomerID)に加えて、ここに登録された顧客も消化できる",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                    }
+                },
+                "sold_by_id": {
+                    "description": "販売したスタッフ(User)のID",
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "active / completed / expired",
+                    "type": "string"
+                },
+                "store_id": {
+                    "description": "購入・発行した店舗のID",
+                    "type": "integer"
+                },
+                "total_count": {
+                    "description": "購入時の最大回数 (Courseマスタからコピー)",
+                    "type": "integer"
+                },
+                "transferred_from_id": {
+                    "description": "譲渡で作成されたチケットの場合、譲渡元のチケットID",
+                    "type": "integer"
+                },
+                "unused_amount": {
+                    "type": "integer"
+                },
+                "unused_count": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.VisitVoidRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "salon-app_backend_internal_model.Course": {
+            "type": "object",
+            "properties": {
+                "buffer_minutes": {
+                    "description": "施術後の片付け・準備時間（分）。この間は次の予約を入れない",
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "duration_minutes": {
+                    "description": "施術時間（分）。予約の終了時刻・空き枠の計算に使う",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "コース名称 (例: \"全身脱毛 5回パック\", \"カット\")",
+                    "type": "string"
+                },
+                "online_bookable": {
+                    "description": "オンライン予約（/api/public）で選択できるか",
+                    "type": "boolean"
+                },
+                "price": {
+                    "description": "販売価格。税込か税抜かは店舗の PriceTaxMode に従う",
+                    "type": "integer"
+                },
+                "store": {
+                    "description": "店舗情報へのリレーション",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Store"
+                        }
+                    ]
+                },
+                "store_id": {
+                    "description": "所属店舗ID。多店舗展開時に使用",
+                    "type": "integer"
+                },
+                "tax_category": {
+                    "description": "税率区分 (TaxStandard / TaxReduced)",
+                    "type": "string"
+                },
+                "total_count": {
+                    "description": "規定回数。単発は1、回数券は5や10などを設定",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "validity_days": {
+                    "description": "回数券の有効期間（購入日から何日間）。0 なら無期限",
+                    "type": "integer"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.Customer": {
+            "type": "object",
+            "properties": {
+                "address1": {
+                    "description": "市区町村・町域",
+                    "type": "string"
+                },
+                "address2": {
+                    "description": "ビル・マンション名",
+                    "type": "string"
+                },
+                "birth_date": {
+                    "description": "日付型がおすすめ",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "first_name_kana": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_provisional": {
+                    "description": "オンライン予約で既存顧客と照合できなかった場合に作成される仮登録の顧客",
+                    "type": "boolean"
+                },
+                "last_name": {
+                    "description": "名前（フリガナ含め分けているのはGood!）",
+                    "type": "string"
+                },
+                "last_name_kana": {
+                    "type": "string"
+                },
+                "phone": {
+                    "description": "uintからstringへ変更",
+                    "type": "string"
+                },
+                "pref_name": {
+                    "description": "文字列で持つパターン",
+                    "type": "string"
+                },
+                "sex": {
+                    "description": "属性情報",
+                    "type": "string"
+                },
+                "store": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Store"
+                },
+                "store_id": {
+                    "description": "店舗紐付け",
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "zip_code": {
+                    "description": "住所（ポストくん構成）",
+                    "type": "string"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.LoginThrottle": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "failures": {
+                    "description": "連続失敗回数",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "last_failed_at": {
+                    "type": "string"
+                },
+                "locked_until": {
+                    "description": "この時刻まではログイン不可",
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.PostalCode": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "description": "市区町村",
+                    "type": "string"
+                },
+                "city_kana": {
+                    "type": "string"
+                },
+                "pref_kana": {
+                    "type": "string"
+                },
+                "pref_name": {
+                    "description": "都道府県（Customer.PrefName と同じ表記）",
+                    "type": "string"
+                },
+                "town": {
+                    "description": "町域。「以下に掲載がない場合」などは空",
+                    "type": "string"
+                },
+                "town_kana": {
+                    "type": "string"
+                },
+                "zip_code": {
+                    "description": "ハイフンなしの7桁",
+                    "type": "string"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.RegisterMovement": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "RegisterCashIn / RegisterCashOut",
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "session_id": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "操作したスタッフ(User)のID",
+                    "type": "integer"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.RegisterSession": {
+            "type": "object",
+            "properties": {
+                "cash_in": {
+                    "description": "入金の合計",
+                    "type": "integer"
+                },
+                "cash_out": {
+                    "description": "出金の合計",
+                    "type": "integer"
+                },
+                "cash_refunds": {
+                    "description": "期間中のチケット返金のうち現金の分",
+                    "type": "integer"
+                },
+                "cash_sales": {
+                    "description": "集計（開いている間は照会のたびに計算し、締めで確定して保存する）",
+                    "type": "integer"
+                },
+                "cash_ticket_sales": {
+                    "description": "期間中に会計を通さず登録した回数券（POST /tickets）の現金の支払額",
+                    "type": "integer"
+                },
+                "cash_voids": {
+                    "description": "期間中に取り消した売上の現金支払（返した現金）",
+                    "type": "integer"
+                },
+                "closed_at": {
+                    "type": "string"
+                },
+                "closed_by_id": {
+                    "type": "integer"
+                },
+                "counted_cash": {
+                    "description": "締めで数えた現金",
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "expected_cash": {
+                    "description": "OpeningFloat + CashSales + CashTicketSales - CashVoids - CashRefunds + CashIn - CashOut",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "movements": {
+                    "description": "リレーション",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/salon-app_backend_internal_model.RegisterMovement"
+                    }
+                },
+                "note": {
+                    "type": "string"
+                },
+                "opened_at": {
+                    "type": "string"
+                },
+                "opened_by_id": {
+                    "type": "integer"
+                },
+                "opening_float": {
+                    "description": "釣銭準備金",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "variance": {
+                    "description": "CountedCash - ExpectedCash（過不足）",
+                    "type": "integer"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.Reservation": {
+            "type": "object",
+            "properties": {
+                "buffer_minutes": {
+                    "description": "予約時のコースの準備時間。EndAt からこの分数も枠を占有する",
+                    "type": "integer"
+                },
+                "cancelled_at": {
+                    "description": "キャンセル・無断キャンセルの記録日時",
+                    "type": "string"
+                },
+                "course": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Course"
+                },
+                "course_id": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "customer": {
+                    "description": "リレーション",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                        }
+                    ]
+                },
+                "customer_id": {
+                    "type": "integer"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "end_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "source": {
+                    "description": "staff: 店頭・電話, online: オンライン予約",
+                    "type": "string"
+                },
+                "staff": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.User"
+                },
+                "staff_id": {
+                    "description": "担当スタッフ(User)",
+                    "type": "integer"
+                },
+                "start_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "store": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Store"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "visit": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Visit"
+                },
+                "visit_id": {
+                    "description": "完了時に作成した来店記録",
+                    "type": "integer"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.Sale": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "customer": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                },
+                "customer_id": {
+                    "description": "顧客が特定できない物販のみの会計では null",
+                    "type": "integer"
+                },
+                "customer_name": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "discount_amount": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "invoice_number": {
+                    "type": "string"
+                },
+                "issuer_name": {
+                    "description": "領収書の記載内容のスナップショット（後から店舗名・顧客名が変わっても同じ領収書を再発行できる）",
+                    "type": "string"
+                },
+                "lines": {
+                    "description": "リレーション",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/salon-app_backend_internal_model.SaleLine"
+                    }
+                },
+                "memo": {
+                    "type": "string"
+                },
+                "payment_method": {
+                    "description": "支払方法が1つならその方法、複数なら split",
+                    "type": "string"
+                },
+                "payments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/salon-app_backend_internal_model.SalePayment"
+                    }
+                },
+                "sold_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "subtotal": {
+                    "type": "integer"
+                },
+                "tax_mode": {
+                    "description": "会計時の店舗の価格の表示方法（税込 / 税抜）",
+                    "type": "string"
+                },
+                "tax_total": {
+                    "description": "消費税額の合計（税込価格の場合は内税）",
+                    "type": "integer"
+                },
+                "taxes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/salon-app_backend_internal_model.SaleTax"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "会計したスタッフ(User)のID",
+                    "type": "integer"
+                },
+                "void_reason": {
+                    "type": "string"
+                },
+                "voided_at": {
+                    "description": "取消（void）。取り消した売上も削除せずに残す",
+                    "type": "string"
+                },
+                "voided_by_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.SaleLine": {
             "type": "object",
             "properties": {
-                "time": {
+                "amount": {
+                    "type": "integer"
+                },
+                "course_id": {
+                    "type": "integer"
+                },
+                "createdAt": {
                     "type": "string"
                 },
-                "valid": {
-                    "description": "Valid is true if Time is not NULL",
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "discount_amount": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "issued_by_sale": {
+                    "description": "TicketID のチケットがこの会計で発行されたものか（取消時に削除するのはこのチケットだけ）",
                     "type": "boolean"
+                },
+                "kind": {
+                    "description": "SaleLine* 定数のいずれか",
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "sale_id": {
+                    "type": "integer"
+                },
+                "tax_category": {
+                    "description": "税率区分 (TaxStandard / TaxReduced)",
+                    "type": "string"
+                },
+                "tax_rate": {
+                    "description": "適用税率 (%)",
+                    "type": "integer"
+                },
+                "ticket_id": {
+                    "description": "回数券の販売の場合、販売したチケット",
+                    "type": "integer"
+                },
+                "ticket_remaining": {
+                    "description": "会計時点のチケットの残り回数（領収書に記載する。回数券・回数券利用の施術のみ）",
+                    "type": "integer"
+                },
+                "unit_price": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "visit_id": {
+                    "description": "施術の場合、支払対象の来店記録",
+                    "type": "integer"
                 }
             }
         },
-        "salon-app_backend_internal_model.Course": {
+        "salon-app_backend_internal_model.SalePayment": {
             "type": "object",
             "properties": {
+                "amount": {
+                    "type": "integer"
+                },
                 "createdAt": {
                     "type": "string"
                 },
@@ -619,28 +5111,40 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
-                "name": {
-                    "description": "コース名称 (例: \"全身脱毛 5回パック\", \"カット\")",
+                "method": {
+                    "description": "Payment* 定数のいずれか（split 以外）",
                     "type": "string"
                 },
-                "price": {
-                    "description": "販売価格 (税込み/税抜きは運用に合わせる)",
+                "sale_id": {
                     "type": "integer"
                 },
-                "store": {
-                    "description": "店舗情報へのリレーション",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/salon-app_backend_internal_model.Store"
-                        }
-                    ]
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.SaleTax": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
                 },
-                "store_id": {
-                    "description": "所属店舗ID。多店舗展開時に使用",
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "total_count": {
-                    "description": "規定回数。単発は1、回数券は5や10などを設定",
+                "rate": {
+                    "type": "integer"
+                },
+                "sale_id": {
+                    "type": "integer"
+                },
+                "tax": {
+                    "type": "integer"
+                },
+                "taxable": {
                     "type": "integer"
                 },
                 "updatedAt": {
@@ -648,87 +5152,141 @@ const docTemplate = `{
                 }
             }
         },
-        "salon-app_backend_internal_model.Customer": {
+        "salon-app_backend_internal_model.StaffShift": {
             "type": "object",
             "properties": {
-                "address1": {
-                    "description": "市区町村・町域",
+                "createdAt": {
                     "type": "string"
                 },
-                "address2": {
-                    "description": "ビル・マンション名",
+                "date": {
                     "type": "string"
                 },
-                "birth_date": {
-                    "description": "日付型がおすすめ",
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "end_time": {
+                    "description": "\"HH:MM\"",
                     "type": "string"
                 },
-                "createdAt": {
+                "id": {
+                    "type": "integer"
+                },
+                "start_time": {
+                    "description": "\"HH:MM\"",
                     "type": "string"
                 },
-                "deletedAt": {
-                    "$ref": "#/definitions/gorm.DeletedAt"
+                "store_id": {
+                    "type": "integer"
                 },
-                "first_name": {
+                "updatedAt": {
                     "type": "string"
                 },
-                "first_name_kana": {
+                "user": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.User"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.Store": {
+            "type": "object",
+            "properties": {
+                "capacity": {
+                    "description": "同時に施術できる席数。予約の重複チェックに使う (0 は無制限)",
+                    "type": "integer"
+                },
+                "close_time": {
+                    "description": "閉店時刻 \"HH:MM\"",
                     "type": "string"
                 },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "last_name": {
-                    "description": "名前（フリガナ含め分けているのはGood!）",
+                "invoice_number": {
+                    "description": "消費税・インボイス（適格請求書）の設定",
                     "type": "string"
                 },
-                "last_name_kana": {
+                "name": {
+                    "description": "店舗名",
                     "type": "string"
                 },
-                "phone": {
-                    "description": "uintからstringへ変更",
+                "open_time": {
+                    "description": "営業時間（空き枠検索に使用）",
                     "type": "string"
                 },
-                "pref_name": {
-                    "description": "文字列で持つパターン",
+                "price_tax_mode": {
+                    "description": "価格の表示方法 (TaxInclusive / TaxExclusive)",
                     "type": "string"
                 },
-                "sex": {
-                    "description": "属性情報",
+                "reduced_tax_rate": {
+                    "description": "軽減税率 (%)",
+                    "type": "integer"
+                },
+                "regular_holidays": {
+                    "description": "定休日の曜日。0=日〜6=土 をカンマ区切り (例: \"1,2\")",
                     "type": "string"
                 },
-                "store": {
-                    "$ref": "#/definitions/salon-app_backend_internal_model.Store"
+                "retention_at_risk_days": {
+                    "description": "最終来店からこの日数が経つと離反予備軍 (at_risk)",
+                    "type": "integer"
                 },
-                "store_id": {
-                    "description": "店舗紐付け",
+                "retention_lapsed_days": {
+                    "description": "最終来店からこの日数が経つと離反 (lapsed)",
                     "type": "integer"
                 },
-                "updatedAt": {
+                "retention_loyal_visits": {
+                    "description": "顧客の離反分析（RFM）のしきい値",
+                    "type": "integer"
+                },
+                "retention_overdue_percent": {
+                    "description": "いつもの来店間隔のこの割合(%)を過ぎると来店遅れ",
+                    "type": "integer"
+                },
+                "slot_minutes": {
+                    "description": "予約開始時刻の刻み（分）",
+                    "type": "integer"
+                },
+                "standard_tax_rate": {
+                    "description": "標準税率 (%)",
+                    "type": "integer"
+                },
+                "tax_rounding": {
+                    "description": "消費税の端数処理 (RoundFloor / RoundHalfUp / RoundCeil)。税率ごとに1会計1回",
                     "type": "string"
                 },
-                "zip_code": {
-                    "description": "住所（ポストくん構成）",
+                "updatedAt": {
                     "type": "string"
                 }
             }
         },
-        "salon-app_backend_internal_model.Store": {
+        "salon-app_backend_internal_model.StoreHoliday": {
             "type": "object",
             "properties": {
                 "createdAt": {
                     "type": "string"
                 },
+                "date": {
+                    "type": "string"
+                },
                 "deletedAt": {
                     "$ref": "#/definitions/gorm.DeletedAt"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "name": {
-                    "description": "店舗名",
+                "reason": {
                     "type": "string"
                 },
+                "store_id": {
+                    "type": "integer"
+                },
                 "updatedAt": {
                     "type": "string"
                 }
@@ -744,6 +5302,10 @@ const docTemplate = `{
                     "description": "どのコースのチケットか",
                     "type": "integer"
                 },
+                "course_name": {
+                    "description": "購入時のコースのスナップショット（後からコースを変更・削除しても購入内容は変わらない）",
+                    "type": "string"
+                },
                 "createdAt": {
                     "type": "string"
                 },
@@ -766,6 +5328,14 @@ const docTemplate = `{
                 "deletedAt": {
                     "$ref": "#/definitions/gorm.DeletedAt"
                 },
+                "expired_at": {
+                    "description": "期限切れ処理（job.ExpireTickets）の結果。未使用の残回数と、その金額（支払額を回数で按分）をレポート用に記録する",
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "有効期限。ExpiresAt を過ぎたチケットは消化できない。nil なら無期限",
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
@@ -773,6 +5343,37 @@ const docTemplate = `{
                     "description": "全回数を使い切ったかどうかのフラグ",
                     "type": "boolean"
                 },
+                "list_price": {
+                    "description": "購入時のコースの販売価格",
+                    "type": "integer"
+                },
+                "payment_method": {
+                    "description": "支払方法 (Payment* 定数のいずれか)",
+                    "type": "string"
+                },
+                "price_paid": {
+                    "description": "実際に支払った金額（値引き後）",
+                    "type": "integer"
+                },
+                "purchased_at": {
+                    "description": "購入日時",
+                    "type": "string"
+                },
+                "refunded_amount": {
+                    "description": "返金済みの金額の合計（調整の refund）",
+                    "type": "integer"
+                },
+                "shared_with": {
+                    "description": "家族などで共有する場合の利用者。購入者(CustomerID)に加えて、ここに登録された顧客も消化できる",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/salon-app_backend_internal_model.Customer"
+                    }
+                },
+                "sold_by_id": {
+                    "description": "販売したスタッフ(User)のID",
+                    "type": "integer"
+                },
                 "store_id": {
                     "description": "購入・発行した店舗のID",
                     "type": "integer"
@@ -781,8 +5382,85 @@ const docTemplate = `{
                     "description": "購入時の最大回数 (Courseマスタからコピー)",
                     "type": "integer"
                 },
+                "transferred_from_id": {
+                    "description": "譲渡で作成されたチケットの場合、譲渡元のチケットID",
+                    "type": "integer"
+                },
+                "unused_amount": {
+                    "type": "integer"
+                },
+                "unused_count": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "salon-app_backend_internal_model.TicketAdjustment": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "current_after": {
+                    "type": "integer"
+                },
+                "current_before": {
+                    "type": "integer"
+                },
+                "deletedAt": {
+                    "$ref": "#/definitions/gorm.DeletedAt"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "Adjust* 定数のいずれか",
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "reason_code": {
+                    "description": "Reason* 定数のいずれか",
+                    "type": "string"
+                },
+                "refund_amount": {
+                    "description": "返金額（refund のみ）",
+                    "type": "integer"
+                },
+                "refund_method": {
+                    "description": "返金方法（refund のみ。Payment* 定数のいずれか）",
+                    "type": "string"
+                },
+                "store_id": {
+                    "type": "integer"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                },
+                "total_after": {
+                    "type": "integer"
+                },
+                "total_before": {
+                    "description": "変更前後の回数（差分ではなく両方を保存しておく）",
+                    "type": "integer"
+                },
                 "updatedAt": {
                     "type": "string"
+                },
+                "user": {
+                    "description": "リレーション",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/salon-app_backend_internal_model.User"
+                        }
+                    ]
+                },
+                "user_id": {
+                    "description": "操作したスタッフ(User)のID",
+                    "type": "integer"
                 }
             }
         },
@@ -819,6 +5497,9 @@ const docTemplate = `{
                 "store_id": {
                     "type": "integer"
                 },
+                "totp_enabled": {
+                    "type": "boolean"
+                },
                 "updatedAt": {
                     "type": "string"
                 }
@@ -859,6 +5540,13 @@ const docTemplate = `{
                     "description": "施術内容や顧客の反応などのメモ",
                     "type": "string"
                 },
+                "staff_id": {
+                    "description": "担当スタッフ(User)のID。未指定の場合は null",
+                    "type": "integer"
+                },
+                "store": {
+                    "$ref": "#/definitions/salon-app_backend_internal_model.Store"
+                },
                 "store_id": {
                     "description": "来店した店舗のID",
                     "type": "integer"
@@ -874,7 +5562,18 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "visit_count": {
-                    "description": "VisitCount:\nこの来店がそのチケットにとって「何回目」だったかをスナップショットとして記録。\nチケット残数計算の履歴の整合性を保つために保持。",
+                    "description": "VisitCount:\nこの来店がそのチケットにとって「何回目」だったかをスナップショットとして記録。\nチケット残数計算の履歴の整合性を保つために保持。\n前の来店を取り消し・付け替えた場合は、後の来店の番号を繰り上げる（releaseTicket）。",
+                    "type": "integer"
+                },
+                "void_reason": {
+                    "type": "string"
+                },
+                "voided_at": {
+                    "description": "取消（void）。取り消した来店記録も削除せずに残し、消化したチケットの回数は戻す",
+                    "type": "string"
+                },
+                "voided_by_id": {
+                    "description": "取り消したスタッフ(User)のID",
                     "type": "integer"
                 }
             }
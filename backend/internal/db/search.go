@@ -0,0 +1,51 @@
+package db
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+	"salon-app/backend/internal/model"
+)
+
+// TrigramSearch は pg_trgm 拡張が使えるか（MigrateCustomerSearch で設定する）。
+// 使えなければ顧客検索は似た表記（類似度）を含めず、部分一致（LIKE）だけで行う。
+var TrigramSearch bool
+
+// MigrateCustomerSearch は顧客検索のための pg_trgm 拡張とインデックスを作成し、
+// 検索用のカラムが空の顧客（カラム追加前に登録された顧客）を埋める。AutoMigrate の後に呼ぶ。
+// 拡張の作成にはデータベースの CREATE 権限（PostgreSQL 13 未満はスーパーユーザー）が必要で、
+// 作成できなければログに残して LIKE だけの検索で起動する。
+func MigrateCustomerSearch() error {
+	if err := DB.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Printf("pg_trgm 拡張を作成できないため、顧客検索は部分一致（LIKE）だけで行います: %v", err)
+	} else {
+		stmts := []string{
+			"CREATE INDEX IF NOT EXISTS idx_customers_search_name_trgm ON customers USING gin (search_name gin_trgm_ops)",
+			"CREATE INDEX IF NOT EXISTS idx_customers_search_phone_trgm ON customers USING gin (search_phone gin_trgm_ops)",
+			"CREATE INDEX IF NOT EXISTS idx_customers_search_email_trgm ON customers USING gin (search_email gin_trgm_ops)",
+		}
+		for _, s := range stmts {
+			if err := DB.Exec(s).Error; err != nil {
+				return err
+			}
+		}
+		TrigramSearch = true
+	}
+
+	var customers []model.Customer
+	return DB.Unscoped().Where("search_name = ''").FindInBatches(&customers, 500, func(*gorm.DB, int) error {
+		for i := range customers {
+			cu := &customers[i]
+			cu.RefreshSearchColumns()
+			// UpdateColumns は updated_at を変えない
+			if err := DB.Unscoped().Model(cu).UpdateColumns(map[string]interface{}{
+				"search_name":  cu.SearchName,
+				"search_phone": cu.SearchPhone,
+				"search_email": cu.SearchEmail,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
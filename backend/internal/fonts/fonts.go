@@ -0,0 +1,8 @@
+// Package fonts はPDF出力に埋め込む日本語フォントを提供する。
+// M+ 1p Regular（M+ FONTS PROJECT、ライセンスは LICENSE_E を参照）。
+package fonts
+
+import _ "embed"
+
+//go:embed mplus-1p-regular.ttf
+var MPlus1pRegular []byte
@@ -0,0 +1,348 @@
+package handler
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// 集計API（ダッシュボード用）。
+// 集計はすべて SQL で行い、取り消した売上・来店記録は含めない。期間・店舗の指定は parseAnalyticsRange を参照。
+
+// RevenueRow は店舗・期間ごとの売上と来店数
+type RevenueRow struct {
+	StoreID    uint   `json:"store_id"`
+	Period     string `json:"period"` // 期間の開始日 YYYY-MM-DD
+	SalesCount int    `json:"sales_count"`
+	Revenue    int    `json:"revenue"`     // 売上（税込）
+	TaxTotal   int    `json:"tax_total"`   // うち消費税
+	NetRevenue int    `json:"net_revenue"` // 売上（税抜）
+	Visits     int    `json:"visits"`
+}
+
+// CustomerMixRow は店舗・期間ごとの新規・リピーターの人数
+type CustomerMixRow struct {
+	StoreID   uint   `json:"store_id"`
+	Period    string `json:"period"`
+	New       int    `json:"new"`       // この期間に初めて来店した顧客
+	Returning int    `json:"returning"` // それ以前にも来店がある顧客
+	Visits    int    `json:"visits"`
+}
+
+// CoursePopularityRow はコースごとの来店数と回数券の販売数
+type CoursePopularityRow struct {
+	CourseID        uint   `json:"course_id"`
+	CourseName      string `json:"course_name"`
+	StoreID         uint   `json:"store_id"`
+	Visits          int    `json:"visits"`
+	UniqueCustomers int    `json:"unique_customers"`
+	TicketsSold     int    `json:"tickets_sold"`
+	TicketRevenue   int    `json:"ticket_revenue"`
+}
+
+// StaffProductivityRow はスタッフごとの担当来店数と会計・販売の実績
+type StaffProductivityRow struct {
+	UserID          uint   `json:"user_id"`
+	Name            string `json:"name"`
+	Visits          int    `json:"visits"`           // 担当した来店
+	UniqueCustomers int    `json:"unique_customers"` // 担当した顧客の人数
+	SalesCount      int    `json:"sales_count"`      // 会計した件数
+	Revenue         int    `json:"revenue"`          // 会計した売上（税込）
+	TicketsSold     int    `json:"tickets_sold"`     // 販売した回数券
+}
+
+// TicketSellThroughRow は期間中に販売した回数券のコースごとの消化状況
+type TicketSellThroughRow struct {
+	CourseID        uint    `json:"course_id"`
+	CourseName      string  `json:"course_name"`
+	TicketsSold     int     `json:"tickets_sold"`
+	Revenue         int     `json:"revenue"`
+	SessionsSold    int     `json:"sessions_sold"`
+	SessionsUsed    int     `json:"sessions_used"`
+	Completed       int     `json:"completed"`        // 使い切ったチケット
+	Expired         int     `json:"expired"`          // 期限切れになったチケット
+	ExpiredSessions int     `json:"expired_sessions"` // 期限切れで失効した回数
+	SellThrough     float64 `json:"sell_through"`     // SessionsUsed / SessionsSold
+}
+
+// TicketLiabilityRow は未消化の回数券の残高（前受金）
+type TicketLiabilityRow struct {
+	StoreID           uint   `json:"store_id"`
+	CourseID          uint   `json:"course_id"`
+	CourseName        string `json:"course_name"`
+	Tickets           int    `json:"tickets"`
+	RemainingSessions int    `json:"remaining_sessions"`
+	Amount            int    `json:"amount"` // 残り回数分の金額（返金後の支払額を回数で按分）
+}
+
+// @Summary      売上・来店数の推移
+// @Description  店舗・期間（日・週・月）ごとの売上（取り消した売上を除く）と来店数（取り消した来店を除く）
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        period    query  string  false  "day（既定）/ week / month"
+// @Param        from      query  string  false  "開始日 YYYY-MM-DD（省略時は30日前）"
+// @Param        to        query  string  false  "終了日 YYYY-MM-DD（省略時は今日）"
+// @Param        store_id  query  int     false  "店舗ID"
+// @Success      200 {array} RevenueRow
+// @Router       /analytics/revenue [get]
+func GetRevenueAnalyticsHandler(c *gin.Context) {
+    r, ok := parseAnalyticsRange(c)
+    if !ok {
+        return
+    }
+    period := c.DefaultQuery("period", "day")
+    salePeriod, ok := periodExpr(period, "sold_at")
+    if !ok {
+        c.JSON(400, gin.H{"error": "period は day / week / month のいずれかです"})
+        return
+    }
+    visitPeriod, _ := periodExpr(period, "created_at")
+
+    var sales []RevenueRow
+    if err := db.DB.Model(&model.Sale{}).Scopes(r.stores(c, "store_id"), r.between("sold_at")).
+        Where("status = ?", model.SaleCompleted).
+        Select("store_id, " + salePeriod + " AS period, COUNT(*) AS sales_count, SUM(total) AS revenue, SUM(tax_total) AS tax_total").
+        Group("store_id, period").Scan(&sales).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to aggregate sales"})
+        return
+    }
+    var visits []RevenueRow
+    if err := db.DB.Model(&model.Visit{}).Scopes(r.stores(c, "store_id"), r.between("created_at")).
+        Where("voided_at IS NULL").
+        Select("store_id, " + visitPeriod + " AS period, COUNT(*) AS visits").
+        Group("store_id, period").Scan(&visits).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to aggregate visits"})
+        return
+    }
+
+    // 売上と来店を店舗・期間ごとにまとめる（どちらか一方しかない期間もある）
+    type key struct {
+        storeID uint
+        period  string
+    }
+    rows := map[key]*RevenueRow{}
+    for i := range sales {
+        s := &sales[i]
+        s.NetRevenue = s.Revenue - s.TaxTotal
+        rows[key{s.StoreID, s.Period}] = s
+    }
+    for _, v := range visits {
+        if row, ok := rows[key{v.StoreID, v.Period}]; ok {
+            row.Visits = v.Visits
+        } else {
+            rows[key{v.StoreID, v.Period}] = &v
+        }
+    }
+    result := make([]RevenueRow, 0, len(rows))
+    for _, row := range rows {
+        result = append(result, *row)
+    }
+    sort.Slice(result, func(i, j int) bool {
+        if result[i].Period != result[j].Period {
+            return result[i].Period < result[j].Period
+        }
+        return result[i].StoreID < result[j].StoreID
+    })
+    c.JSON(200, result)
+}
+
+// @Summary      新規・リピーター
+// @Description  店舗・期間ごとに来店した顧客を、その期間に初来店した新規と、それ以前にも来店があるリピーターに分けて数えます
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        period    query  string  false  "day / week / month（既定）"
+// @Param        from      query  string  false  "開始日 YYYY-MM-DD（省略時は30日前）"
+// @Param        to        query  string  false  "終了日 YYYY-MM-DD（省略時は今日）"
+// @Param        store_id  query  int     false  "店舗ID"
+// @Success      200 {array} CustomerMixRow
+// @Router       /analytics/customers [get]
+func GetCustomerMixAnalyticsHandler(c *gin.Context) {
+    r, ok := parseAnalyticsRange(c)
+    if !ok {
+        return
+    }
+    period := c.DefaultQuery("period", "month")
+    visitPeriod, ok := periodExpr(period, "visits.created_at")
+    if !ok {
+        c.JSON(400, gin.H{"error": "period は day / week / month のいずれかです"})
+        return
+    }
+    firstPeriod, _ := periodExpr(period, "firsts.first_at")
+
+    // 顧客ごとの初来店日時（期間より前の来店も含めて判定する）
+    firsts := db.DB.Model(&model.Visit{}).Where("voided_at IS NULL").
+        Select("customer_id, MIN(created_at) AS first_at").Group("customer_id")
+
+    var rows []CustomerMixRow
+    if err := db.DB.Model(&model.Visit{}).Scopes(r.stores(c, "visits.store_id"), r.between("visits.created_at")).
+        Joins("JOIN (?) AS firsts ON firsts.customer_id = visits.customer_id", firsts).
+        Where("visits.voided_at IS NULL").
+        Select("visits.store_id, " + visitPeriod + " AS period, " +
+            "COUNT(DISTINCT CASE WHEN " + firstPeriod + " = " + visitPeriod + " THEN visits.customer_id END) AS \"new\", " +
+            "COUNT(DISTINCT CASE WHEN " + firstPeriod + " < " + visitPeriod + " THEN visits.customer_id END) AS \"returning\", " +
+            "COUNT(*) AS visits").
+        Group("visits.store_id, period").Order("period, visits.store_id").Scan(&rows).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to aggregate customers"})
+        return
+    }
+    c.JSON(200, rows)
+}
+
+// @Summary      コース別の人気
+// @Description  コースごとの来店数・顧客数と、期間中に販売した回数券の枚数・金額（来店数の多い順）
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        from      query  string  false  "開始日 YYYY-MM-DD（省略時は30日前）"
+// @Param        to        query  string  false  "終了日 YYYY-MM-DD（省略時は今日）"
+// @Param        store_id  query  int     false  "店舗ID"
+// @Success      200 {array} CoursePopularityRow
+// @Router       /analytics/courses [get]
+func GetCoursePopularityAnalyticsHandler(c *gin.Context) {
+    r, ok := parseAnalyticsRange(c)
+    if !ok {
+        return
+    }
+
+    // 削除済みのコースも名前を出すため courses は論理削除を無視して結合する
+    visits := db.DB.Model(&model.Visit{}).Scopes(r.stores(c, "visits.store_id"), r.between("visits.created_at")).
+        Where("visits.voided_at IS NULL").
+        Select("course_id, COUNT(*) AS visits, COUNT(DISTINCT customer_id) AS unique_customers").Group("course_id")
+    tickets := db.DB.Model(&model.Ticket{}).Scopes(r.stores(c, "tickets.store_id"), r.between("tickets.purchased_at")).
+        Where("tickets.transferred_from_id IS NULL").
+        Select("course_id, COUNT(*) AS tickets_sold, SUM(price_paid) AS ticket_revenue").Group("course_id")
+
+    var rows []CoursePopularityRow
+    if err := db.DB.Table("courses").Scopes(r.stores(c, "courses.store_id")).
+        Joins("LEFT JOIN (?) AS v ON v.course_id = courses.id", visits).
+        Joins("LEFT JOIN (?) AS t ON t.course_id = courses.id", tickets).
+        Where("v.course_id IS NOT NULL OR t.course_id IS NOT NULL").
+        Select("courses.id AS course_id, courses.name AS course_name, courses.store_id, " +
+            "COALESCE(v.visits, 0) AS visits, COALESCE(v.unique_customers, 0) AS unique_customers, " +
+            "COALESCE(t.tickets_sold, 0) AS tickets_sold, COALESCE(t.ticket_revenue, 0) AS ticket_revenue").
+        Order("visits DESC, tickets_sold DESC, courses.id").Scan(&rows).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to aggregate courses"})
+        return
+    }
+    c.JSON(200, rows)
+}
+
+// @Summary      スタッフ別の実績
+// @Description  スタッフごとの担当来店数・顧客数、会計した売上、販売した回数券の枚数
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        from      query  string  false  "開始日 YYYY-MM-DD（省略時は30日前）"
+// @Param        to        query  string  false  "終了日 YYYY-MM-DD（省略時は今日）"
+// @Param        store_id  query  int     false  "店舗ID"
+// @Success      200 {array} StaffProductivityRow
+// @Router       /analytics/staff [get]
+func GetStaffProductivityAnalyticsHandler(c *gin.Context) {
+    r, ok := parseAnalyticsRange(c)
+    if !ok {
+        return
+    }
+
+    visits := db.DB.Model(&model.Visit{}).Scopes(r.stores(c, "visits.store_id"), r.between("visits.created_at")).
+        Where("visits.voided_at IS NULL AND visits.staff_id IS NOT NULL").
+        Select("staff_id AS user_id, COUNT(*) AS visits, COUNT(DISTINCT customer_id) AS unique_customers").Group("staff_id")
+    sales := db.DB.Model(&model.Sale{}).Scopes(r.stores(c, "sales.store_id"), r.between("sales.sold_at")).
+        Where("sales.status = ?", model.SaleCompleted).
+        Select("user_id, COUNT(*) AS sales_count, SUM(total) AS revenue").Group("user_id")
+    tickets := db.DB.Model(&model.Ticket{}).Scopes(r.stores(c, "tickets.store_id"), r.between("tickets.purchased_at")).
+        Where("tickets.sold_by_id IS NOT NULL AND tickets.transferred_from_id IS NULL").
+        Select("sold_by_id AS user_id, COUNT(*) AS tickets_sold").Group("sold_by_id")
+
+    var rows []StaffProductivityRow
+    if err := db.DB.Table("users").
+        Joins("LEFT JOIN (?) AS v ON v.user_id = users.id", visits).
+        Joins("LEFT JOIN (?) AS s ON s.user_id = users.id", sales).
+        Joins("LEFT JOIN (?) AS t ON t.user_id = users.id", tickets).
+        Where("v.user_id IS NOT NULL OR s.user_id IS NOT NULL OR t.user_id IS NOT NULL").
+        Select("users.id AS user_id, users.name, " +
+            "COALESCE(v.visits, 0) AS visits, COALESCE(v.unique_customers, 0) AS unique_customers, " +
+            "COALESCE(s.sales_count, 0) AS sales_count, COALESCE(s.revenue, 0) AS revenue, " +
+            "COALESCE(t.tickets_sold, 0) AS tickets_sold").
+        Order("visits DESC, revenue DESC, users.id").Scan(&rows).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to aggregate staff"})
+        return
+    }
+    c.JSON(200, rows)
+}
+
+// @Summary      回数券の消化率
+// @Description  期間中に販売した回数券（譲渡で作成したものを除く）のコースごとの消化状況。sell_through は消化した回数 / 販売した回数
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        from      query  string  false  "開始日 YYYY-MM-DD（省略時は30日前）"
+// @Param        to        query  string  false  "終了日 YYYY-MM-DD（省略時は今日）"
+// @Param        store_id  query  int     false  "店舗ID"
+// @Success      200 {array} TicketSellThroughRow
+// @Router       /analytics/tickets [get]
+func GetTicketSellThroughAnalyticsHandler(c *gin.Context) {
+    r, ok := parseAnalyticsRange(c)
+    if !ok {
+        return
+    }
+
+    var rows []TicketSellThroughRow
+    if err := db.DB.Model(&model.Ticket{}).Scopes(r.stores(c, "tickets.store_id"), r.between("tickets.purchased_at")).
+        Where("tickets.transferred_from_id IS NULL").
+        Select("course_id, MAX(course_name) AS course_name, COUNT(*) AS tickets_sold, SUM(price_paid) AS revenue, " +
+            "SUM(total_count) AS sessions_sold, SUM(current_count) AS sessions_used, " +
+            "COUNT(*) FILTER (WHERE is_completed) AS completed, " +
+            "COUNT(*) FILTER (WHERE expired_at IS NOT NULL) AS expired, SUM(unused_count) AS expired_sessions").
+        Group("course_id").Order("tickets_sold DESC, course_id").Scan(&rows).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to aggregate tickets"})
+        return
+    }
+    for i := range rows {
+        rows[i].SellThrough = ratio(rows[i].SessionsUsed, rows[i].SessionsSold)
+    }
+    c.JSON(200, rows)
+}
+
+// @Summary      回数券の未消化残高
+// @Description  現時点で消化できる回数券（期限内で残り回数がある）の残り回数と金額（前受金）を店舗・コースごとに集計します。期間の指定は使いません
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        store_id  query  int     false  "店舗ID"
+// @Success      200 {object} map[string]interface{}
+// @Router       /analytics/liability [get]
+func GetTicketLiabilityAnalyticsHandler(c *gin.Context) {
+    r := analyticsRange{StoreID: c.Query("store_id")}
+
+    var rows []TicketLiabilityRow
+    if err := db.DB.Model(&model.Ticket{}).Scopes(r.stores(c, "store_id")).
+        Where("expired_at IS NULL AND total_count > current_count AND (expires_at IS NULL OR expires_at > ?)", time.Now()).
+        Select("store_id, course_id, MAX(course_name) AS course_name, COUNT(*) AS tickets, " +
+            "SUM(total_count - current_count) AS remaining_sessions, " +
+            "SUM((price_paid - refunded_amount) * (total_count - current_count) / total_count) AS amount").
+        Group("store_id, course_id").Order("store_id, amount DESC, course_id").Scan(&rows).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to aggregate liability"})
+        return
+    }
+    total, sessions := 0, 0
+    for _, row := range rows {
+        total += row.Amount
+        sessions += row.RemainingSessions
+    }
+    c.JSON(200, gin.H{
+        "courses": rows,
+        "summary": gin.H{"remaining_sessions": sessions, "amount": total},
+    })
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"salon-app/backend/internal/middleware"
+	"salon-app/backend/internal/utils"
+)
+
+// analyticsRange は集計APIの共通の絞り込み条件（期間は JST の日付で from 以上 to 以下）
+type analyticsRange struct {
+	From    time.Time
+	To      time.Time // to の翌日 0時（この時刻を含まない）
+	StoreID string
+}
+
+// parseAnalyticsRange はクエリの from / to / store_id を読む。期間の省略時は今日までの30日間。
+// 形式が正しくなければ 400 を返して false。
+func parseAnalyticsRange(c *gin.Context) (analyticsRange, bool) {
+	now := time.Now().In(utils.JST)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.JST)
+	r := analyticsRange{From: today.AddDate(0, 0, -29), To: today.AddDate(0, 0, 1), StoreID: c.Query("store_id")}
+	if v := c.Query("from"); v != "" {
+		t, err := time.ParseInLocation(dateLayout, v, utils.JST)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "from の形式が正しくありません（YYYY-MM-DD）"})
+			return r, false
+		}
+		r.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.ParseInLocation(dateLayout, v, utils.JST)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "to の形式が正しくありません（YYYY-MM-DD）"})
+			return r, false
+		}
+		r.To = t.AddDate(0, 0, 1)
+	}
+	if !r.From.Before(r.To) {
+		c.JSON(400, gin.H{"error": "from は to 以前の日付にしてください"})
+		return r, false
+	}
+	return r, true
+}
+
+// stores はログイン中の店舗と store_id の指定で column（"sales.store_id" など）を絞り込む Scope を返す
+func (r analyticsRange) stores(c *gin.Context, column string) func(*gorm.DB) *gorm.DB {
+	tenant := middleware.StoreScopeColumn(c, column)
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tenant(tx)
+		if r.StoreID != "" {
+			tx = tx.Where(column+" = ?", r.StoreID)
+		}
+		return tx
+	}
+}
+
+// between は column が期間内の行に絞り込む Scope を返す
+func (r analyticsRange) between(column string) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(column+" >= ? AND "+column+" < ?", r.From, r.To)
+	}
+}
+
+// analyticsPeriods は集計単位ごとの date_trunc の単位
+var analyticsPeriods = map[string]string{"day": "day", "week": "week", "month": "month"}
+
+// periodExpr は column（timestamptz）を JST の集計単位の開始日 "YYYY-MM-DD" にする SQL 式を返す。
+// 週は月曜始まり。period が不正なら false（SQL に埋め込むため、必ずこの関数を通す）。
+func periodExpr(period, column string) (string, bool) {
+	unit, ok := analyticsPeriods[period]
+	if !ok {
+		return "", false
+	}
+	return "to_char(date_trunc('" + unit + "', " + column + " AT TIME ZONE 'Asia/Tokyo'), 'YYYY-MM-DD')", true
+}
+
+// ratio は a / b（b が0なら0）
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"salon-app/backend/internal/utils"
+)
+
+func TestParseAnalyticsRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, utils.JST) }
+
+	tests := []struct {
+		query    string
+		ok       bool
+		from, to time.Time
+	}{
+		// to の日を含めるため、to は翌日 0時（JST）
+		{"from=2026-10-01&to=2026-10-31", true, day(2026, 10, 1), day(2026, 11, 1)},
+		{"from=2026-10-18&to=2026-10-18", true, day(2026, 10, 18), day(2026, 10, 19)},
+		{"from=2026-10-19&to=2026-10-18", false, time.Time{}, time.Time{}},
+		{"from=2026/10/01", false, time.Time{}, time.Time{}},
+		{"to=20261031", false, time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil)
+		r, ok := parseAnalyticsRange(c)
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v (status %d), want %v", tt.query, ok, w.Code, tt.ok)
+			continue
+		}
+		if !ok {
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want 400", tt.query, w.Code)
+			}
+			continue
+		}
+		if !r.From.Equal(tt.from) || !r.To.Equal(tt.to) {
+			t.Errorf("%s: range = %v - %v, want %v - %v", tt.query, r.From, r.To, tt.from, tt.to)
+		}
+	}
+
+	// 省略時は今日までの30日間
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	r, ok := parseAnalyticsRange(c)
+	now := time.Now().In(utils.JST)
+	if !ok || r.To.Sub(r.From) != 30*24*time.Hour || !now.Before(r.To) || now.Before(r.To.AddDate(0, 0, -1)) {
+		t.Errorf("default range = %v - %v, want the 30 days up to today", r.From, r.To)
+	}
+}
+
+func TestPeriodExpr(t *testing.T) {
+	for _, period := range []string{"day", "week", "month"} {
+		if _, ok := periodExpr(period, "sales.sold_at"); !ok {
+			t.Errorf("periodExpr(%q) rejected", period)
+		}
+	}
+	// SQL に埋め込むので、許可した単位以外は受け付けない
+	for _, period := range []string{"", "year", "day'); DROP TABLE sales; --"} {
+		if expr, ok := periodExpr(period, "sales.sold_at"); ok {
+			t.Errorf("periodExpr(%q) = %q, want rejected", period, expr)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+const (
+	maxAvailabilityDays = 31 // 一度に検索できる日数
+	defaultSlotMinutes  = 30
+	dateLayout          = "2006-01-02"
+)
+
+// Slot は予約可能な時間枠と、その枠を担当できるスタッフの一覧
+type Slot struct {
+	StartAt  time.Time `json:"start_at"`
+	EndAt    time.Time `json:"end_at"`
+	StaffIDs []uint    `json:"staff_ids"`
+}
+
+// findAvailableSlots は from〜to（日付、両端を含む）の空き枠を返す。staffID が 0 なら全スタッフが対象。
+// exceptID の予約は枠を占有していないものとして扱う（日時変更で自分自身の枠と重ならないように）。
+// 空き枠は「営業日」「営業時間とシフトの重なり」「担当スタッフの予約」「店舗の席数」から計算する。
+func findAvailableSlots(tx *gorm.DB, store model.Store, course model.Course, from, to time.Time, staffID, exceptID uint) ([]Slot, error) {
+	fromKey, toKey := from.Format(dateLayout), to.Format(dateLayout)
+
+	var holidays []model.StoreHoliday
+	if err := tx.Where("store_id = ? AND date BETWEEN ? AND ?", store.ID, fromKey, toKey).Find(&holidays).Error; err != nil {
+		return nil, err
+	}
+	closed := map[string]bool{}
+	for _, h := range holidays {
+		closed[h.Date.Format(dateLayout)] = true
+	}
+
+	shiftQuery := tx.Where("store_id = ? AND date BETWEEN ? AND ?", store.ID, fromKey, toKey)
+	if staffID != 0 {
+		shiftQuery = shiftQuery.Where("user_id = ?", staffID)
+	}
+	var shifts []model.StaffShift
+	if err := shiftQuery.Find(&shifts).Error; err != nil {
+		return nil, err
+	}
+
+	reservations, err := activeOverlaps(tx, store.ID, from, to.AddDate(0, 0, 1), exceptID)
+	if err != nil {
+		return nil, err
+	}
+
+	return computeSlots(store, course, from, to, closed, shifts, reservations, time.Now()), nil
+}
+
+// computeSlots は空き枠の計算本体（DBアクセスなし）
+func computeSlots(store model.Store, course model.Course, from, to time.Time, closed map[string]bool,
+	shifts []model.StaffShift, reservations []model.Reservation, now time.Time) []Slot {
+	open, okOpen := parseClock(store.OpenTime)
+	closeAt, okClose := parseClock(store.CloseTime)
+	if !okOpen || !okClose || open >= closeAt {
+		return []Slot{}
+	}
+	step := store.SlotMinutes
+	if step <= 0 {
+		step = defaultSlotMinutes
+	}
+	regular := regularHolidays(store.RegularHolidays)
+	duration := time.Duration(course.DurationMinutes) * time.Minute
+	blocked := duration + time.Duration(course.BufferMinutes)*time.Minute
+
+	byStart := map[time.Time][]uint{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format(dateLayout)
+		if closed[key] || regular[day.Weekday()] {
+			continue
+		}
+		for _, shift := range shifts {
+			if shift.Date.Format(dateLayout) != key {
+				continue
+			}
+			shiftStart, ok1 := parseClock(shift.StartTime)
+			shiftEnd, ok2 := parseClock(shift.EndTime)
+			if !ok1 || !ok2 {
+				continue
+			}
+			// 営業時間とシフトが重なる時間帯のみ。開始時刻は開店時刻から step 分刻み
+			windowStart, windowEnd := max(open, shiftStart), min(closeAt, shiftEnd)
+			first := open + (windowStart-open+step-1)/step*step
+			for m := first; m+course.DurationMinutes <= windowEnd; m += step {
+				start := day.Add(time.Duration(m) * time.Minute)
+				if !start.After(now) {
+					continue
+				}
+				end := start.Add(blocked)
+				if staffBusy(reservations, shift.UserID, start, end) || storeFull(store, reservations, start, end) {
+					continue
+				}
+				byStart[start] = append(byStart[start], shift.UserID)
+			}
+		}
+	}
+
+	slots := make([]Slot, 0, len(byStart))
+	for start, staff := range byStart {
+		sort.Slice(staff, func(i, j int) bool { return staff[i] < staff[j] })
+		slots = append(slots, Slot{StartAt: start, EndAt: start.Add(duration), StaffIDs: staff})
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].StartAt.Before(slots[j].StartAt) })
+	return slots
+}
+
+// staffBusy は担当スタッフに [start, end) と重なる予約があるかを返す
+func staffBusy(reservations []model.Reservation, staffID uint, start, end time.Time) bool {
+	for _, r := range reservations {
+		if r.StaffID == staffID && r.StartAt.Before(end) && r.BlockedUntil().After(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// storeFull は [start, end) に予約を追加すると店舗の席数を超えるかを返す
+func storeFull(store model.Store, reservations []model.Reservation, start, end time.Time) bool {
+	if store.Capacity <= 0 {
+		return false
+	}
+	var overlaps []model.Reservation
+	for _, r := range reservations {
+		if r.StartAt.Before(end) && r.BlockedUntil().After(start) {
+			overlaps = append(overlaps, r)
+		}
+	}
+	return maxConcurrent(overlaps)+1 > store.Capacity
+}
+
+// parseClock は "HH:MM" を0時からの分数に変換する
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// formatClock は0時からの分を "HH:MM" にする（保存する時刻の表記をそろえる）
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// regularHolidays は "1,2" 形式の定休日を曜日のセットに変換する
+func regularHolidays(s string) map[time.Weekday]bool {
+	days := map[time.Weekday]bool{}
+	for _, part := range strings.Split(s, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && n >= 0 && n <= 6 {
+			days[time.Weekday(n)] = true
+		}
+	}
+	return days
+}
+
+// parseDateRange は from / to（YYYY-MM-DD、JST）を解釈する。省略時は今日から1週間。
+func parseDateRange(fromStr, toStr string) (time.Time, time.Time, bool) {
+	now := time.Now().In(utils.JST)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.JST)
+	if fromStr != "" {
+		t, err := time.ParseInLocation(dateLayout, fromStr, utils.JST)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+	to := from.AddDate(0, 0, 6)
+	if toStr != "" {
+		t, err := time.ParseInLocation(dateLayout, toStr, utils.JST)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+	if to.Before(from) || to.Sub(from) >= maxAvailabilityDays*24*time.Hour {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"salon-app/backend/internal/postal"
+	"salon-app/backend/internal/utils"
+)
+
+// normalizeCustomerKana はフリガナ（姓・名）を utils.NormalizeKana で正規の形（全角ひらがな）に書き換える。
+// かな以外の文字が含まれていれば 400 を返して false。
+func normalizeCustomerKana(c *gin.Context, last, first *string) bool {
+	for _, p := range []*string{last, first} {
+		kana, ok := utils.NormalizeKana(*p)
+		if !ok {
+			c.JSON(400, gin.H{"error": "フリガナはひらがなまたはカタカナで入力してください（" + *p + "）"})
+			return false
+		}
+		*p = kana
+	}
+	return true
+}
+
+// normalizeCustomerAddress は郵便番号をハイフンなしの7桁に書き換え、郵便番号のデータにある番号なら都道府県が一致するかを確かめる。
+// 郵便番号が空なら何もしない。正しくなければ 400（DBエラーなら 500）を返して false。
+func normalizeCustomerAddress(c *gin.Context, zip *string, pref string) bool {
+	if strings.TrimSpace(*zip) == "" {
+		*zip = ""
+		return true
+	}
+	normalized, ok := postal.NormalizeZip(*zip)
+	if !ok {
+		c.JSON(400, gin.H{"error": "郵便番号は7桁の数字で入力してください"})
+		return false
+	}
+	*zip = normalized
+	if pref == "" {
+		return true
+	}
+	match, known, err := postal.PrefMatches(normalized, pref)
+	if err != nil {
+		c.Error(err)
+		c.JSON(500, gin.H{"error": "Failed to look up postal code"})
+		return false
+	}
+	if known && !match {
+		c.JSON(400, gin.H{"error": "都道府県が郵便番号（" + normalized + "）の住所と一致しません"})
+		return false
+	}
+	return true
+}
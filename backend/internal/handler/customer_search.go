@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+const (
+	defaultCustomerSearchLimit = 20
+	maxCustomerSearchLimit     = 50
+)
+
+// customerSearchSpec は顧客検索の絞り込み（並び替え・ページングはしない）
+var customerSearchSpec = listSpec{
+	Filters: []listFilter{
+		{Param: "store_id", Column: "customers.store_id", Kind: filterID},
+	},
+}
+
+// CustomerSearchResult は顧客検索の1件（顧客と最終来店日時）
+type CustomerSearchResult struct {
+	model.Customer
+	LastVisitAt *time.Time `json:"last_visit_at"` // 取り消していない最後の来店。来店がなければ null
+}
+
+// likeEscaper は LIKE のパターンの特殊文字をエスケープする
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// customerSearchQuery は q に一致する顧客を探すクエリを返す。q が空白だけなら nil。
+//
+// q は検索用のカラムと同じ規則（utils.NormalizeSearchText）で正規化し、氏名・氏名カナ・メールアドレスの部分一致、
+// 数字・ハイフン等だけなら電話番号（数字のみ）の部分一致でも探す。3文字以上なら氏名の似た表記（pg_trgm。使えなければ含めない）も含める。
+// 並び順は 完全一致 → 前方一致 → 部分一致、同じなら氏名の類似度、最終来店の新しい順。
+func customerSearchQuery(scopes func(*gorm.DB) *gorm.DB, q string) *gorm.DB {
+	name := utils.NormalizeSearchText(q)
+	if name == "" {
+		return nil
+	}
+	digits := ""
+	if looksLikePhone(name) {
+		digits = utils.PhoneDigits(name)
+	}
+	args := map[string]interface{}{
+		"name":          name,
+		"contains":      "%" + likeEscaper.Replace(name) + "%",
+		"prefix":        likeEscaper.Replace(name) + "%",
+		"word_prefix":   "% " + likeEscaper.Replace(name) + "%",
+		"digits":        digits,
+		"digits_prefix": digits + "%",
+		"digits_like":   "%" + digits + "%",
+	}
+
+	match := db.DB.Where("customers.search_name LIKE @contains OR customers.search_email LIKE @contains", args)
+	if db.TrigramSearch && len([]rune(name)) >= 3 {
+		match = match.Or("customers.search_name % @name", args)
+	}
+	if digits != "" {
+		match = match.Or("customers.search_phone LIKE @digits_like", args)
+	}
+
+	// pg_trgm がなければ類似度は使えないので、並び順は一致の種類と最終来店だけで決める
+	similarity := "similarity(customers.search_name, @name)"
+	if !db.TrigramSearch {
+		similarity = "0"
+	}
+
+	lastVisits := db.DB.Model(&model.Visit{}).Where("voided_at IS NULL").
+		Select("customer_id, MAX(created_at) AS last_visit_at").Group("customer_id")
+
+	return db.DB.Model(&model.Customer{}).Scopes(scopes).Where(match).
+		Joins("LEFT JOIN (?) AS v ON v.customer_id = customers.id", lastVisits).
+		Select("customers.*, v.last_visit_at, "+
+			"CASE WHEN customers.search_email = @name OR @name = ANY(string_to_array(customers.search_name, ' ')) "+
+			"OR (@digits <> '' AND customers.search_phone = @digits) THEN 3 "+
+			"WHEN customers.search_name LIKE @prefix OR customers.search_name LIKE @word_prefix OR customers.search_email LIKE @prefix "+
+			"OR (@digits <> '' AND customers.search_phone LIKE @digits_prefix) THEN 2 "+
+			"ELSE 1 END AS relevance, "+
+			similarity+" AS name_similarity", args).
+		Order("relevance DESC, name_similarity DESC, v.last_visit_at DESC NULLS LAST, customers.id DESC")
+}
+
+// looksLikePhone は正規化した検索語が電話番号らしい（数字を3つ以上含み、ほかは + - ( ) と長音記号だけ）か
+func looksLikePhone(s string) bool {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			n++
+		case strings.ContainsRune("+-()‐−ー", r):
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
@@ -1,8 +1,12 @@
 package handler
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
 	"salon-app/backend/internal/model"
 )
 
@@ -17,57 +21,105 @@ func PingHandler(c *gin.Context) {
     c.JSON(200, gin.H{"message": "Hello from Go Backend!"})
 }
 
+// userListSpec はスタッフ一覧の並び替え・絞り込み
+var userListSpec = listSpec{
+    Sorts:       map[string]string{"id": "users.id", "name": "users.name", "created_at": "users.created_at"},
+    DefaultSort: "id",
+    Filters: []listFilter{
+        {Param: "store_id", Column: "users.store_id", Kind: filterID},
+        {Param: "role", Column: "users.role", Kind: filterEnum, Values: []string{model.RoleAdmin, model.RoleManager, model.RoleStaff}},
+    },
+}
+
 // @Summary      スタッフ一覧 (ユーザー一覧)
 // @Description  スタッフ一覧取得
 // @Tags         system
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} model.User
+// @Param        store_id query  int     false  "店舗ID"
+// @Param        role     query  string  false  "admin / manager / staff"
+// @Param        sort     query  string  false  "id（既定）/ name / created_at。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {array} model.User
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
 // @Router       /users [get]
 func GetUserListHandler(c *gin.Context) {
     var users []model.User
     // StoreもPreloadしておく（表示用）
-    if err := db.DB.Preload("Store").Find(&users).Error; err != nil {
-        c.Error(err)
-        c.JSON(500, gin.H{"error": "Failed to fetch users"})
+    if !paginate(c, db.DB.Scopes(middleware.StoreScope(c)), userListSpec, &users, "Store") {
         return
     }
     // model.User の Password は json:"-" なので自動で隠れるが念のため
     c.JSON(200, users)
 }
 
+// visitListSpec は来店履歴一覧の並び替え・絞り込み
+var visitListSpec = listSpec{
+    Sorts:       map[string]string{"id": "visits.id", "created_at": "visits.created_at"},
+    DefaultSort: "-created_at",
+    Filters: []listFilter{
+        {Param: "store_id", Column: "visits.store_id", Kind: filterID},
+        {Param: "customer_id", Column: "visits.customer_id", Kind: filterID},
+        {Param: "course_id", Column: "visits.course_id", Kind: filterID},
+        {Param: "staff_id", Column: "visits.staff_id", Kind: filterID},
+        {Param: "ticket_id", Column: "visits.ticket_id", Kind: filterID},
+        {Param: "from", Column: "visits.created_at", Kind: filterSince},
+        {Param: "to", Column: "visits.created_at", Kind: filterUntil},
+    },
+}
+
 // @Summary      来店履歴一覧
-// @Description  来店履歴一覧取得
+// @Description  来店履歴一覧取得（新しい順）。取り消した来店記録も voided_at 付きで含みます
 // @Tags         system
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} model.Visit
+// @Param        store_id     query  int     false  "店舗ID"
+// @Param        customer_id  query  int     false  "顧客ID"
+// @Param        course_id    query  int     false  "コースID"
+// @Param        staff_id     query  int     false  "担当スタッフID"
+// @Param        ticket_id    query  int     false  "チケットID"
+// @Param        from         query  string  false  "来店日 YYYY-MM-DD 以降"
+// @Param        to           query  string  false  "来店日 YYYY-MM-DD まで"
+// @Param        sort         query  string  false  "created_at / id（既定は -created_at）。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {array} model.Visit
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
 // @Router       /visit [get]
 func GetVisitHandler(c *gin.Context) {
     var visits []model.Visit
-    if err := db.DB.Preload("Customer").Preload("Course").Preload("Store").Find(&visits).Error; err != nil {
-        c.Error(err)
-        c.JSON(500, gin.H{"error": "Failed to fetch visits"})
+    if !paginate(c, db.DB.Scopes(middleware.StoreScope(c)), visitListSpec, &visits, "Customer", "Course", "Store") {
         return
     }
     c.JSON(200, visits)
 }
 
 // @Summary      来店履歴検索
-// @Description  来店履歴検索
+// @Description  来店履歴検索（パラメータは来店履歴一覧と同じ）
 // @Tags         system
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} model.Visit
+// @Success      200 {array} model.Visit
 // @Router       /visit/search [get]
 func GetVisitSearchHandler(c *gin.Context) {
-    var visits []model.Visit
-    if err := db.DB.Preload("Customer").Preload("Course").Preload("Store").Find(&visits).Error; err != nil {
-        c.Error(err)
-        c.JSON(500, gin.H{"error": "Failed to fetch visits"})
-        return
-    }
-    c.JSON(200, visits)
+    GetVisitHandler(c)
+}
+
+// customerListSpec は顧客一覧の並び替え・絞り込み
+var customerListSpec = listSpec{
+    Sorts: map[string]string{
+        "id": "customers.id", "created_at": "customers.created_at",
+        "last_name": "customers.last_name", "last_name_kana": "customers.last_name_kana",
+    },
+    DefaultSort: "id",
+    Filters: []listFilter{
+        {Param: "store_id", Column: "customers.store_id", Kind: filterID},
+        {Param: "from", Column: "customers.created_at", Kind: filterSince},
+        {Param: "to", Column: "customers.created_at", Kind: filterUntil},
+    },
 }
 
 // @Summary      顧客一覧
@@ -75,13 +127,19 @@ func GetVisitSearchHandler(c *gin.Context) {
 // @Tags         system
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} model.Customer
+// @Param        store_id  query  int     false  "店舗ID"
+// @Param        from      query  string  false  "登録日 YYYY-MM-DD 以降"
+// @Param        to        query  string  false  "登録日 YYYY-MM-DD まで"
+// @Param        sort      query  string  false  "id（既定）/ created_at / last_name / last_name_kana。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {array} model.Customer
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
 // @Router       /customer [get]
 func GetCustomerHandler(c *gin.Context) {
     var customers []model.Customer
-    if err := db.DB.Preload("Store").Find(&customers).Error; err != nil {
-        c.Error(err)
-        c.JSON(500, gin.H{"error": "Failed to fetch customers"})
+    if !paginate(c, db.DB.Scopes(middleware.StoreScope(c)), customerListSpec, &customers, "Store") {
         return
     }
     c.JSON(200, customers)
@@ -99,7 +157,8 @@ func GetStoreHandler(c *gin.Context) {
         ID   uint   `json:"id"`
         Name string `json:"name"`
     }
-    if err := db.DB.Model(&model.Store{}).Select("id", "name").Find(&stores).Error; err != nil {
+    // admin 以外は所属店舗のみ
+    if err := db.DB.Model(&model.Store{}).Scopes(middleware.StoreScopeColumn(c, "id")).Select("id", "name").Find(&stores).Error; err != nil {
         c.Error(err)
         c.JSON(500, gin.H{"error": "Failed to fetch stores"})
         return
@@ -107,40 +166,229 @@ func GetStoreHandler(c *gin.Context) {
     c.JSON(200, gin.H{"stores": stores})
 }
 
+// ticketListSpec はチケット一覧の並び替え・絞り込み（customer_id・status は GetTicketHandler で扱う）
+var ticketListSpec = listSpec{
+    Sorts:       map[string]string{"id": "tickets.id", "purchased_at": "tickets.purchased_at"},
+    DefaultSort: "-id",
+    Filters: []listFilter{
+        {Param: "course_id", Column: "tickets.course_id", Kind: filterID},
+        {Param: "store_id", Column: "tickets.store_id", Kind: filterID},
+        {Param: "from", Column: "tickets.purchased_at", Kind: filterSince},
+        {Param: "to", Column: "tickets.purchased_at", Kind: filterUntil},
+    },
+}
+
 // @Summary      チケット一覧
-// @Description  チケット一覧
-// @Tags         system
+// @Description  チケット一覧（顧客・コース付き）。残り回数と状態（active / completed / expired）を含みます
+// @Tags         ticket
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} map[string]string
+// @Param        customer_id  query  int     false  "顧客ID（共有メンバーになっているチケットも含む）"
+// @Param        course_id    query  int     false  "コースID"
+// @Param        store_id     query  int     false  "店舗ID"
+// @Param        status       query  string  false  "状態 (active / completed / expired)"
+// @Param        from         query  string  false  "購入日 YYYY-MM-DD 以降"
+// @Param        to           query  string  false  "購入日 YYYY-MM-DD まで"
+// @Param        sort         query  string  false  "id / purchased_at（既定は -id）。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {object} TicketView
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
 // @Router       /ticket [get]
 func GetTicketHandler(c *gin.Context) {
-    var tickets []struct {
-        ID   uint   `json:"id"`
-        Name string `json:"name"`
+    now := time.Now()
+    query := db.DB.Scopes(middleware.StoreScope(c))
+    if customerID := c.Query("customer_id"); customerID != "" {
+        id, err := strconv.ParseUint(customerID, 10, 64)
+        if err != nil {
+            c.JSON(400, gin.H{"error": "customer_id は正の整数で指定してください"})
+            return
+        }
+        // 共有メンバーになっているチケットも含める
+        query = query.Where("(tickets.customer_id = ? OR tickets.id IN (SELECT ticket_id FROM ticket_members WHERE customer_id = ?))", id, id)
+    }
+    if status := c.Query("status"); status != "" {
+        scope, ok := ticketStatusScope(status, now)
+        if !ok {
+            c.JSON(400, gin.H{"error": "status は active / completed / expired のいずれかで指定してください"})
+            return
+        }
+        query = query.Scopes(scope)
+    }
+
+    var tickets []model.Ticket
+    if !paginate(c, query, ticketListSpec, &tickets, "Customer", "Course", "SharedWith") {
+        return
+    }
+    views := make([]TicketView, 0, len(tickets))
+    for _, t := range tickets {
+        views = append(views, newTicketView(t, now))
+    }
+    c.JSON(200, gin.H{"tickets": views})
+}
+
+// @Summary      チケットの消化履歴
+// @Description  チケットを消化した来店記録を消化した順（何回目か）に返します。譲渡の履歴も含みます
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Ticket ID"
+// @Success      200 {object} map[string]interface{}
+// @Router       /ticket/{id}/history [get]
+func GetTicketHistoryHandler(c *gin.Context) {
+    var ticket model.Ticket
+    if err := db.DB.Scopes(middleware.StoreScope(c)).Preload("Customer").Preload("Course").
+        First(&ticket, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Ticket not found"})
+        return
+    }
+    var visits []model.Visit
+    if err := db.DB.Where("ticket_id = ?", ticket.ID).Preload("Store").
+        Order("visit_count, created_at").Find(&visits).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch ticket history"})
+        return
+    }
+    var transfers []model.TicketTransfer
+    if err := db.DB.Where("from_ticket_id = ? OR to_ticket_id = ?", ticket.ID, ticket.ID).
+        Preload("FromCustomer").Preload("ToCustomer").Order("created_at").Find(&transfers).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch ticket history"})
+        return
+    }
+    c.JSON(200, gin.H{"ticket": newTicketView(ticket, time.Now()), "visits": visits, "transfers": transfers})
+}
+
+// @Summary      顧客の履歴
+// @Description  顧客の来店記録・チケット（共有メンバーのものを含む）・チケット譲渡の履歴（譲渡元・譲渡先どちらの場合も）を返します
+// @Tags         customer
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Customer ID"
+// @Success      200 {object} map[string]interface{}
+// @Router       /customer/{id}/history [get]
+func GetCustomerHistoryHandler(c *gin.Context) {
+    var customer model.Customer
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&customer, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Customer not found"})
+        return
     }
-    if err := db.DB.Model(&model.Ticket{}).Select("id", "name").Find(&tickets).Error; err != nil {
+    var visits []model.Visit
+    if err := db.DB.Where("customer_id = ?", customer.ID).Preload("Course").Preload("Ticket").
+        Order("created_at DESC").Find(&visits).Error; err != nil {
         c.Error(err)
-        c.JSON(500, gin.H{"error": "Failed to fetch tickets"})
+        c.JSON(500, gin.H{"error": "Failed to fetch customer history"})
         return
     }
-    c.JSON(200, gin.H{"tickets": tickets})
+    var tickets []model.Ticket
+    if err := db.DB.Where("customer_id = ? OR id IN (SELECT ticket_id FROM ticket_members WHERE customer_id = ?)", customer.ID, customer.ID).
+        Preload("Customer").Preload("SharedWith").Order("id DESC").Find(&tickets).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch customer history"})
+        return
+    }
+    var transfers []model.TicketTransfer
+    if err := db.DB.Where("from_customer_id = ? OR to_customer_id = ?", customer.ID, customer.ID).
+        Preload("FromCustomer").Preload("ToCustomer").Order("created_at DESC").Find(&transfers).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch customer history"})
+        return
+    }
+    now := time.Now()
+    views := make([]TicketView, 0, len(tickets))
+    for _, t := range tickets {
+        views = append(views, newTicketView(t, now))
+    }
+    c.JSON(200, gin.H{"customer": customer, "visits": visits, "tickets": views, "transfers": transfers})
 }
 
+// expiringTicketListSpec は期限切れ間近のチケット一覧の並び順（期限の近い順のみ）
+var expiringTicketListSpec = listSpec{
+    Sorts:       map[string]string{"expires_at": "tickets.expires_at"},
+    DefaultSort: "expires_at",
+}
+
+// @Summary      期限切れ間近のチケット一覧
+// @Description  days 日以内に有効期限を迎える、消化中のチケットを期限の近い順に返します
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        days  query  int  false  "何日以内か（省略時は30、最大365）"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {object} TicketView
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
+// @Router       /tickets/expiring [get]
+func GetExpiringTicketsHandler(c *gin.Context) {
+    days := 30
+    if v := c.Query("days"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 1 || n > 365 {
+            c.JSON(400, gin.H{"error": "days は1〜365で指定してください"})
+            return
+        }
+        days = n
+    }
+    now := time.Now()
+    var tickets []model.Ticket
+    query := db.DB.Scopes(middleware.StoreScope(c)).
+        Where("expires_at > ? AND expires_at <= ? AND expired_at IS NULL AND is_completed = ?", now, now.AddDate(0, 0, days), false)
+    if !paginate(c, query, expiringTicketListSpec, &tickets, "Customer") {
+        return
+    }
+    views := make([]TicketView, 0, len(tickets))
+    for _, t := range tickets {
+        views = append(views, newTicketView(t, now))
+    }
+    c.JSON(200, gin.H{"tickets": views})
+}
+
+// courseListSpec はコース一覧の並び替え・絞り込み
+var courseListSpec = listSpec{
+    Sorts:       map[string]string{"id": "courses.id", "name": "courses.name", "price": "courses.price"},
+    DefaultSort: "id",
+    Filters: []listFilter{
+        {Param: "store_id", Column: "courses.store_id", Kind: filterID},
+    },
+}
 
 // @Summary      コース一覧
 // @Description  コース一覧
 // @Tags         system
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} model.Course
-// @Router       /course [get]
+// @Param        store_id  query  int     false  "店舗ID"
+// @Param        sort      query  string  false  "id（既定）/ name / price。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {array} model.Course
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
+// @Router       /courses [get]
 func GetCourseHandler(c *gin.Context) {
     var courses []model.Course
-    if err := db.DB.Find(&courses).Error; err != nil {
-        c.Error(err)
-        c.JSON(500, gin.H{"error": "Failed to get courses"})
+    if !paginate(c, db.DB.Scopes(middleware.StoreScope(c)), courseListSpec, &courses) {
         return
     }
     c.JSON(200, courses)
 }
+
+
+// @Summary      ログインロック一覧
+// @Description  現在ロック中のアカウント・IPアドレスの一覧（権限: user.security）
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} model.LoginThrottle
+// @Router       /login-locks [get]
+func GetLoginLocksHandler(c *gin.Context) {
+    var throttles []model.LoginThrottle
+    if err := db.DB.Where("locked_until > ?", time.Now()).Order("locked_until DESC").Find(&throttles).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch login locks"})
+        return
+    }
+    c.JSON(200, throttles)
+}
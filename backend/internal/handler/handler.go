@@ -17,26 +17,79 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required,min=4,max=16"`
 }
 
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"` // 認証アプリの6桁コード、またはリカバリーコード
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LoginUnlockRequest struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
 type StoreRegistrationRequest struct {
 	Name string `json:"name" binding:"required"`
+	Capacity int `json:"capacity" binding:"min=0"` // 同時施術可能数 (0 は無制限)
+	StoreTaxSettings
+	StoreRetentionSettings
+}
+
+// StoreTaxSettings は店舗の消費税・インボイスの設定（店舗の登録・更新で共通）。
+// 省略した項目は、登録では既定値、更新では変更しない
+type StoreTaxSettings struct {
+	InvoiceNumber   *string `json:"invoice_number" binding:"omitempty,max=14"` // "T" + 13桁（形式は applyStoreTaxSettings で確認）。空文字で登録番号を削除
+	PriceTaxMode    *string `json:"price_tax_mode" binding:"omitempty,oneof=inclusive exclusive"`
+	TaxRounding     *string `json:"tax_rounding" binding:"omitempty,oneof=floor round ceil"`
+	StandardTaxRate *int    `json:"standard_tax_rate" binding:"omitempty,min=0,max=100"` // 0 なら 10
+	ReducedTaxRate  *int    `json:"reduced_tax_rate" binding:"omitempty,min=0,max=100"`  // 0 なら 8
+}
+
+// StoreRetentionSettings は顧客の離反分析（RFM）のしきい値（店舗の登録・更新で共通）。
+// 省略した項目は、登録では既定値、更新では変更しない。0 を指定すると既定値に戻す
+type StoreRetentionSettings struct {
+	RetentionLoyalVisits    *int `json:"retention_loyal_visits" binding:"omitempty,min=0"`    // 0 なら 5
+	RetentionAtRiskDays     *int `json:"retention_at_risk_days" binding:"omitempty,min=0"`    // 0 なら 60
+	RetentionLapsedDays     *int `json:"retention_lapsed_days" binding:"omitempty,min=0"`     // 0 なら 180
+	RetentionOverduePercent *int `json:"retention_overdue_percent" binding:"omitempty,min=0"` // 0 なら 150
 }
 
 type CourseRegistrationRequest struct {
 	Name string `json:"name" binding:"required"`
 	Price int `json:"price" binding:"required"`
 	TotalCount int `json:"total_count" binding:"required"`
+	DurationMinutes int `json:"duration_minutes" binding:"min=0"`
+	BufferMinutes int `json:"buffer_minutes" binding:"min=0"`
+	OnlineBookable bool `json:"online_bookable"`
+	ValidityDays int `json:"validity_days" binding:"min=0"` // 回数券の有効期間（日）。0 なら無期限
+	TaxCategory string `json:"tax_category" binding:"omitempty,oneof=standard reduced"` // 省略時は標準税率
 	StoreID uint `json:"store_id" binding:"required"`
 }
 
 type VisitRegistrationRequest struct {
 	CustomerID uint `json:"customer_id" binding:"required"`
 	CourseID uint `json:"course_id" binding:"required"`
-	TicketID *uint `json:"ticket_id"`
-	VisitCount int `json:"visit_count"`
+	TicketID *uint `json:"ticket_id"` // 消化する回数券。null なら都度払い
 	StoreID uint `json:"store_id" binding:"required"`
+	StaffID *uint `json:"staff_id"` // 担当スタッフ（任意）
 	Memo string `json:"memo"`
 }
 
+type VisitVoidRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
 type CustomerRegistrationRequest struct {
 	LastName      string `json:"last_name" binding:"required"`
 	FirstName     string `json:"first_name" binding:"required"`
@@ -49,21 +102,32 @@ type CustomerRegistrationRequest struct {
 	Sex       string    `json:"sex"`
 	BirthDate time.Time `json:"birth_date"`
 	Phone     string    `json:"phone"`
+	Email     string    `json:"email" binding:"omitempty,email"`
 	StoreID uint `json:"store_id" binding:"required"`
 }
 
+// CustomerSearchRequest は POST /customer-search の本文。q がなければ last_name_kana（旧形式）で検索する
 type CustomerSearchRequest struct {
-	LastNameKana string `json:"last_name_kana" binding:"required"`
+	Q            string `json:"q"`
+	LastNameKana string `json:"last_name_kana"`
 }
 
 type StoreUpdateRequest struct {
 	Name string `json:"name" binding:"required"`
+	Capacity int `json:"capacity" binding:"min=0"` // 同時施術可能数 (0 は無制限)
+	StoreTaxSettings
+	StoreRetentionSettings
 }
 
 type CourseUpdateRequest struct {
 	Name string `json:"name" binding:"required"`
 	Price int `json:"price" binding:"required"`
 	TotalCount int `json:"total_count" binding:"required"`
+	DurationMinutes int `json:"duration_minutes" binding:"min=0"`
+	BufferMinutes int `json:"buffer_minutes" binding:"min=0"`
+	OnlineBookable bool `json:"online_bookable"`
+	ValidityDays int `json:"validity_days" binding:"min=0"` // 回数券の有効期間（日）。0 なら無期限
+	TaxCategory string `json:"tax_category" binding:"omitempty,oneof=standard reduced"` // 省略時は標準税率
 	StoreID uint `json:"store_id" binding:"required"`
 }
 
@@ -87,21 +151,149 @@ type CustomerUpdateRequest struct {
 	Sex       string    `json:"sex"`
 	BirthDate time.Time `json:"birth_date"`
 	Phone     string    `json:"phone"`
+	Email     string    `json:"email" binding:"omitempty,email"`
 	StoreID uint `json:"store_id" binding:"required"`
 }
 
 type VisitUpdateRequest struct {
 	CustomerID uint `json:"customer_id" binding:"required"`
 	CourseID uint `json:"course_id" binding:"required"`
-	TicketID *uint `json:"ticket_id"`
-	VisitCount int `json:"visit_count"`
+	TicketID *uint `json:"ticket_id"` // 変更するとチケットの消化も付け替える。省略・null なら変更しない
+	ClearTicket bool `json:"clear_ticket"` // true ならチケットの消化を戻して都度払いにする（ticket_id と同時には指定できない）
 	StoreID uint `json:"store_id" binding:"required"`
 	Memo string `json:"memo"`
 }
 
 type TicketUpdateRequest struct {
-    Name  string `json:"name"`
-    Price int    `json:"price"`
-	TotalCount int `json:"total_count"`
-	StoreID uint `json:"store_id" binding:"required"`
-}
\ No newline at end of file
+	TotalCount int    `json:"total_count" binding:"required,min=1"`
+	ReasonCode string `json:"reason_code" binding:"required,oneof=apology campaign input_error customer_request migration other"`
+	Note       string `json:"note" binding:"max=500"`
+}
+
+type TicketAdjustmentRequest struct {
+	Kind         string     `json:"kind" binding:"required,oneof=complimentary correction refund reopen"`
+	Sessions     int        `json:"sessions"`                                                    // 追加・修正する回数（kind ごとの意味は AdjustTicketHandler を参照）
+	RefundAmount *int       `json:"refund_amount" binding:"omitempty,min=0"`                     // refund のみ。省略時は返金済みを除いた支払額を回数で按分（上限は支払額 - 返金済みの額）
+	RefundMethod string     `json:"refund_method" binding:"omitempty,oneof=cash card qr other"` // refund のみ。返金方法（省略時は cash）
+	ExpiresAt    *time.Time `json:"expires_at"`                                                  // reopen のみ。新しい有効期限
+	ReasonCode   string     `json:"reason_code" binding:"required,oneof=apology campaign input_error customer_request migration other"`
+	Note         string     `json:"note" binding:"max=500"`
+}
+
+type TicketTransferRequest struct {
+	ToCustomerID uint   `json:"to_customer_id" binding:"required"`
+	Sessions     int    `json:"sessions" binding:"min=0"` // 省略時は残り回数すべて
+	ReasonCode   string `json:"reason_code" binding:"required,oneof=apology campaign input_error customer_request migration other"`
+	Note         string `json:"note" binding:"max=500"`
+}
+
+type TicketMemberRequest struct {
+	CustomerID uint `json:"customer_id" binding:"required"`
+}
+
+type ReservationRequest struct {
+	CustomerID uint      `json:"customer_id" binding:"required"`
+	CourseID   uint      `json:"course_id" binding:"required"`
+	StaffID    uint      `json:"staff_id" binding:"required"`
+	StoreID    uint      `json:"store_id" binding:"required"`
+	StartAt    time.Time `json:"start_at" binding:"required"`
+	EndAt      time.Time `json:"end_at" binding:"omitempty,gtfield=StartAt"` // 省略時はコースの施術時間から計算
+	Memo       string    `json:"memo"`
+}
+
+type ReservationStatusRequest struct {
+	Status   string `json:"status" binding:"required,oneof=booked confirmed arrived completed cancelled no_show"`
+	Memo     string `json:"memo"`      // 完了時は来店記録のメモになる
+	TicketID *uint  `json:"ticket_id"` // 完了時に消化する回数券。null なら都度払い
+}
+
+type TicketPurchaseRequest struct {
+	CustomerID    uint       `json:"customer_id" binding:"required"`
+	CourseID      uint       `json:"course_id" binding:"required"`
+	StoreID       uint       `json:"store_id" binding:"required"`
+	PricePaid     *int       `json:"price_paid" binding:"omitempty,min=0"` // 省略時はコースの販売価格
+	PaymentMethod string     `json:"payment_method" binding:"required,oneof=cash card qr other"`
+	PurchasedAt   *time.Time `json:"purchased_at"` // 省略時は現在時刻
+}
+
+type BusinessHoursRequest struct {
+	OpenTime        string `json:"open_time" binding:"required,datetime=15:04"`
+	CloseTime       string `json:"close_time" binding:"required,datetime=15:04"`
+	SlotMinutes     int    `json:"slot_minutes" binding:"required,min=5,max=120"`
+	RegularHolidays []int  `json:"regular_holidays" binding:"dive,min=0,max=6"` // 0=日〜6=土
+}
+
+type StoreHolidayRequest struct {
+	Date   string `json:"date" binding:"required,datetime=2006-01-02"`
+	Reason string `json:"reason"`
+}
+
+type ShiftRequest struct {
+	UserID    uint   `json:"user_id" binding:"required"`
+	Date      string `json:"date" binding:"required,datetime=2006-01-02"`
+	StartTime string `json:"start_time" binding:"required,datetime=15:04"`
+	EndTime   string `json:"end_time" binding:"required,datetime=15:04"`
+}
+
+type PublicBookingRequest struct {
+	CourseID      uint      `json:"course_id" binding:"required"`
+	StartAt       time.Time `json:"start_at" binding:"required"`
+	LastName      string    `json:"last_name" binding:"required,max=50"`
+	FirstName     string    `json:"first_name" binding:"required,max=50"`
+	LastNameKana  string    `json:"last_name_kana" binding:"required,max=50"`
+	FirstNameKana string    `json:"first_name_kana" binding:"required,max=50"`
+	Phone         string    `json:"phone" binding:"required,max=20"`
+	Email         string    `json:"email" binding:"required,email,max=255"`
+}
+
+type PublicRescheduleRequest struct {
+	StartAt time.Time `json:"start_at" binding:"required"`
+}
+
+type SaleRequest struct {
+	StoreID        uint                 `json:"store_id" binding:"required"`
+	CustomerID     *uint                `json:"customer_id"`
+	SoldAt         *time.Time           `json:"sold_at"` // 省略時は現在時刻
+	DiscountAmount int                  `json:"discount_amount" binding:"min=0"` // 会計全体の値引き
+	Memo           string               `json:"memo" binding:"max=500"`
+	Lines          []SaleLineRequest    `json:"lines" binding:"required,min=1,dive"`
+	Payments       []SalePaymentRequest `json:"payments" binding:"required,min=1,dive"`
+}
+
+type SaleLineRequest struct {
+	Kind           string `json:"kind" binding:"required,oneof=ticket treatment retail"`
+	CourseID       *uint  `json:"course_id"`   // ticket: 販売するコース（ticket_id がなければ回数券を発行する）/ treatment: 施術したコース
+	TicketID       *uint  `json:"ticket_id"`   // ticket: 発行済みの回数券の代金を受け取る場合
+	VisitID        *uint  `json:"visit_id"`    // treatment: 支払対象の来店記録
+	Description    string `json:"description" binding:"max=100"` // 省略時はコース名（retail は必須）
+	UnitPrice      *int   `json:"unit_price" binding:"omitempty,min=0"` // 省略時はコースの販売価格（retail は必須）
+	Quantity       int    `json:"quantity" binding:"min=0"` // 省略時は1
+	DiscountAmount int    `json:"discount_amount" binding:"min=0"`
+	TaxCategory    string `json:"tax_category" binding:"omitempty,oneof=standard reduced"` // 省略時はコースの税率区分（コースがなければ標準税率）
+}
+
+type SalePaymentRequest struct {
+	Method string `json:"method" binding:"required,oneof=cash card qr other"`
+	Amount int    `json:"amount" binding:"min=0"`
+}
+
+type SaleVoidRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+type RegisterOpenRequest struct {
+	StoreID      uint   `json:"store_id" binding:"required"`
+	OpeningFloat int    `json:"opening_float" binding:"min=0"` // 釣銭準備金
+	Note         string `json:"note" binding:"max=500"`
+}
+
+type RegisterMovementRequest struct {
+	Kind   string `json:"kind" binding:"required,oneof=in out"`
+	Amount int    `json:"amount" binding:"required,min=1"`
+	Reason string `json:"reason" binding:"required,max=200"`
+}
+
+type RegisterCloseRequest struct {
+	CountedCash *int   `json:"counted_cash" binding:"required,min=0"` // 数えた現金
+	Note        string `json:"note" binding:"max=500"`
+}
@@ -0,0 +1,309 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"salon-app/backend/internal/utils"
+)
+
+// 一覧APIの共通のクエリ（カーソルページング・並び替え・絞り込み）。
+//
+//	limit   1ページの件数（既定 100、最大 500）
+//	sort    並び替えの項目。"-" を付けると降順（例: sort=-created_at）。listSpec.Sorts にある項目のみ
+//	cursor  前のページのレスポンスの X-Next-Cursor
+//
+// NULL になるカラム（ポインタのフィールド）で並び替える場合、NULL の行は昇順・降順どちらでも最後に並ぶ。
+// 件数とカーソルはレスポンスヘッダー X-Total-Count / X-Next-Cursor で返す（本文の形は変えない）。
+// X-Next-Cursor がなければ最後のページ。X-Total-Count はページングする前の絞り込み後の件数。
+const (
+	defaultListLimit = 100
+	maxListLimit     = 500
+)
+
+// filterKind は絞り込みの値の型
+type filterKind int
+
+const (
+	filterID       filterKind = iota // 正の整数（ID）と一致
+	filterEnum                       // Values のいずれかと一致
+	filterSince                      // 日時のカラムが YYYY-MM-DD（JST）以降
+	filterUntil                      // 日時のカラムが YYYY-MM-DD（JST）の日まで（その日を含む）
+	filterDaySince                   // 日付（date 型）のカラムが YYYY-MM-DD 以降
+	filterDayUntil                   // 日付（date 型）のカラムが YYYY-MM-DD 以前
+)
+
+// listFilter はクエリパラメータ Param で Column を絞り込む定義
+type listFilter struct {
+	Param  string
+	Column string
+	Kind   filterKind
+	Values []string // filterEnum で許可する値
+}
+
+// listSpec は一覧APIごとの並び替え・絞り込みの定義
+type listSpec struct {
+	Sorts       map[string]string // sort の値 → カラム
+	DefaultSort string            // 例: "-created_at"
+	Filters     []listFilter
+}
+
+// listCursor は次のページの開始位置（最後の行の並び替えの値とID）。Value が nil なら最後の行の値は NULL
+type listCursor struct {
+	Sort  string  `json:"s"`
+	Value *string `json:"v"`
+	ID    uint    `json:"id"`
+}
+
+// paginate は spec に従って query を絞り込み・並び替え、1ページ分を dest に読み込む。
+// 件数・次のカーソルはレスポンスヘッダーに設定する。パラメータが正しくなければ 400、
+// DBエラーなら 500 を返して false。
+// preloads は件数の集計には使わず、ページの読み込みにだけ付ける。
+func paginate[T any](c *gin.Context, query *gorm.DB, spec listSpec, dest *[]T, preloads ...string) bool {
+	limit := defaultListLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(400, gin.H{"error": "limit は1以上の整数で指定してください"})
+			return false
+		}
+		limit = min(n, maxListLimit)
+	}
+
+	sortParam := c.DefaultQuery("sort", spec.DefaultSort)
+	column, ok := spec.Sorts[strings.TrimPrefix(sortParam, "-")]
+	if !ok {
+		c.JSON(400, gin.H{"error": "sort に指定できる項目は " + sortKeys(spec) + " です"})
+		return false
+	}
+	desc := strings.HasPrefix(sortParam, "-")
+
+	query, ok = applyListFilters(c, query, spec)
+	if !ok {
+		return false
+	}
+
+	var model T
+	var total int64
+	if err := query.Session(&gorm.Session{}).Model(&model).Count(&total).Error; err != nil {
+		c.Error(err)
+		c.JSON(500, gin.H{"error": "Failed to count records"})
+		return false
+	}
+
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(&model); err != nil {
+		c.Error(err)
+		c.JSON(500, gin.H{"error": "Failed to fetch records"})
+		return false
+	}
+	idColumn := stmt.Schema.Table + ".id"
+	nullable := nullableColumn(stmt, column)
+
+	if v := c.Query("cursor"); v != "" {
+		cur, err := decodeCursor(v)
+		if err == nil && (cur.Sort != sortParam || cur.Value == nil && !nullable) {
+			err = fmt.Errorf("cursor: does not match sort %s", sortParam)
+		}
+		var value interface{}
+		if err == nil && cur.Value != nil {
+			value, err = cursorValue(stmt, column, *cur.Value)
+		}
+		if err != nil {
+			c.JSON(400, gin.H{"error": "cursor が正しくありません（sort を変えた場合は先頭から取得し直してください）"})
+			return false
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		switch {
+		case cur.Value == nil:
+			// NULL の行は最後に並ぶので、残りは NULL の行の続きだけ
+			query = query.Where(column+" IS NULL AND "+idColumn+" "+op+" ?", cur.ID)
+		case nullable:
+			query = query.Where("(("+column+", "+idColumn+") "+op+" (?, ?) OR "+column+" IS NULL)", value, cur.ID)
+		default:
+			query = query.Where("("+column+", "+idColumn+") "+op+" (?, ?)", value, cur.ID)
+		}
+	}
+
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	if nullable {
+		query = query.Order(column + dir + " NULLS LAST")
+	} else {
+		query = query.Order(column + dir)
+	}
+	if column != idColumn {
+		query = query.Order(idColumn + dir)
+	}
+	query = query.Limit(limit + 1)
+	for _, p := range preloads {
+		query = query.Preload(p)
+	}
+	if err := query.Find(dest).Error; err != nil {
+		c.Error(err)
+		c.JSON(500, gin.H{"error": "Failed to fetch records"})
+		return false
+	}
+
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if len(*dest) > limit {
+		*dest = (*dest)[:limit]
+		next, err := nextCursor(stmt, sortParam, column, &(*dest)[limit-1])
+		if err != nil {
+			c.Error(err)
+			c.JSON(500, gin.H{"error": "Failed to fetch records"})
+			return false
+		}
+		c.Header("X-Next-Cursor", next)
+	}
+	return true
+}
+
+// applyListFilters は spec の絞り込みを query に付ける。値が正しくなければ 400 を返して false。
+// 一覧と同じ条件で集計する場合などに使う（paginate も内部で呼ぶ）。
+func applyListFilters(c *gin.Context, query *gorm.DB, spec listSpec) (*gorm.DB, bool) {
+	for _, f := range spec.Filters {
+		v := c.Query(f.Param)
+		if v == "" {
+			continue
+		}
+		cond, arg, msg := f.condition(v)
+		if msg != "" {
+			c.JSON(400, gin.H{"error": msg})
+			return query, false
+		}
+		query = query.Where(cond, arg)
+	}
+	return query, true
+}
+
+// condition は絞り込みの値を検証して WHERE 句と引数にする。値が正しくなければ msg にエラーメッセージ。
+func (f listFilter) condition(v string) (cond string, arg interface{}, msg string) {
+	switch f.Kind {
+	case filterID:
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || id == 0 {
+			return "", nil, f.Param + " は正の整数で指定してください"
+		}
+		return f.Column + " = ?", id, ""
+	case filterEnum:
+		for _, allowed := range f.Values {
+			if v == allowed {
+				return f.Column + " = ?", v, ""
+			}
+		}
+		return "", nil, f.Param + " は " + strings.Join(f.Values, " / ") + " のいずれかで指定してください"
+	}
+
+	day, err := time.ParseInLocation(dateLayout, v, utils.JST)
+	if err != nil {
+		return "", nil, f.Param + " の形式が正しくありません（YYYY-MM-DD）"
+	}
+	switch f.Kind {
+	case filterSince:
+		return f.Column + " >= ?", day, ""
+	case filterUntil:
+		return f.Column + " < ?", day.AddDate(0, 0, 1), ""
+	case filterDaySince:
+		return f.Column + " >= ?", day.Format(dateLayout), ""
+	default: // filterDayUntil
+		return f.Column + " <= ?", day.Format(dateLayout), ""
+	}
+}
+
+// nextCursor は row（ページの最後の行へのポインタ）から次のページのカーソルを作る
+func nextCursor(stmt *gorm.Statement, sortParam, column string, row interface{}) (string, error) {
+	rv := reflect.ValueOf(row).Elem()
+	name := column[strings.LastIndex(column, ".")+1:]
+	field, idField := stmt.Schema.LookUpField(name), stmt.Schema.LookUpField("id")
+	if field == nil || idField == nil {
+		return "", fmt.Errorf("cursor: unknown column %s", column)
+	}
+	ctx := context.Background()
+	value, _ := field.ValueOf(ctx, rv)
+	id, _ := idField.ValueOf(ctx, rv)
+	// NULL になるカラムはポインタなので、値を取り出してから文字列にする
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			value = nil
+		} else {
+			value = v.Elem().Interface()
+		}
+	}
+
+	cur := listCursor{Sort: sortParam, ID: uint(reflect.ValueOf(id).Uint())}
+	switch v := value.(type) {
+	case nil:
+	case time.Time:
+		s := v.Format(time.RFC3339Nano)
+		cur.Value = &s
+	default:
+		s := fmt.Sprint(v)
+		cur.Value = &s
+	}
+	b, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// nullableColumn は並び替えのカラムが NULL になりうる（ポインタのフィールド）かを返す
+func nullableColumn(stmt *gorm.Statement, column string) bool {
+	field := stmt.Schema.LookUpField(column[strings.LastIndex(column, ".")+1:])
+	return field != nil && field.FieldType.Kind() == reflect.Ptr
+}
+
+// cursorValue はカーソルの文字列の値を並び替えのカラムの型に戻す
+func cursorValue(stmt *gorm.Statement, column, value string) (interface{}, error) {
+	field := stmt.Schema.LookUpField(column[strings.LastIndex(column, ".")+1:])
+	if field == nil {
+		return nil, fmt.Errorf("cursor: unknown column %s", column)
+	}
+	t := field.FieldType
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return time.Parse(time.RFC3339Nano, value)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return strconv.ParseInt(value, 10, 64)
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		return strconv.ParseUint(value, 10, 64)
+	}
+	return value, nil
+}
+
+func decodeCursor(s string) (listCursor, error) {
+	var cur listCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(b, &cur)
+	return cur, err
+}
+
+func sortKeys(spec listSpec) string {
+	keys := make([]string, 0, len(spec.Sorts))
+	for k := range spec.Sorts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, " / ")
+}
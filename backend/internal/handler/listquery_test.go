@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// TestNextCursorNullableColumn はポインタのカラム（tickets.expires_at）のカーソルが、
+// 値があれば元の日時に戻せ、NULL なら値なし（nil）になることを確認する。
+func TestNextCursorNullableColumn(t *testing.T) {
+	s, err := schema.Parse(&model.Ticket{}, &sync.Map{}, schema.NamingStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := &gorm.Statement{Schema: s}
+	if !nullableColumn(stmt, "tickets.expires_at") || nullableColumn(stmt, "tickets.purchased_at") {
+		t.Fatal("nullableColumn: want expires_at nullable and purchased_at not")
+	}
+
+	expiresAt := time.Date(2026, 10, 18, 15, 0, 0, 123, time.UTC)
+	withValue := model.Ticket{ExpiresAt: &expiresAt}
+	withValue.ID = 7
+	next, err := nextCursor(stmt, "expires_at", "tickets.expires_at", &withValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cur, err := decodeCursor(next)
+	if err != nil || cur.Value == nil || cur.ID != 7 {
+		t.Fatalf("cursor = %+v, %v, want a value and id 7", cur, err)
+	}
+	value, err := cursorValue(stmt, "tickets.expires_at", *cur.Value)
+	if got, ok := value.(time.Time); err != nil || !ok || !got.Equal(expiresAt) {
+		t.Errorf("cursorValue = %v, %v, want %v", value, err, expiresAt)
+	}
+
+	withNull := model.Ticket{}
+	withNull.ID = 8
+	next, err = nextCursor(stmt, "expires_at", "tickets.expires_at", &withNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cur, err := decodeCursor(next); err != nil || cur.Value != nil || cur.ID != 8 {
+		t.Errorf("cursor = %+v, %v, want nil value and id 8", cur, err)
+	}
+}
+
+// TestPaginateNullableColumn は NULL を含むカラムで並び替えても、ページをたどると
+// 全件が1回ずつ、NULL の行が最後に（ID順で）返ることを確認する。
+func TestPaginateNullableColumn(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	store := model.Store{Name: "test store"}
+	mustExec(t, db.DB.Create(&store))
+	customer := model.Customer{LastName: "山田", FirstName: "花子", StoreID: store.ID}
+	mustExec(t, db.DB.Create(&customer))
+	course := model.Course{Name: "5回券", Price: 50000, TotalCount: 5, StoreID: store.ID}
+	mustExec(t, db.DB.Create(&course))
+	base := time.Now().Truncate(time.Second)
+	days := []int{3, 1, 0, 2, 0} // 0 は期限なし（NULL）
+	tickets := make([]model.Ticket, len(days))
+	for i, d := range days {
+		tickets[i] = newTicketFromCourse(customer.ID, course, base, model.PaymentCash, 0)
+		if d > 0 {
+			expiresAt := base.AddDate(0, 0, d)
+			tickets[i].ExpiresAt = &expiresAt
+		}
+	}
+	mustExec(t, db.DB.Create(&tickets))
+	t.Cleanup(func() {
+		db.DB.Unscoped().Delete(&tickets)
+		db.DB.Unscoped().Delete(&course)
+		db.DB.Unscoped().Delete(&customer)
+		db.DB.Unscoped().Delete(&store)
+	})
+
+	spec := listSpec{Sorts: map[string]string{"expires_at": "tickets.expires_at"}, DefaultSort: "expires_at"}
+	id := func(i int) uint { return tickets[i].ID }
+	tests := []struct {
+		sort string
+		want []uint
+	}{
+		{"expires_at", []uint{id(1), id(3), id(0), id(2), id(4)}},
+		{"-expires_at", []uint{id(0), id(3), id(1), id(2), id(4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			var got []uint
+			cursor := ""
+			for page := 0; page < len(tickets); page++ {
+				q := url.Values{"sort": {tt.sort}, "limit": {"2"}}
+				if cursor != "" {
+					q.Set("cursor", cursor)
+				}
+				w := httptest.NewRecorder()
+				c, _ := gin.CreateTestContext(w)
+				c.Request = httptest.NewRequest(http.MethodGet, "/?"+q.Encode(), nil)
+				var rows []model.Ticket
+				if !paginate(c, db.DB.Where("store_id = ?", store.ID), spec, &rows) {
+					t.Fatalf("page %d: status %d (%s)", page, w.Code, w.Body.String())
+				}
+				for _, r := range rows {
+					got = append(got, r.ID)
+				}
+				if cursor = w.Header().Get("X-Next-Cursor"); cursor == "" {
+					break
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("ids = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
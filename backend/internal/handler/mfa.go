@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+const (
+	mfaIssuer         = "Salon App" // 認証アプリに表示される発行者名
+	recoveryCodeCount = 10
+	mfaLockThreshold  = 5 // 二段階認証コードの連続失敗でロックするまでの回数
+)
+
+var totpCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+// mfaRequired はロールに二段階認証が必須かを返す。
+// 環境変数 MFA_REQUIRED_ROLES にカンマ区切りで指定する（例: "admin,manager"）。
+func mfaRequired(role string) bool {
+	for _, r := range strings.Split(os.Getenv("MFA_REQUIRED_ROLES"), ",") {
+		if strings.TrimSpace(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+func mfaThrottleKey(userID uint) string {
+	return "mfa:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// checkMFAThrottle は二段階認証コードの試行がロック中でないかを確認する。
+// ロック中なら 429（DBエラーなら 500）を返して false。
+func checkMFAThrottle(c *gin.Context, key string) bool {
+	until, locked, err := loginLockedUntil(db.DB, key)
+	if err != nil {
+		c.Error(err)
+		c.JSON(500, gin.H{"error": "サーバーエラーが発生しました"})
+		return false
+	}
+	if locked {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+		c.JSON(429, gin.H{"error": "認証コードの試行回数が上限を超えました。しばらくしてから再度お試しください"})
+		return false
+	}
+	return true
+}
+
+// recordMFAFailure は二段階認証コードの失敗回数を加算する（ログイン・無効化・リカバリーコード再発行で共通のキー）
+func recordMFAFailure(c *gin.Context, key string) {
+	if err := db.DB.Transaction(func(tx *gorm.DB) error {
+		return bumpThrottle(tx, key, mfaLockThreshold)
+	}); err != nil {
+		c.Error(err)
+	}
+}
+
+// verifySecondFactor は TOTP コード、またはリカバリーコードを検証する。
+// user は呼び出し側で行ロック（FOR UPDATE）しておくこと。
+func verifySecondFactor(tx *gorm.DB, user *model.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if totpCodePattern.MatchString(code) {
+		step, ok := utils.VerifyTOTP(user.TOTPSecret, code, time.Now())
+		// 一度使ったコード（同じか古いタイムステップ）は受け付けない
+		if !ok || step <= user.TOTPLastStep {
+			return false, nil
+		}
+		user.TOTPLastStep = step
+		return true, tx.Model(user).UpdateColumn("totp_last_step", step).Error
+	}
+
+	var recovery model.RecoveryCode
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, utils.HashToken(utils.NormalizeRecoveryCode(code))).
+		First(&recovery).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	recovery.UsedAt = &now
+	return true, tx.Save(&recovery).Error
+}
+
+// replaceRecoveryCodes は既存のリカバリーコードを破棄して新しく発行し、平文を返す（平文を返すのはこの時だけ）
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		rc := model.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(utils.NormalizeRecoveryCode(code))}
+		if err := tx.Create(&rc).Error; err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// clearMFA は二段階認証の設定とリカバリーコードを削除する
+func clearMFA(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"totp_secret":    "",
+		"totp_enabled":   false,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+}
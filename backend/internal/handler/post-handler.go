@@ -1,12 +1,20 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 	
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
 	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/permission"
 	"salon-app/backend/internal/utils"
 )
 
@@ -25,6 +33,14 @@ func SignUpHandler(c *gin.Context) {
         c.JSON(400, gin.H{"error": "入力が正しくありません"})
         return
     }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    // 自分より上位のロールは付与できない（manager が admin を作成する等を防ぐ）
+    if !permission.CanAssignRole(middleware.CurrentRole(c), req.Role) {
+        middleware.Forbidden(c, permission.UserManage)
+        return
+    }
     hashed, err := utils.HashPassword(req.Password)
     if err != nil {
         c.Error(err)
@@ -63,6 +79,13 @@ func StoreRegistrationHandler(c *gin.Context) {
     //DBに保存する構造体を定義
     var store model.Store
     store.Name = req.Name
+    store.Capacity = req.Capacity
+    if !applyStoreTaxSettings(c, &store, req.StoreTaxSettings) {
+        return
+    }
+    if !applyStoreRetentionSettings(c, &store, req.StoreRetentionSettings) {
+        return
+    }
     if err := db.DB.Create(&store).Error; err != nil {
         c.Error(err)
         c.JSON(500, gin.H{"error": "Failed to register store"})
@@ -87,17 +110,31 @@ func LoginHandler(c *gin.Context) {
         return
     }
 
-    // データベースからユーザーを検索
-    var user model.User
-    if err := db.DB.Preload("Store").Where("email = ?", req.Email).First(&user).Error; err != nil {
-        // ユーザーが見つからない場合
+    // ロック中なら照合せずに拒否（アカウント単位・IP単位）
+    accountKey, ipKey := accountThrottleKey(req.Email), ipThrottleKey(c.ClientIP())
+    until, locked, err := loginLockedUntil(db.DB, accountKey, ipKey)
+    if err != nil {
         c.Error(err)
-        c.JSON(401, gin.H{"error": "メールアドレスがありません"})
+        c.JSON(500, gin.H{"error": "サーバーエラーが発生しました"})
+        return
+    }
+    if locked {
+        c.Header("Retry-After", strconv.Itoa(int(time.Until(until).Seconds())+1))
+        c.JSON(429, gin.H{"error": "ログイン試行回数が上限を超えました。しばらくしてから再度お試しください"})
         return
     }
 
+    // データベースからユーザーを検索
+    // 見つからない場合もダミーのハッシュで照合し、応答時間からメールアドレスの有無を推測されないようにする
+    var user model.User
+    found := db.DB.Preload("Store").Where("email = ?", req.Email).First(&user).Error == nil
+    hash := user.Password
+    if !found {
+        hash = utils.DummyPasswordHash()
+    }
+
     // パスワードの照合 (Argon2)
-    match, err := utils.CheckPassword(req.Password, user.Password)
+    match, err := utils.CheckPassword(req.Password, hash)
 
     // 1. err が発生した場合だけ c.Error(err) を呼ぶ
     if err != nil {
@@ -106,16 +143,57 @@ func LoginHandler(c *gin.Context) {
         return
     }
 
-    // 2. match が false (パスワード不一致) の場合
-    if !match {
-        // ここで c.Error(err) を呼ぶと、err は nil なのでパニックになる
-        c.JSON(401, gin.H{"error": "パスワードが正しくありません"})
+    // 2. ユーザーがいない / パスワード不一致の場合
+    // どちらも同じメッセージを返す（アカウントの存在を推測されないように）
+    if !found || !match {
+        if err := recordLoginFailure(req.Email, c.ClientIP()); err != nil {
+            c.Error(err)
+            c.JSON(500, gin.H{"error": "サーバーエラーが発生しました"})
+            return
+        }
+        c.JSON(401, gin.H{"error": "メールアドレスまたはパスワードが正しくありません"})
+        return
+    }
+
+    // 成功したらアカウントの失敗回数はリセット（IP側は総当たり対策のため残す）
+    if _, err := clearLoginThrottle(db.DB, accountKey); err != nil {
+        c.Error(err)
+    }
+
+    // 二段階認証が有効なら、コード入力用のチャレンジトークンだけを返す
+    if user.TOTPEnabled {
+        challenge, err := utils.GenerateChallengeToken(user.ID, user.TokenVersion)
+        if err != nil {
+            c.Error(err)
+            c.JSON(500, gin.H{"error": "トークンの生成に失敗しました"})
+            return
+        }
+        c.JSON(200, gin.H{
+            "message":         "二段階認証コードを入力してください",
+            "mfa_required":    true,
+            "challenge_token": challenge,
+        })
+        return
+    }
+    // 二段階認証が必須のロールで未登録なら、登録専用のトークンを返す（/2fa 以下のAPIのみ利用可）
+    if mfaRequired(user.Role) {
+        enrollment, err := utils.GenerateEnrollmentToken(user.ID, user.Name, user.Role, user.StoreID, user.Store.Name, user.TokenVersion)
+        if err != nil {
+            c.Error(err)
+            c.JSON(500, gin.H{"error": "トークンの生成に失敗しました"})
+            return
+        }
+        c.JSON(200, gin.H{
+            "message":                 "二段階認証の登録が必要です",
+            "mfa_enrollment_required": true,
+            "enrollment_token":        enrollment,
+        })
         return
     }
 
     // JWTトークンの生成
-    // ログイン成功！ユーザーIDと権限（Role）をトークンに詰め込む
-    token, err := utils.GenerateToken(user.ID, user.Name, user.Role, user.StoreID, user.Store.Name)
+    // ログイン成功！アクセストークンとリフレッシュトークンを発行する
+    session, _, err := issueSession(db.DB, user)
     if err != nil {
         c.Error(err)
         c.JSON(500, gin.H{"error": "トークンの生成に失敗しました"})
@@ -123,14 +201,469 @@ func LoginHandler(c *gin.Context) {
     }
 
     // 成功レスポンス（トークンを返す）
+    session["message"] = "ログイン成功"
+    c.JSON(200, session)
+}
+
+// @Summary      二段階認証ログイン
+// @Description  ログインで返されたチャレンジトークンと、認証アプリのコード（またはリカバリーコード）を検証してトークンを発行します
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Param        body body MFALoginRequest true "チャレンジトークンとコード"
+// @Success      200 {object} map[string]interface{}
+// @Router       /login/2fa [post]
+func LoginMFAHandler(c *gin.Context) {
+    var req MFALoginRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    claims, err := utils.ParseToken(req.ChallengeToken)
+    if err != nil || claims["typ"] != "mfa_challenge" {
+        c.JSON(401, gin.H{"error": "チャレンジトークンが無効です。再度ログインしてください"})
+        return
+    }
+    userID, _ := claims["user_id"].(float64)
+    ver, _ := claims["ver"].(float64)
+
+    key := mfaThrottleKey(uint(userID))
+    if !checkMFAThrottle(c, key) {
+        return
+    }
+
+    var session gin.H
+    verified := false
+    err = db.DB.Transaction(func(tx *gorm.DB) error {
+        var user model.User
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Store").First(&user, uint(userID)).Error; err != nil {
+            return err
+        }
+        if !user.TOTPEnabled || user.TokenVersion != int(ver) {
+            return gorm.ErrRecordNotFound
+        }
+        ok, err := verifySecondFactor(tx, &user, req.Code)
+        if err != nil || !ok {
+            return err
+        }
+        verified = true
+        if _, err := clearLoginThrottle(tx, key); err != nil {
+            return err
+        }
+        session, _, err = issueSession(tx, user)
+        return err
+    })
+    if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "サーバーエラーが発生しました"})
+        return
+    }
+    if err != nil || !verified {
+        recordMFAFailure(c, key)
+        c.JSON(401, gin.H{"error": "認証コードが正しくありません"})
+        return
+    }
+    session["message"] = "ログイン成功"
+    c.JSON(200, session)
+}
+
+// @Summary      二段階認証セットアップ
+// @Description  TOTPの秘密鍵を発行し、認証アプリ登録用のURI（QRコード用）を返します。有効化は /2fa/enable で行います
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} map[string]string
+// @Router       /2fa/setup [post]
+func SetupMFAHandler(c *gin.Context) {
+    var user model.User
+    if err := db.DB.First(&user, middleware.CurrentUserID(c)).Error; err != nil {
+        c.JSON(404, gin.H{"error": "User not found"})
+        return
+    }
+    if user.TOTPEnabled {
+        c.JSON(409, gin.H{"error": "二段階認証はすでに有効です"})
+        return
+    }
+    secret, err := utils.GenerateTOTPSecret()
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "秘密鍵の生成に失敗しました"})
+        return
+    }
+    if err := db.DB.Model(&user).Updates(map[string]interface{}{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to save secret"})
+        return
+    }
     c.JSON(200, gin.H{
-        "message": "ログイン成功",
-        "token":   token, // フロントエンドはこれを受け取って保存する
-        "user": gin.H{
-            "name": user.Name,
-            "role": user.Role,
-        },
+        "secret":           secret, // 手入力用
+        "provisioning_uri": utils.TOTPProvisioningURI(mfaIssuer, user.Email, secret),
+    })
+}
+
+// @Summary      二段階認証有効化
+// @Description  認証アプリのコードを確認して二段階認証を有効にし、リカバリーコードを返します（リカバリーコードが表示されるのはこの時だけです）
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        body body MFACodeRequest true "認証コード"
+// @Success      200 {object} map[string]interface{}
+// @Router       /2fa/enable [post]
+func EnableMFAHandler(c *gin.Context) {
+    var req MFACodeRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+
+    var codes []string
+    var session gin.H
+    status := 200
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        var user model.User
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Store").First(&user, middleware.CurrentUserID(c)).Error; err != nil {
+            return err
+        }
+        if user.TOTPEnabled || user.TOTPSecret == "" {
+            status = 409
+            return nil
+        }
+        step, ok := utils.VerifyTOTP(user.TOTPSecret, strings.TrimSpace(req.Code), time.Now())
+        if !ok {
+            status = 400
+            return nil
+        }
+        if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+            return err
+        }
+        var err error
+        if codes, err = replaceRecoveryCodes(tx, user.ID); err != nil {
+            return err
+        }
+        // 登録用トークンでアクセスしている場合は、ここで通常のセッションを発行する
+        if c.GetString("token_type") == "mfa_enroll" {
+            session, _, err = issueSession(tx, user)
+        }
+        return err
+    })
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "二段階認証の有効化に失敗しました"})
+        return
+    }
+    switch status {
+    case 409:
+        c.JSON(409, gin.H{"error": "先に /2fa/setup を実行してください（すでに有効な場合は不要です）"})
+        return
+    case 400:
+        c.JSON(400, gin.H{"error": "認証コードが正しくありません"})
+        return
+    }
+
+    res := gin.H{"message": "二段階認証を有効にしました", "recovery_codes": codes}
+    for k, v := range session {
+        res[k] = v
+    }
+    c.JSON(200, res)
+}
+
+// @Summary      二段階認証無効化
+// @Description  認証コード（またはリカバリーコード）を確認して二段階認証を無効にします。必須ロールでは無効にできません。
+// @Description  二段階認証ログインと同じく、コードを続けて間違えると一定時間ロックされます（429）
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        body body MFACodeRequest true "認証コード"
+// @Success      200 {object} map[string]string
+// @Router       /2fa/disable [post]
+func DisableMFAHandler(c *gin.Context) {
+    var req MFACodeRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    if mfaRequired(middleware.CurrentRole(c)) {
+        c.JSON(403, gin.H{"error": "このロールでは二段階認証を無効にできません"})
+        return
+    }
+    // ログインの二段階認証と同じ失敗回数でロックする（盗まれたセッションからのコードの総当たり対策）
+    key := mfaThrottleKey(middleware.CurrentUserID(c))
+    if !checkMFAThrottle(c, key) {
+        return
+    }
+
+    verified := false
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        var user model.User
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, middleware.CurrentUserID(c)).Error; err != nil {
+            return err
+        }
+        if !user.TOTPEnabled {
+            return nil
+        }
+        ok, err := verifySecondFactor(tx, &user, req.Code)
+        if err != nil || !ok {
+            return err
+        }
+        verified = true
+        if _, err := clearLoginThrottle(tx, key); err != nil {
+            return err
+        }
+        return clearMFA(tx, user.ID)
+    })
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "二段階認証の無効化に失敗しました"})
+        return
+    }
+    if !verified {
+        recordMFAFailure(c, key)
+        c.JSON(400, gin.H{"error": "認証コードが正しくありません"})
+        return
+    }
+    c.JSON(200, gin.H{"message": "二段階認証を無効にしました"})
+}
+
+// @Summary      リカバリーコード再発行
+// @Description  認証コードを確認して、リカバリーコードを作り直します（古いコードは使えなくなります）。
+// @Description  二段階認証ログインと同じく、コードを続けて間違えると一定時間ロックされます（429）
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        body body MFACodeRequest true "認証コード"
+// @Success      200 {object} map[string]interface{}
+// @Router       /2fa/recovery-codes [post]
+func RegenerateRecoveryCodesHandler(c *gin.Context) {
+    var req MFACodeRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    key := mfaThrottleKey(middleware.CurrentUserID(c))
+    if !checkMFAThrottle(c, key) {
+        return
+    }
+
+    var codes []string
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        var user model.User
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, middleware.CurrentUserID(c)).Error; err != nil {
+            return err
+        }
+        if !user.TOTPEnabled {
+            return nil
+        }
+        ok, err := verifySecondFactor(tx, &user, req.Code)
+        if err != nil || !ok {
+            return err
+        }
+        if _, err := clearLoginThrottle(tx, key); err != nil {
+            return err
+        }
+        codes, err = replaceRecoveryCodes(tx, user.ID)
+        return err
+    })
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "リカバリーコードの再発行に失敗しました"})
+        return
+    }
+    if codes == nil {
+        recordMFAFailure(c, key)
+        c.JSON(400, gin.H{"error": "認証コードが正しくありません"})
+        return
+    }
+    c.JSON(200, gin.H{"recovery_codes": codes})
+}
+
+// @Summary      二段階認証リセット
+// @Description  認証アプリを紛失したスタッフの二段階認証を解除し、全セッションを無効化します（権限: user.security）
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200 {object} map[string]string
+// @Router       /users/{id}/2fa/reset [post]
+func ResetUserMFAHandler(c *gin.Context) {
+    id := c.Param("id")
+    var user model.User
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&user, id).Error; err != nil {
+        c.JSON(404, gin.H{"error": "User not found"})
+        return
+    }
+    if err := db.DB.Transaction(func(tx *gorm.DB) error {
+        if err := clearMFA(tx, user.ID); err != nil {
+            return err
+        }
+        return revokeAllSessions(tx, user.ID)
+    }); err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to reset 2FA"})
+        return
+    }
+    c.JSON(200, gin.H{"message": "二段階認証をリセットしました", "id": user.ID})
+}
+
+// @Summary      トークン再発行
+// @Description  リフレッシュトークンを使ってアクセストークンを再発行します。リフレッシュトークンは使用のたびに新しいものへ切り替わります
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Param        body body RefreshRequest true "リフレッシュトークン"
+// @Success      200 {object} map[string]interface{}
+// @Router       /refresh [post]
+func RefreshHandler(c *gin.Context) {
+    var req RefreshRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+
+    var session gin.H
+    reused := false
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        var current model.RefreshToken
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("token_hash = ?", utils.HashToken(req.RefreshToken)).First(&current).Error; err != nil {
+            return err
+        }
+        // 失効済みトークンの再利用は漏洩とみなし、そのユーザーの全セッションを無効化する
+        if current.RevokedAt != nil {
+            reused = true
+            return revokeAllSessions(tx, current.UserID)
+        }
+        if time.Now().After(current.ExpiresAt) {
+            return gorm.ErrRecordNotFound
+        }
+
+        var user model.User
+        if err := tx.Preload("Store").First(&user, current.UserID).Error; err != nil {
+            return err
+        }
+        var next *model.RefreshToken
+        var err error
+        session, next, err = issueSession(tx, user)
+        if err != nil {
+            return err
+        }
+        now := time.Now()
+        current.RevokedAt = &now
+        current.ReplacedByID = &next.ID
+        return tx.Save(&current).Error
     })
+    if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "トークンの再発行に失敗しました"})
+        return
+    }
+    if err != nil || reused {
+        c.JSON(401, gin.H{"error": "リフレッシュトークンが無効です。再度ログインしてください"})
+        return
+    }
+    c.JSON(200, session)
+}
+
+// @Summary      ログアウト
+// @Description  現在のアクセストークンと、指定されたリフレッシュトークンを失効させます
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Param        body body LogoutRequest false "リフレッシュトークン"
+// @Success      200 {object} map[string]string
+// @Router       /logout [post]
+func LogoutHandler(c *gin.Context) {
+    var req LogoutRequest
+    // ボディは任意（リフレッシュトークンを持っていないクライアントもある）
+    _ = c.ShouldBindJSON(&req)
+
+    userID := middleware.CurrentUserID(c)
+    jti, _ := c.Get("jti")
+    exp, _ := c.Get("exp")
+    expUnix, _ := exp.(float64)
+
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        // アクセストークンは有効期限まで失効リストに載せる
+        revoked := model.RevokedToken{JTI: fmt.Sprint(jti), ExpiresAt: time.Unix(int64(expUnix), 0)}
+        if err := tx.Create(&revoked).Error; err != nil {
+            return err
+        }
+        // 期限切れになった失効リストは不要なので掃除しておく
+        if err := tx.Unscoped().Where("expires_at < ?", time.Now()).Delete(&model.RevokedToken{}).Error; err != nil {
+            return err
+        }
+        if req.RefreshToken != "" {
+            return tx.Model(&model.RefreshToken{}).
+                Where("token_hash = ? AND user_id = ? AND revoked_at IS NULL", utils.HashToken(req.RefreshToken), userID).
+                Update("revoked_at", time.Now()).Error
+        }
+        return nil
+    })
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "ログアウトに失敗しました"})
+        return
+    }
+    c.JSON(200, gin.H{"message": "ログアウトしました"})
+}
+
+// @Summary      ログインロック解除
+// @Description  メールアドレスまたはIPアドレスのログイン失敗回数とロックを解除します（権限: user.security）
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Param        body body LoginUnlockRequest true "解除対象"
+// @Success      200 {object} map[string]interface{}
+// @Router       /login-locks/unlock [post]
+func UnlockLoginHandler(c *gin.Context) {
+    var req LoginUnlockRequest
+    if err := c.ShouldBindJSON(&req); err != nil || (req.Email == "" && req.IP == "") {
+        c.JSON(400, gin.H{"error": "email または ip を指定してください"})
+        return
+    }
+    var keys []string
+    if req.Email != "" {
+        keys = append(keys, accountThrottleKey(req.Email))
+    }
+    if req.IP != "" {
+        keys = append(keys, ipThrottleKey(req.IP))
+    }
+    cleared, err := clearLoginThrottle(db.DB, keys...)
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to unlock"})
+        return
+    }
+    c.JSON(200, gin.H{"message": "ロックを解除しました", "cleared": cleared})
+}
+
+// @Summary      全セッション無効化
+// @Description  指定したスタッフのログイン中セッションをすべて無効化します（権限: user.security）
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200 {object} map[string]string
+// @Router       /users/{id}/revoke-sessions [post]
+func RevokeUserSessionsHandler(c *gin.Context) {
+    id := c.Param("id")
+    var user model.User
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&user, id).Error; err != nil {
+        c.JSON(404, gin.H{"error": "User not found"})
+        return
+    }
+    if err := db.DB.Transaction(func(tx *gorm.DB) error {
+        return revokeAllSessions(tx, user.ID)
+    }); err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to revoke sessions"})
+        return
+    }
+    c.JSON(200, gin.H{"message": "セッションを無効化しました", "id": user.ID})
 }
 
 // @Summary      コース登録
@@ -147,10 +680,18 @@ func CourseRegistrationHandler(c *gin.Context) {
         c.JSON(400, gin.H{"error": "入力が正しくありません"})
         return
     }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
     var course model.Course
     course.Name = req.Name
     course.Price = req.Price
     course.TotalCount = req.TotalCount
+    course.DurationMinutes = req.DurationMinutes
+    course.BufferMinutes = req.BufferMinutes
+    course.OnlineBookable = req.OnlineBookable
+    course.ValidityDays = req.ValidityDays
+    course.TaxCategory = defaultString(req.TaxCategory, model.TaxStandard)
     course.StoreID = req.StoreID
     if err := db.DB.Create(&course).Error; err != nil {
         c.Error(err)
@@ -160,70 +701,210 @@ func CourseRegistrationHandler(c *gin.Context) {
     c.JSON(200, gin.H{"message": "登録完了"})
 }
 
+// @Summary      回数券の購入
+// @Description  コースの内容（コース名・価格・回数・有効期間）をスナップショットとして回数券を発行し、購入日時・支払金額・支払方法を記録します。同じ顧客・コースで複数の回数券を持てます
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        body body TicketPurchaseRequest true "購入内容"
+// @Success      200 {object} model.Ticket
+// @Router       /tickets [post]
+func CreateTicketHandler(c *gin.Context) {
+    var req TicketPurchaseRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    var customer model.Customer
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&customer, req.CustomerID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "顧客が見つかりません"})
+        return
+    }
+    var course model.Course
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&course, req.CourseID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "コースが見つかりません"})
+        return
+    }
+    if customer.StoreID != req.StoreID || course.StoreID != req.StoreID {
+        c.JSON(400, gin.H{"error": "顧客・コースと購入店舗が一致しません"})
+        return
+    }
+    if course.TotalCount <= 0 {
+        c.JSON(400, gin.H{"error": "このコースは回数券として販売できません"})
+        return
+    }
+
+    purchasedAt := time.Now()
+    if req.PurchasedAt != nil {
+        purchasedAt = *req.PurchasedAt
+    }
+    ticket := newTicketFromCourse(customer.ID, course, purchasedAt, req.PaymentMethod, middleware.CurrentUserID(c))
+    if req.PricePaid != nil {
+        ticket.PricePaid = *req.PricePaid
+    }
+    if err := db.DB.Create(&ticket).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to create ticket"})
+        return
+    }
+    c.JSON(200, ticket)
+}
+
 // @Summary      来店登録
-// @Description  来店登録
+// @Description  来店登録。ticket_id を指定するとその回数券を1回分消化し（同じトランザクションで行います）、null なら都度払いとして登録します。Idempotency-Key ヘッダーを付けると、同じキーでの再送は二重に登録せず最初の結果を返します
 // @Tags         system
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} map[string]string
+// @Param        Idempotency-Key  header  string  false  "再送時の二重登録防止キー"
+// @Success      200 {object} map[string]interface{}
 // @Router       /visit-registration [post]
 func VisitRegistrationHandler(c *gin.Context) {
     var req VisitRegistrationRequest
-    var ticket model.Ticket
     if err := c.ShouldBindJSON(&req); err != nil {
         log.Printf("BindJSON error: %v", err)
         c.JSON(400, gin.H{"error": "入力が正しくありません"})
         return
     }
-    //　コースの検索
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    key := strings.TrimSpace(c.GetHeader("Idempotency-Key"))
+    if len(key) > 100 {
+        c.JSON(400, gin.H{"error": "Idempotency-Key が長すぎます"})
+        return
+    }
+    // 同じキーで登録済みなら、その結果を返す
+    if key != "" && replayVisit(c, key, req) {
+        return
+    }
+
+    //　顧客・コースの検索（他店舗のものは見つからない扱い）
+    var customer model.Customer
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&customer, req.CustomerID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "顧客が見つかりません"})
+        return
+    }
     var course model.Course
-    if err := db.DB.First(&course, req.CourseID).Error; err != nil {
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&course, req.CourseID).Error; err != nil {
         c.JSON(404, gin.H{"error": "コースが見つかりません"})
         return
     }
-    // チケットの検索
-    result := db.DB.Where("customer_id = ? AND course_id = ? AND is_completed = ?", 
-             req.CustomerID, req.CourseID, false).First(&ticket)
-    // なければ作成
-    if result.Error != nil {
-        // なければ新規チケットを Course.TotalCount で作成
-        ticket = model.Ticket{
-            CustomerID:   req.CustomerID,
-            CourseID:     req.CourseID,
-            TotalCount:   course.TotalCount,
-            CurrentCount: 0,      // まだ 0 回消化
-            IsCompleted:  false,
-            StoreID:      req.StoreID,
-        }
-        db.DB.Create(&ticket)
-    }
-    // チケットの更新
-    ticket.CurrentCount += 1
-    // 今回の＋1で規定回数に達した（または超えた）かチェック
-    if ticket.CurrentCount >= ticket.TotalCount {
-        ticket.IsCompleted = true
-        log.Printf("チケットを使い切りました！ (ID: %d, Count: %d/%d)", 
-            ticket.ID, ticket.CurrentCount, ticket.TotalCount)
-    }
-    if err := db.DB.Save(&ticket).Error; err != nil {
-        log.Printf("チケットの更新に失敗: %v", err)
-        c.JSON(500, gin.H{"error": "チケットの更新に失敗しました"})
-        return
-    }
-    //　来店記録の作成
+    if customer.StoreID != req.StoreID || course.StoreID != req.StoreID {
+        c.JSON(400, gin.H{"error": "顧客・コースと来店店舗が一致しません"})
+        return
+    }
+    if req.StaffID != nil {
+        ok, err := staffBelongsToStore(db.DB, *req.StaffID, req.StoreID)
+        if err != nil {
+            c.Error(err)
+            c.JSON(500, gin.H{"error": "Failed to register visit"})
+            return
+        }
+        if !ok {
+            c.JSON(400, gin.H{"error": "担当スタッフと来店店舗が一致しません"})
+            return
+        }
+    }
+    //　来店記録の作成（チケットの消化も同じトランザクションで行う）
     var visit model.Visit
     visit.CustomerID = req.CustomerID
     visit.CourseID = req.CourseID
     visit.TicketID = req.TicketID
-    visit.VisitCount = req.VisitCount
     visit.StoreID = req.StoreID
+    visit.StaffID = req.StaffID
     visit.Memo = req.Memo
-    if err := db.DB.Create(&visit).Error; err != nil {
+    if key != "" {
+        visit.IdempotencyKey = &key
+    }
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        return registerVisit(tx, &visit)
+    })
+    if err != nil {
+        // 同じキーの登録が同時に来て先に確定した場合は、その結果を返す
+        if key != "" && replayVisit(c, key, req) {
+            return
+        }
+        if status, msg, ok := ticketErrorResponse(err); ok {
+            c.JSON(status, gin.H{"error": msg})
+            return
+        }
         c.Error(err)
         c.JSON(500, gin.H{"error": "Failed to register visit"})
         return
     }
-    c.JSON(200, gin.H{"message": "登録完了"})
+    c.JSON(200, gin.H{"message": "登録完了", "visit": visit})
+}
+
+// replayVisit は Idempotency-Key で登録済みの来店記録があればレスポンスを返して true を返す。
+// 別の内容の登録に使われたキーなら 409 を返す。
+func replayVisit(c *gin.Context, key string, req VisitRegistrationRequest) bool {
+    var visit model.Visit
+    if err := db.DB.Where("idempotency_key = ?", key).First(&visit).Error; err != nil {
+        return false
+    }
+    if visit.CustomerID != req.CustomerID || visit.CourseID != req.CourseID || visit.StoreID != req.StoreID {
+        c.JSON(409, gin.H{"error": "この Idempotency-Key は別の来店登録で使用されています"})
+        return true
+    }
+    c.JSON(200, gin.H{"message": "登録完了", "visit": visit})
+    return true
+}
+
+// @Summary      来店取消
+// @Description  来店記録を取り消し、消化したチケットの1回分を戻します。取り消した来店記録は理由とともに残ります
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Visit ID"
+// @Param        body body VisitVoidRequest true "取消理由"
+// @Success      200 {object} model.Visit
+// @Router       /visit/{id}/void [post]
+func VoidVisitHandler(c *gin.Context) {
+    var req VisitVoidRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "取消理由を入力してください"})
+        return
+    }
+    var visit model.Visit
+    errVoided := errors.New("visit already voided")
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Scopes(middleware.StoreScope(c)).Clauses(clause.Locking{Strength: "UPDATE"}).
+            First(&visit, c.Param("id")).Error; err != nil {
+            return err
+        }
+        if visit.VoidedAt != nil {
+            return errVoided
+        }
+        if err := releaseTicket(tx, visit); err != nil {
+            return err
+        }
+        now := time.Now()
+        visit.VoidedAt = &now
+        visit.VoidReason = req.Reason
+        if userID := middleware.CurrentUserID(c); userID != 0 {
+            visit.VoidedByID = &userID
+        }
+        return tx.Omit(clause.Associations).Save(&visit).Error
+    })
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(404, gin.H{"error": "Visit not found"})
+        return
+    }
+    if errors.Is(err, errVoided) {
+        c.JSON(409, gin.H{"error": "この来店記録はすでに取り消されています"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to void visit"})
+        return
+    }
+    c.JSON(200, visit)
 }
 
 // @Summary      顧客登録
@@ -240,6 +921,15 @@ func CustomerRegistrationHandler(c *gin.Context) {
         c.JSON(400, gin.H{"error": "入力が正しくありません"})
         return
     }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    if !normalizeCustomerKana(c, &req.LastNameKana, &req.FirstNameKana) {
+        return
+    }
+    if !normalizeCustomerAddress(c, &req.ZipCode, req.PrefName) {
+        return
+    }
     var customer model.Customer
     customer.LastName = req.LastName
     customer.FirstName = req.FirstName
@@ -252,6 +942,7 @@ func CustomerRegistrationHandler(c *gin.Context) {
     customer.Sex = req.Sex
     customer.BirthDate = req.BirthDate
     customer.Phone = req.Phone
+    customer.Email = req.Email
     customer.StoreID = req.StoreID
     if err := db.DB.Create(&customer).Error; err != nil {
         c.Error(err)
@@ -261,43 +952,53 @@ func CustomerRegistrationHandler(c *gin.Context) {
     c.JSON(200, gin.H{"message": "登録完了"})
 }
 
-// @Summary      顧客検索(苗字：ひらがな)
-// @Description  顧客検索(苗字：ひらがな)
+// @Summary      顧客検索
+// @Description  氏名（漢字）・フリガナ（ひらがな／カタカナ、全角／半角を区別しない）・電話番号（ハイフンを無視）・メールアドレスで顧客を探します。
+// @Description  完全一致 → 前方一致 → 部分一致の順に、同じなら最終来店の新しい順に返します。POST の場合は本文の q（旧形式の last_name_kana も可）で検索します
 // @Tags         system
 // @Accept       json
 // @Produce      json
-// @Success      200 {object} model.Customer
+// @Param        q         query  string  false  "検索語"
+// @Param        store_id  query  int     false  "店舗ID"
+// @Param        limit     query  int     false  "件数（既定 20、最大 50）"
+// @Success      200 {array} CustomerSearchResult
 // @Router       /customer-search [get]
 func GetCustomerSearchHandler(c *gin.Context) {
-    // 1人ではなく、複数人を格納するためにスライス（[]）で定義
-    var customers []model.Customer
-    var req CustomerSearchRequest
+    q := c.Query("q")
+    if c.Request.Method == "POST" {
+        var req CustomerSearchRequest
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.Error(err)
+            c.JSON(400, gin.H{"error": "入力が正しくありません"})
+            return
+        }
+        q = defaultString(req.Q, req.LastNameKana)
+    }
+    limit := defaultCustomerSearchLimit
+    if v := c.Query("limit"); v != "" {
+        n, err := strconv.Atoi(v)
+        if err != nil || n < 1 {
+            c.JSON(400, gin.H{"error": "limit は1以上の整数で指定してください"})
+            return
+        }
+        limit = min(n, maxCustomerSearchLimit)
+    }
 
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.Error(err)
-        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+    // 結果が0件でも空の配列を返す（フロントで扱いやすくするため）
+    customers := []CustomerSearchResult{}
+    query := customerSearchQuery(middleware.StoreScopeColumn(c, "customers.store_id"), q)
+    if query == nil {
+        c.JSON(200, customers)
         return
     }
-
-    fmt.Println("検索開始")
-    fmt.Println(req.LastNameKana)
-
-    // 文字列が空の場合は検索せずに空配列を返す（DB負荷軽減）
-    if req.LastNameKana == "" {
-        c.JSON(200, []model.Customer{})
+    query, ok := applyListFilters(c, query, customerSearchSpec)
+    if !ok {
         return
     }
-
-    query := "%" + req.LastNameKana + "%"
-    if err := db.DB.Where("last_name_kana LIKE ?", query).Limit(10).Find(&customers).Error; err != nil {
+    if err := query.Limit(limit).Scan(&customers).Error; err != nil {
         c.Error(err)
         c.JSON(500, gin.H{"error": "検索中にエラーが発生しました"})
         return
     }
-
-    fmt.Println("検索結果")
-    fmt.Println(customers)
-
-    // 結果が0件でも空のスライス [] が返るので、フロントで扱いやすくなります
     c.JSON(200, customers)
 }
\ No newline at end of file
@@ -0,0 +1,35 @@
+package handler
+
+import (
+    "github.com/gin-gonic/gin"
+    "salon-app/backend/internal/model"
+    "salon-app/backend/internal/postal"
+)
+
+// @Summary      郵便番号検索
+// @Description  郵便番号（ハイフン・全角数字可）から都道府県・市区町村・町域を返します。1つの郵便番号に複数の町域がある場合は町域ごとに返します
+// @Tags         system
+// @Accept       json
+// @Produce      json
+// @Param        zip  path  string  true  "郵便番号（例: 1000001 / 100-0001）"
+// @Success      200 {array} model.PostalCode
+// @Router       /postal/{zip} [get]
+func GetPostalCodeHandler(c *gin.Context) {
+    zip, ok := postal.NormalizeZip(c.Param("zip"))
+    if !ok {
+        c.JSON(400, gin.H{"error": "郵便番号は7桁の数字で入力してください"})
+        return
+    }
+    var rows []model.PostalCode
+    var err error
+    if rows, err = postal.Lookup(zip); err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to look up postal code"})
+        return
+    }
+    if len(rows) == 0 {
+        c.JSON(404, gin.H{"error": "Postal code not found"})
+        return
+    }
+    c.JSON(200, rows)
+}
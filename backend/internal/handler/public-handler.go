@@ -0,0 +1,441 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+// /api/public 以下は認証なしで公開するオンライン予約用のAPI。
+// 予約者には確認トークンを返し、確認・キャンセル・日時変更はそのトークンで行う。
+
+// publicSlot はオンライン予約向けの空き枠（担当スタッフは公開しない）
+type publicSlot struct {
+    StartAt time.Time `json:"start_at"`
+    EndAt   time.Time `json:"end_at"`
+}
+
+// @Summary      オンライン予約: コース一覧
+// @Description  店舗のオンライン予約可能なコース一覧
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        store_id  path  int  true  "Store ID"
+// @Success      200 {object} map[string]interface{}
+// @Router       /public/stores/{store_id}/courses [get]
+func GetPublicCoursesHandler(c *gin.Context) {
+    var courses []struct {
+        ID              uint   `json:"id"`
+        Name            string `json:"name"`
+        Price           int    `json:"price"`
+        DurationMinutes int    `json:"duration_minutes"`
+    }
+    if err := db.DB.Model(&model.Course{}).Scopes(onlineBookableCourses(c.Param("store_id"))).
+        Select("id", "name", "price", "duration_minutes").Order("id").Find(&courses).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch courses"})
+        return
+    }
+    c.JSON(200, gin.H{"courses": courses})
+}
+
+// @Summary      オンライン予約: 空き枠
+// @Description  コースの予約可能な開始時刻一覧
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        store_id   path   int     true   "Store ID"
+// @Param        course_id  query  int     true   "コースID"
+// @Param        from       query  string  false  "開始日 YYYY-MM-DD"
+// @Param        to         query  string  false  "終了日 YYYY-MM-DD"
+// @Success      200 {object} map[string]interface{}
+// @Router       /public/stores/{store_id}/availability [get]
+func GetPublicAvailabilityHandler(c *gin.Context) {
+    store, course, ok := loadPublicCourse(c, c.Query("course_id"))
+    if !ok {
+        return
+    }
+    from, to, ok := parseDateRange(c.Query("from"), c.Query("to"))
+    if !ok {
+        c.JSON(400, gin.H{"error": "期間の指定が正しくありません（YYYY-MM-DD、最大31日）"})
+        return
+    }
+    slots, err := findAvailableSlots(db.DB, store, course, from, to, 0, 0)
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to search availability"})
+        return
+    }
+    res := make([]publicSlot, 0, len(slots))
+    for _, s := range slots {
+        res = append(res, publicSlot{StartAt: s.StartAt, EndAt: s.EndAt})
+    }
+    c.JSON(200, gin.H{"slots": res})
+}
+
+// @Summary      オンライン予約: 予約申し込み
+// @Description  予約を申し込みます。電話番号・フリガナで既存顧客と照合し、見つからなければ仮登録の顧客を作成します。確認・キャンセル・変更用のトークンを返します
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        store_id  path  int  true  "Store ID"
+// @Param        body body PublicBookingRequest true "予約内容"
+// @Success      200 {object} map[string]interface{}
+// @Router       /public/stores/{store_id}/bookings [post]
+func CreatePublicBookingHandler(c *gin.Context) {
+    var req PublicBookingRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    if !normalizeCustomerKana(c, &req.LastNameKana, &req.FirstNameKana) {
+        return
+    }
+    store, course, ok := loadPublicCourse(c, req.CourseID)
+    if !ok {
+        return
+    }
+    staffID, ok := pickAvailableStaff(store, course, req.StartAt, 0, 0)
+    if !ok {
+        c.JSON(409, gin.H{"error": "選択された時間は予約できません。別の時間をお選びください"})
+        return
+    }
+
+    plain, hash, err := utils.GenerateOpaqueToken()
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "予約に失敗しました"})
+        return
+    }
+
+    var reservation model.Reservation
+    var conflict string
+    err = db.DB.Transaction(func(tx *gorm.DB) error {
+        customer, err := matchOrCreateCustomer(tx, store.ID, req)
+        if err != nil {
+            return err
+        }
+        reservation = model.Reservation{
+            CustomerID:            customer.ID,
+            CourseID:              course.ID,
+            StoreID:               store.ID,
+            StaffID:               staffID,
+            StartAt:               req.StartAt,
+            EndAt:                 req.StartAt.Add(time.Duration(course.DurationMinutes) * time.Minute),
+            BufferMinutes:         course.BufferMinutes,
+            Status:                model.ReservationBooked,
+            Source:                "online",
+            ConfirmationTokenHash: &hash,
+        }
+        locked, err := lockStore(tx, store.ID)
+        if err != nil {
+            return err
+        }
+        if conflict, err = reservationConflict(tx, locked, reservation); err != nil || conflict != "" {
+            // 顧客の仮登録も取り消す
+            if err == nil {
+                err = errSlotTaken
+            }
+            return err
+        }
+        return tx.Create(&reservation).Error
+    })
+    if errors.Is(err, errSlotTaken) {
+        c.JSON(409, gin.H{"error": "選択された時間は予約できません。別の時間をお選びください"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "予約に失敗しました"})
+        return
+    }
+
+    res := publicBookingSummary(reservation, store, course)
+    res["confirmation_token"] = plain // 確認・キャンセル・変更リンクに使う。再表示はできない
+    c.JSON(200, res)
+}
+
+// @Summary      オンライン予約: 予約確認
+// @Description  確認トークンで予約内容を取得します
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        token  path  string  true  "確認トークン"
+// @Success      200 {object} map[string]interface{}
+// @Router       /public/bookings/{token} [get]
+func GetPublicBookingHandler(c *gin.Context) {
+    reservation, ok := loadPublicBooking(c, db.DB)
+    if !ok {
+        return
+    }
+    c.JSON(200, publicBookingSummary(reservation, reservation.Store, reservation.Course))
+}
+
+// @Summary      オンライン予約: キャンセル
+// @Description  確認トークンで予約をキャンセルします（予約開始時刻まで）
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        token  path  string  true  "確認トークン"
+// @Success      200 {object} map[string]interface{}
+// @Router       /public/bookings/{token}/cancel [post]
+func CancelPublicBookingHandler(c *gin.Context) {
+    var reservation model.Reservation
+    errNotChangeable := errors.New("booking not changeable")
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        // 同時に来た変更・店舗側の操作と競合しないよう、行ロックを取ってから状態を確認する
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+            Where("confirmation_token_hash = ?", utils.HashToken(c.Param("token"))).
+            First(&reservation).Error; err != nil {
+            return err
+        }
+        if !bookingChangeable(reservation) {
+            return errNotChangeable
+        }
+        now := time.Now()
+        reservation.Status = model.ReservationCancelled
+        reservation.CancelledAt = &now
+        return tx.Omit(clause.Associations).Save(&reservation).Error
+    })
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(404, gin.H{"error": "予約が見つかりません"})
+        return
+    }
+    if errors.Is(err, errNotChangeable) {
+        c.JSON(409, gin.H{"error": errBookingNotChangeableMsg})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "キャンセルに失敗しました"})
+        return
+    }
+    reservation, ok := loadPublicBooking(c, db.DB)
+    if !ok {
+        return
+    }
+    c.JSON(200, publicBookingSummary(reservation, reservation.Store, reservation.Course))
+}
+
+// @Summary      オンライン予約: 日時変更
+// @Description  確認トークンで予約の日時を変更します（予約開始時刻まで。オンライン予約の対象から外れたコースは変更できません）
+// @Tags         public
+// @Accept       json
+// @Produce      json
+// @Param        token  path  string  true  "確認トークン"
+// @Param        body body PublicRescheduleRequest true "変更後の日時"
+// @Success      200 {object} map[string]interface{}
+// @Router       /public/bookings/{token}/reschedule [post]
+func ReschedulePublicBookingHandler(c *gin.Context) {
+    var req PublicRescheduleRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    reservation, ok := loadPublicBooking(c, db.DB)
+    if !ok {
+        return
+    }
+    if !publicBookingChangeable(c, reservation) {
+        return
+    }
+    // 申し込み後にオンライン予約の対象から外されたコースは、オンラインでは日時を変えられない
+    var n int64
+    if err := db.DB.Model(&model.Course{}).Scopes(onlineBookableCourses(reservation.StoreID)).
+        Where("id = ?", reservation.CourseID).Count(&n).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "予約の変更に失敗しました"})
+        return
+    }
+    if n == 0 {
+        c.JSON(409, gin.H{"error": errBookingNotChangeableMsg})
+        return
+    }
+    // できるだけ同じ担当者のまま変更する
+    staffID, ok := pickAvailableStaff(reservation.Store, reservation.Course, req.StartAt, reservation.StaffID, reservation.ID)
+    if !ok {
+        c.JSON(409, gin.H{"error": "選択された時間は予約できません。別の時間をお選びください"})
+        return
+    }
+    reservation.StaffID = staffID
+    reservation.StartAt = req.StartAt
+    reservation.EndAt = req.StartAt.Add(time.Duration(reservation.Course.DurationMinutes) * time.Minute)
+    reservation.BufferMinutes = reservation.Course.BufferMinutes
+
+    errNotChangeable := errors.New("booking not changeable")
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        store, err := lockStore(tx, reservation.StoreID)
+        if err != nil {
+            return err
+        }
+        // 読み込んだ後にキャンセルされていないか、行ロックを取ってから確認し直す
+        var current model.Reservation
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, reservation.ID).Error; err != nil {
+            return err
+        }
+        if !bookingChangeable(current) {
+            return errNotChangeable
+        }
+        // 日時と担当だけを書き換え、その間に店舗側で変えられた項目は残す
+        current.StaffID = reservation.StaffID
+        current.StartAt = reservation.StartAt
+        current.EndAt = reservation.EndAt
+        current.BufferMinutes = reservation.BufferMinutes
+        reservation.Status = current.Status
+        conflict, err := reservationConflict(tx, store, current)
+        if err != nil {
+            return err
+        }
+        if conflict != "" {
+            return errSlotTaken
+        }
+        return tx.Save(&current).Error
+    })
+    if errors.Is(err, errNotChangeable) {
+        c.JSON(409, gin.H{"error": errBookingNotChangeableMsg})
+        return
+    }
+    if errors.Is(err, errSlotTaken) {
+        c.JSON(409, gin.H{"error": "選択された時間は予約できません。別の時間をお選びください"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "予約の変更に失敗しました"})
+        return
+    }
+    c.JSON(200, publicBookingSummary(reservation, reservation.Store, reservation.Course))
+}
+
+var errSlotTaken = errors.New("slot already taken")
+
+// onlineBookableCourses は店舗のコースのうち、オンライン予約で選べるもの（施術時間が設定済み）に絞り込む
+func onlineBookableCourses(storeID interface{}) func(*gorm.DB) *gorm.DB {
+    return func(tx *gorm.DB) *gorm.DB {
+        return tx.Where("store_id = ? AND online_bookable = ? AND duration_minutes > 0", storeID, true)
+    }
+}
+
+// loadPublicCourse は店舗とオンライン予約可能なコースを取得する。見つからなければ 404 を返して false。
+func loadPublicCourse(c *gin.Context, courseID interface{}) (model.Store, model.Course, bool) {
+    var store model.Store
+    var course model.Course
+    if err := db.DB.First(&store, c.Param("store_id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "店舗が見つかりません"})
+        return store, course, false
+    }
+    if err := db.DB.Scopes(onlineBookableCourses(store.ID)).First(&course, courseID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "コースが見つかりません"})
+        return store, course, false
+    }
+    return store, course, true
+}
+
+// loadPublicBooking は URL の確認トークンから予約を取得する。見つからなければ 404 を返して false。
+func loadPublicBooking(c *gin.Context, tx *gorm.DB) (model.Reservation, bool) {
+    var reservation model.Reservation
+    if err := tx.Preload("Store").Preload("Course").Preload("Customer").
+        Where("confirmation_token_hash = ?", utils.HashToken(c.Param("token"))).
+        First(&reservation).Error; err != nil {
+        c.JSON(404, gin.H{"error": "予約が見つかりません"})
+        return reservation, false
+    }
+    return reservation, true
+}
+
+const errBookingNotChangeableMsg = "この予約はオンラインでは変更できません。店舗へお問い合わせください"
+
+// bookingChangeable は予約者自身がキャンセル・変更できる状態（予約済み・確定で、開始前）かを返す
+func bookingChangeable(r model.Reservation) bool {
+    return (r.Status == model.ReservationBooked || r.Status == model.ReservationConfirmed) && r.StartAt.After(time.Now())
+}
+
+// publicBookingChangeable は予約者自身がキャンセル・変更できる状態かを確認する（できなければ 409 を返す）
+func publicBookingChangeable(c *gin.Context, r model.Reservation) bool {
+    if !bookingChangeable(r) {
+        c.JSON(409, gin.H{"error": errBookingNotChangeableMsg})
+        return false
+    }
+    return true
+}
+
+// pickAvailableStaff は start に空いている担当スタッフを選ぶ。preferred が空いていればそのスタッフを優先する。
+// 日時変更では exceptID に変更する予約のIDを渡し、その予約自身の枠は空いているものとして扱う。
+func pickAvailableStaff(store model.Store, course model.Course, start time.Time, preferred, exceptID uint) (uint, bool) {
+    day := start.In(utils.JST)
+    day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, utils.JST)
+    slots, err := findAvailableSlots(db.DB, store, course, day, day, 0, exceptID)
+    if err != nil {
+        return 0, false
+    }
+    for _, s := range slots {
+        if !s.StartAt.Equal(start) {
+            continue
+        }
+        for _, id := range s.StaffIDs {
+            if id == preferred {
+                return id, true
+            }
+        }
+        return s.StaffIDs[0], true
+    }
+    return 0, false
+}
+
+// matchOrCreateCustomer は電話番号（ハイフン等を除いた数字）→ フリガナ（姓名）の順で既存顧客を探し、
+// 見つからなければ仮登録の顧客を作成する
+func matchOrCreateCustomer(tx *gorm.DB, storeID uint, req PublicBookingRequest) (model.Customer, error) {
+    var customer model.Customer
+    phone := utils.PhoneDigits(req.Phone)
+    if phone != "" {
+        err := tx.Where("store_id = ? AND search_phone = ?", storeID, phone).
+            Order("id").First(&customer).Error
+        if err == nil {
+            return customer, nil
+        }
+        if !errors.Is(err, gorm.ErrRecordNotFound) {
+            return customer, err
+        }
+    }
+    lastKana, firstKana := req.LastNameKana, req.FirstNameKana // 正規化済み（normalizeCustomerKana）
+    err := tx.Where("store_id = ? AND last_name_kana = ? AND first_name_kana = ?", storeID, lastKana, firstKana).
+        Order("id").First(&customer).Error
+    if err == nil {
+        return customer, nil
+    }
+    if !errors.Is(err, gorm.ErrRecordNotFound) {
+        return customer, err
+    }
+
+    customer = model.Customer{
+        LastName:      strings.TrimSpace(req.LastName),
+        FirstName:     strings.TrimSpace(req.FirstName),
+        LastNameKana:  lastKana,
+        FirstNameKana: firstKana,
+        Phone:         req.Phone,
+        Email:         req.Email,
+        StoreID:       storeID,
+        IsProvisional: true,
+    }
+    return customer, tx.Create(&customer).Error
+}
+
+// publicBookingSummary は予約者に返す予約内容（社内向けの情報は含めない）
+func publicBookingSummary(r model.Reservation, store model.Store, course model.Course) gin.H {
+    return gin.H{
+        "store":    store.Name,
+        "course":   course.Name,
+        "start_at": r.StartAt,
+        "end_at":   r.EndAt,
+        "status":   r.Status,
+    }
+}
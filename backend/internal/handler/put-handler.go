@@ -1,9 +1,15 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
 	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/permission"
 	"salon-app/backend/internal/utils"
 )
 
@@ -19,17 +25,27 @@ func UpdateStoreHandler(c *gin.Context) {
     id := c.Param("id") // URLの末尾（/api/v1/stores/5）からIDを取得
     var store model.Store
 
-    // 1. 指定されたIDのデータがDBにあるか確認
-    if err := db.DB.First(&store, id).Error; err != nil {
+    // 1. 指定されたIDのデータがDBにあるか確認（他店舗は見つからない扱い）
+    if err := db.DB.Scopes(middleware.StoreScopeColumn(c, "id")).First(&store, id).Error; err != nil {
         c.JSON(404, gin.H{"error": "店舗が見つかりません"})
         return
     }
 
     // 2. フロントから届いた新しい名前などを読み込む
-    if err := c.ShouldBindJSON(&store); err != nil {
+    // モデルに直接バインドするとIDまで書き換えられるため、リクエスト構造体を経由する
+    var req StoreUpdateRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(400, gin.H{"error": "入力データが正しくありません"})
         return
     }
+    store.Name = req.Name
+    store.Capacity = req.Capacity
+    if !applyStoreTaxSettings(c, &store, req.StoreTaxSettings) {
+        return
+    }
+    if !applyStoreRetentionSettings(c, &store, req.StoreRetentionSettings) {
+        return
+    }
 
     // 3. DBを更新する
     db.DB.Save(&store)
@@ -49,7 +65,7 @@ func UpdateStoreHandler(c *gin.Context) {
 func UpdateCustomerHandler(c *gin.Context) {
     id := c.Param("id")
     var customer model.Customer
-    if err := db.DB.First(&customer, id).Error; err != nil {
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&customer, id).Error; err != nil {
         c.JSON(404, gin.H{"error": "Customer not found"})
         return
     }
@@ -60,6 +76,15 @@ func UpdateCustomerHandler(c *gin.Context) {
         c.JSON(400, gin.H{"error": "Invalid input"})
         return
     }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    if !normalizeCustomerKana(c, &req.LastNameKana, &req.FirstNameKana) {
+        return
+    }
+    if !normalizeCustomerAddress(c, &req.ZipCode, req.PrefName) {
+        return
+    }
 
     customer.LastName = req.LastName
     customer.FirstName = req.FirstName
@@ -72,6 +97,8 @@ func UpdateCustomerHandler(c *gin.Context) {
     customer.Sex = req.Sex
     customer.BirthDate = req.BirthDate
     customer.Phone = req.Phone
+    customer.Email = req.Email
+    customer.IsProvisional = false // スタッフが内容を確認したので本登録扱いにする
     customer.StoreID = req.StoreID
 
     if err := db.DB.Save(&customer).Error; err != nil {
@@ -84,7 +111,8 @@ func UpdateCustomerHandler(c *gin.Context) {
 
 
 // @Summary      来店履歴更新
-// @Description  来店履歴更新
+// @Description  来店履歴更新。顧客・コース・チケットを変更すると、元のチケットの消化を戻して新しいチケットで消化し直します（同じトランザクションで行います）。
+// @Description  ticket_id を省略するとチケットは変更しません。都度払いに変えるには clear_ticket を true にします
 // @Tags         system
 // @Accept       json
 // @Produce      json
@@ -93,7 +121,7 @@ func UpdateCustomerHandler(c *gin.Context) {
 func UpdateVisitHandler(c *gin.Context) {
     id := c.Param("id")
     var visit model.Visit
-    if err := db.DB.First(&visit, id).Error; err != nil {
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&visit, id).Error; err != nil {
         c.JSON(404, gin.H{"error": "Visit not found"})
         return
     }
@@ -104,15 +132,84 @@ func UpdateVisitHandler(c *gin.Context) {
         c.JSON(400, gin.H{"error": "Invalid input"})
         return
     }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    if req.ClearTicket && req.TicketID != nil {
+        c.JSON(400, gin.H{"error": "ticket_id と clear_ticket は同時に指定できません"})
+        return
+    }
 
-    visit.CustomerID = req.CustomerID
-    visit.CourseID = req.CourseID
-    visit.TicketID = req.TicketID
-    visit.VisitCount = req.VisitCount
-    visit.StoreID = req.StoreID
-    visit.Memo = req.Memo
+    // 付け替え先の顧客・コースも同じ店舗のものに限る
+    var refs int64
+    if err := db.DB.Model(&model.Customer{}).Where("id = ? AND store_id = ?", req.CustomerID, req.StoreID).Count(&refs).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to update visit"})
+        return
+    }
+    if refs == 0 {
+        c.JSON(400, gin.H{"error": "顧客と来店店舗が一致しません"})
+        return
+    }
+    if err := db.DB.Model(&model.Course{}).Where("id = ? AND store_id = ?", req.CourseID, req.StoreID).Count(&refs).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to update visit"})
+        return
+    }
+    if refs == 0 {
+        c.JSON(400, gin.H{"error": "コースと来店店舗が一致しません"})
+        return
+    }
 
-    if err := db.DB.Save(&visit).Error; err != nil {
+    errVoided := errors.New("visit voided")
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&visit, visit.ID).Error; err != nil {
+            return err
+        }
+        if visit.VoidedAt != nil {
+            return errVoided
+        }
+        // ticket_id を省略した場合はチケットを変更しない（clear_ticket を指定したときだけ都度払いにする）
+        ticketID := visit.TicketID
+        if req.TicketID != nil {
+            ticketID = req.TicketID
+        } else if req.ClearTicket {
+            ticketID = nil
+        }
+        // 顧客・コース・チケット・店舗が変わる場合は、元のチケットの消化を戻して新しいチケットで消化し直す
+        moved := visit.CustomerID != req.CustomerID || visit.CourseID != req.CourseID ||
+            visit.StoreID != req.StoreID || !sameTicket(visit.TicketID, ticketID)
+        if moved {
+            if err := releaseTicket(tx, visit); err != nil {
+                return err
+            }
+        }
+        visit.CustomerID = req.CustomerID
+        visit.CourseID = req.CourseID
+        visit.TicketID = ticketID
+        visit.StoreID = req.StoreID
+        visit.Memo = req.Memo
+        if moved {
+            count, err := consumeTicket(tx, &visit)
+            if err != nil {
+                return err
+            }
+            visit.VisitCount = count
+            if err := placeMovedVisit(tx, &visit); err != nil {
+                return err
+            }
+        }
+        return tx.Omit(clause.Associations).Save(&visit).Error
+    })
+    if errors.Is(err, errVoided) {
+        c.JSON(409, gin.H{"error": "取り消された来店記録は変更できません"})
+        return
+    }
+    if status, msg, ok := ticketErrorResponse(err); ok {
+        c.JSON(status, gin.H{"error": msg})
+        return
+    }
+    if err != nil {
         c.Error(err)
         c.JSON(500, gin.H{"error": "Failed to update visit"})
         return
@@ -120,6 +217,14 @@ func UpdateVisitHandler(c *gin.Context) {
     c.JSON(200, visit)
 }
 
+// sameTicket は2つのチケットID（nil は都度払い）が同じかを返す
+func sameTicket(a, b *uint) bool {
+    if a == nil || b == nil {
+        return a == b
+    }
+    return *a == *b
+}
+
 
 // @Summary      スタッフ更新
 // @Description  スタッフ更新
@@ -131,7 +236,7 @@ func UpdateVisitHandler(c *gin.Context) {
 func UpdateUserHandler(c *gin.Context) {
     id := c.Param("id")
     var user model.User
-    if err := db.DB.First(&user, id).Error; err != nil {
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&user, id).Error; err != nil {
         c.JSON(404, gin.H{"error": "User not found"})
         return
     }
@@ -142,6 +247,24 @@ func UpdateUserHandler(c *gin.Context) {
         c.JSON(400, gin.H{"error": "Invalid input"})
         return
     }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+
+    // 現在のロール・変更後のロールとも、自分が付与できる範囲に限る
+    actorRole := middleware.CurrentRole(c)
+    if !permission.CanAssignRole(actorRole, user.Role) || !permission.CanAssignRole(actorRole, req.Role) {
+        middleware.Forbidden(c, permission.UserManage)
+        return
+    }
+    // 自分自身のロールは変更できない
+    if user.ID == middleware.CurrentUserID(c) && req.Role != user.Role {
+        middleware.Forbidden(c, permission.UserManage)
+        return
+    }
+
+    // パスワード・ロール・所属店舗が変わる場合は、古いトークンを使えないようにする
+    credentialsChanged := req.Password != "" || req.Role != user.Role || req.StoreID != user.StoreID
 
     user.Name = req.Name
     user.Email = req.Email
@@ -164,6 +287,13 @@ func UpdateUserHandler(c *gin.Context) {
         c.JSON(500, gin.H{"error": "Failed to update user"})
         return
     }
+    if credentialsChanged {
+        if err := revokeAllSessions(db.DB, user.ID); err != nil {
+            c.Error(err)
+            c.JSON(500, gin.H{"error": "Failed to revoke sessions"})
+            return
+        }
+    }
     c.JSON(200, user)
 }
 
@@ -177,7 +307,7 @@ func UpdateUserHandler(c *gin.Context) {
 func UpdateCourseHandler(c *gin.Context) {
     id := c.Param("id")
     var course model.Course
-    if err := db.DB.First(&course, id).Error; err != nil {
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&course, id).Error; err != nil {
         c.JSON(404, gin.H{"error": "Course not found"})
         return
     }
@@ -188,10 +318,18 @@ func UpdateCourseHandler(c *gin.Context) {
         c.JSON(400, gin.H{"error": "Invalid input"})
         return
     }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
 
     course.Name = req.Name
     course.Price = req.Price
     course.TotalCount = req.TotalCount
+    course.DurationMinutes = req.DurationMinutes
+    course.BufferMinutes = req.BufferMinutes
+    course.OnlineBookable = req.OnlineBookable
+    course.ValidityDays = req.ValidityDays
+    course.TaxCategory = defaultString(req.TaxCategory, model.TaxStandard)
     course.StoreID = req.StoreID
 
     if err := db.DB.Save(&course).Error; err != nil {
@@ -203,16 +341,17 @@ func UpdateCourseHandler(c *gin.Context) {
 }
 
 // @Summary      チケット更新
-// @Description  チケット更新
-// @Tags         system
+// @Description  チケットの規定回数を修正します。変更は理由コード付きで調整履歴（correction）に記録されます
+// @Tags         ticket
 // @Accept       json
 // @Produce      json
+// @Param        id   path      int  true  "Ticket ID"
+// @Param        body body TicketUpdateRequest true "修正内容"
 // @Success      200 {object} model.Ticket
-// @Router       /ticket/:id [put]
+// @Router       /ticket/{id} [put]
 func UpdateTicketHandler(c *gin.Context) {
-    id := c.Param("id")
     var ticket model.Ticket
-    if err := db.DB.First(&ticket, id).Error; err != nil {
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&ticket, c.Param("id")).Error; err != nil {
         c.JSON(404, gin.H{"error": "Ticket not found"})
         return
     }
@@ -224,12 +363,24 @@ func UpdateTicketHandler(c *gin.Context) {
         return
     }
 
-    // ticket.Name = req.Name
-    // ticket.Price = req.Price
-    ticket.TotalCount = req.TotalCount
-    ticket.StoreID = req.StoreID
-
-    if err := db.DB.Save(&ticket).Error; err != nil {
+    adj := model.TicketAdjustment{
+        Kind:       model.AdjustCorrection,
+        ReasonCode: req.ReasonCode,
+        Note:       req.Note,
+        UserID:     middleware.CurrentUserID(c),
+    }
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        return adjustTicket(tx, &ticket, &adj, func(t *model.Ticket, _ *model.TicketAdjustment) string {
+            t.TotalCount = req.TotalCount
+            return ""
+        })
+    })
+    var invalid errInvalidAdjustment
+    if errors.As(err, &invalid) {
+        c.JSON(400, gin.H{"error": invalid.msg})
+        return
+    }
+    if err != nil {
         c.Error(err)
         c.JSON(500, gin.H{"error": "Failed to update ticket"})
         return
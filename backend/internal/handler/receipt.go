@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+// Receipt は領収書（適格簡易請求書）の記載内容。
+// 記載事項: 発行事業者の氏名・名称と登録番号、取引年月日、取引内容（軽減税率の対象である旨）、
+// 税率ごとに区分した対価の額と適用税率、税率ごとの消費税額。
+type Receipt struct {
+	Title         string           `json:"title"`
+	SaleID        uint             `json:"sale_id"`
+	IssuerName    string           `json:"issuer_name"`
+	InvoiceNumber string           `json:"invoice_number"` // 未登録の店舗では空（その場合は適格請求書にならない）
+	Qualified     bool             `json:"qualified"`      // 適格請求書の要件を満たすか（登録番号があるか）
+	CustomerName  string           `json:"customer_name"`
+	SoldAt        time.Time        `json:"sold_at"`
+	TaxMode       string           `json:"tax_mode"`
+	Lines         []ReceiptLine    `json:"lines"`
+	Subtotal      int              `json:"subtotal"`
+	Discount      int              `json:"discount"`
+	Taxes         []ReceiptTax     `json:"taxes"`
+	TaxTotal      int              `json:"tax_total"`
+	Total         int              `json:"total"`
+	Payments      []ReceiptPayment `json:"payments"`
+	Voided        bool             `json:"voided"`
+	Notes         []string         `json:"notes"`
+}
+
+type ReceiptLine struct {
+	Description     string `json:"description"`
+	Quantity        int    `json:"quantity"`
+	UnitPrice       int    `json:"unit_price"`
+	Discount        int    `json:"discount"`
+	Amount          int    `json:"amount"`
+	TaxRate         int    `json:"tax_rate"`
+	Reduced         bool   `json:"reduced"`                    // 軽減税率の対象（品名の後に ※ を付ける）
+	TicketRemaining *int   `json:"ticket_remaining,omitempty"` // 会計時点のチケットの残り回数
+}
+
+type ReceiptTax struct {
+	Label   string `json:"label"` // 例: "10%対象"
+	Rate    int    `json:"rate"`
+	Taxable int    `json:"taxable"`
+	Tax     int    `json:"tax"`
+}
+
+type ReceiptPayment struct {
+	Method string `json:"method"`
+	Label  string `json:"label"`
+	Amount int    `json:"amount"`
+}
+
+var paymentLabels = map[string]string{
+	model.PaymentCash:  "現金",
+	model.PaymentCard:  "クレジットカード",
+	model.PaymentQR:    "QRコード決済",
+	model.PaymentOther: "その他",
+}
+
+// buildReceipt は売上から領収書の内容を作る。金額・税額・発行者名などは会計時に保存した値をそのまま使う
+// （古い売上でスナップショットがない項目だけ現在の店舗・顧客の情報で補う）。
+func buildReceipt(sale model.Sale, store model.Store) Receipt {
+	r := Receipt{
+		Title:         "領収書",
+		SaleID:        sale.ID,
+		IssuerName:    defaultString(sale.IssuerName, store.Name),
+		InvoiceNumber: defaultString(sale.InvoiceNumber, store.InvoiceNumber),
+		CustomerName:  sale.CustomerName,
+		SoldAt:        sale.SoldAt.In(utils.JST),
+		TaxMode:       sale.TaxMode,
+		Subtotal:      sale.Subtotal,
+		Discount:      sale.DiscountAmount,
+		TaxTotal:      sale.TaxTotal,
+		Total:         sale.Total,
+		Voided:        sale.Status == model.SaleVoided,
+	}
+	r.Qualified = r.InvoiceNumber != ""
+	if r.CustomerName == "" && sale.Customer != nil {
+		r.CustomerName = sale.Customer.LastName + " " + sale.Customer.FirstName
+	}
+	reduced := false
+	for _, l := range sale.Lines {
+		isReduced := l.TaxCategory == model.TaxReduced
+		reduced = reduced || isReduced
+		r.Lines = append(r.Lines, ReceiptLine{
+			Description:     l.Description,
+			Quantity:        l.Quantity,
+			UnitPrice:       l.UnitPrice,
+			Discount:        l.DiscountAmount,
+			Amount:          l.Amount,
+			TaxRate:         l.TaxRate,
+			Reduced:         isReduced,
+			TicketRemaining: l.TicketRemaining,
+		})
+	}
+	for _, t := range sale.Taxes {
+		r.Taxes = append(r.Taxes, ReceiptTax{Label: fmt.Sprintf("%d%%対象", t.Rate), Rate: t.Rate, Taxable: t.Taxable, Tax: t.Tax})
+	}
+	for _, p := range sale.Payments {
+		r.Payments = append(r.Payments, ReceiptPayment{Method: p.Method, Label: paymentLabels[p.Method], Amount: p.Amount})
+	}
+	if reduced {
+		r.Notes = append(r.Notes, "※は軽減税率対象")
+	}
+	if sale.TaxMode == model.TaxExclusive {
+		r.Notes = append(r.Notes, "価格は税抜表示です")
+	}
+	if r.Voided {
+		r.Notes = append(r.Notes, "この取引は取り消されています")
+	}
+	return r
+}
+
+// receiptWidth はレシートプリンター（58mm / 半角32文字）を想定した1行の幅
+const receiptWidth = 32
+
+// renderReceiptText は領収書をレシートプリンター向けのテキストにする
+func renderReceiptText(r Receipt) string {
+	var b strings.Builder
+	line := func(s string) { b.WriteString(s + "\n") }
+	rule := strings.Repeat("-", receiptWidth)
+
+	title := r.Title
+	if r.Voided {
+		title += "（取消）"
+	}
+	line(center(title))
+	line(center(r.IssuerName))
+	if r.InvoiceNumber != "" {
+		line(center("登録番号 " + r.InvoiceNumber))
+	}
+	line(r.SoldAt.Format("2006年01月02日 15:04") + fmt.Sprintf("  No.%d", r.SaleID))
+	if r.CustomerName != "" {
+		line(r.CustomerName + " 様")
+	}
+	line(rule)
+	for _, l := range r.Lines {
+		name := l.Description
+		if l.Reduced {
+			name += "※"
+		}
+		if l.Quantity != 1 {
+			line(name)
+			line(justify(fmt.Sprintf("  %s × %d", yen(l.UnitPrice), l.Quantity), yen(l.UnitPrice*l.Quantity)))
+		} else {
+			line(justify(name, yen(l.UnitPrice)))
+		}
+		if l.Discount > 0 {
+			line(justify("  値引", "-"+yen(l.Discount)))
+		}
+		if l.TicketRemaining != nil {
+			line(fmt.Sprintf("  (チケット残り %d 回)", *l.TicketRemaining))
+		}
+	}
+	line(rule)
+	line(justify("小計", yen(r.Subtotal)))
+	if r.Discount > 0 {
+		line(justify("値引", "-"+yen(r.Discount)))
+	}
+	for _, t := range r.Taxes {
+		if r.TaxMode == model.TaxExclusive {
+			line(justify(t.Label, yen(t.Taxable)))
+			line(justify("  消費税", yen(t.Tax)))
+		} else {
+			line(justify(t.Label, yen(t.Taxable)))
+			line(justify("  (内消費税", yen(t.Tax)+")"))
+		}
+	}
+	line(justify("合計", yen(r.Total)))
+	for _, p := range r.Payments {
+		line(justify(p.Label, yen(p.Amount)))
+	}
+	line(rule)
+	for _, n := range r.Notes {
+		line(n)
+	}
+	return b.String()
+}
+
+// yen は金額を "¥1,234" の形式にする
+func yen(n int) string {
+	sign := ""
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + "¥" + s
+}
+
+// displayWidth は等幅フォントでの表示幅（全角は2）を返す
+func displayWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		if r < 0x80 || r == '¥' || (r >= 0xFF61 && r <= 0xFF9F) { // ASCII・半角カナ
+			w++
+		} else {
+			w += 2
+		}
+	}
+	return w
+}
+
+// justify は left を左寄せ、right を右寄せにして1行にする
+func justify(left, right string) string {
+	pad := receiptWidth - displayWidth(left) - displayWidth(right)
+	if pad < 1 {
+		pad = 1
+	}
+	return left + strings.Repeat(" ", pad) + right
+}
+
+func center(s string) string {
+	pad := (receiptWidth - displayWidth(s)) / 2
+	if pad < 0 {
+		pad = 0
+	}
+	return strings.Repeat(" ", pad) + s
+}
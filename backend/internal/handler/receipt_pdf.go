@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-pdf/fpdf"
+	"salon-app/backend/internal/fonts"
+	"salon-app/backend/internal/model"
+)
+
+// 領収書・請求書のPDF（A5縦）。
+// 同じ売上からは常に同じバイト列になるよう、作成日時は会計日時に固定し、カタログの並びも固定する。
+const (
+	pdfFont   = "mplus"
+	pdfMargin = 12.0
+	pdfWidth  = 148.0 - 2*pdfMargin // A5 の本文幅 (mm)
+)
+
+// renderReceiptPDF は領収書（invoice が true なら請求書）のPDFを作る
+func renderReceiptPDF(r Receipt, invoice bool) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A5", "")
+	pdf.SetCreationDate(r.SoldAt)
+	pdf.SetModificationDate(r.SoldAt)
+	pdf.SetCatalogSort(true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", fonts.MPlus1pRegular)
+
+	title := r.Title
+	if invoice {
+		title = "請求書"
+	}
+	pdf.SetTitle(fmt.Sprintf("%s No.%d", title, r.SaleID), true)
+	pdf.SetAuthor(r.IssuerName, true)
+	pdf.AddPage()
+
+	// タイトル
+	pdf.SetFont(pdfFont, "", 18)
+	if r.Voided {
+		title += "（取消）"
+	}
+	pdf.CellFormat(pdfWidth, 12, title, "", 1, "C", false, 0, "")
+
+	// 宛名・日付
+	pdf.SetFont(pdfFont, "", 11)
+	customer := r.CustomerName
+	if customer == "" {
+		customer = "　　　　　　　　"
+	}
+	pdf.CellFormat(pdfWidth*0.6, 8, customer+" 様", "B", 0, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 9)
+	pdf.CellFormat(pdfWidth*0.4, 8, r.SoldAt.Format("2006年01月02日")+fmt.Sprintf("  No.%d", r.SaleID), "", 1, "R", false, 0, "")
+	pdf.Ln(3)
+
+	// 金額
+	pdf.SetFont(pdfFont, "", 16)
+	label := "ご請求金額"
+	if !invoice {
+		label = "金額"
+	}
+	pdf.CellFormat(pdfWidth*0.3, 11, label, "TB", 0, "C", false, 0, "")
+	pdf.CellFormat(pdfWidth*0.7, 11, yen(r.Total)+"-", "TB", 1, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "", 8)
+	taxNote := "（内消費税等 " + yen(r.TaxTotal) + "）"
+	if r.TaxMode == model.TaxExclusive {
+		taxNote = "（消費税等 " + yen(r.TaxTotal) + " を含みます）"
+	}
+	pdf.CellFormat(pdfWidth, 5, taxNote, "", 1, "R", false, 0, "")
+	if !invoice {
+		pdf.CellFormat(pdfWidth, 5, "上記正に領収いたしました", "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(3)
+
+	// 明細
+	cols := []float64{pdfWidth * 0.52, pdfWidth * 0.1, pdfWidth * 0.18, pdfWidth * 0.2}
+	pdf.SetFont(pdfFont, "", 8)
+	pdf.SetFillColor(235, 235, 235)
+	for i, h := range []string{"品名", "数量", "単価", "金額"} {
+		pdf.CellFormat(cols[i], 6, h, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	for _, l := range r.Lines {
+		name := l.Description
+		if l.Reduced {
+			name += " ※"
+		}
+		if l.TicketRemaining != nil {
+			name += fmt.Sprintf("（残り%d回）", *l.TicketRemaining)
+		}
+		pdf.CellFormat(cols[0], 6, name, "1", 0, "L", false, 0, "")
+		pdf.CellFormat(cols[1], 6, fmt.Sprint(l.Quantity), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(cols[2], 6, yen(l.UnitPrice), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(cols[3], 6, yen(l.UnitPrice*l.Quantity), "1", 1, "R", false, 0, "")
+		if l.Discount > 0 {
+			pdf.CellFormat(cols[0]+cols[1]+cols[2], 6, "　値引", "1", 0, "L", false, 0, "")
+			pdf.CellFormat(cols[3], 6, "-"+yen(l.Discount), "1", 1, "R", false, 0, "")
+		}
+	}
+
+	// 合計・税率ごとの内訳
+	pdf.Ln(2)
+	labelW, valueW := pdfWidth*0.8, pdfWidth*0.2
+	row := func(label, value string) {
+		pdf.CellFormat(labelW, 5.5, label, "", 0, "R", false, 0, "")
+		pdf.CellFormat(valueW, 5.5, value, "", 1, "R", false, 0, "")
+	}
+	row("小計", yen(r.Subtotal))
+	if r.Discount > 0 {
+		row("値引", "-"+yen(r.Discount))
+	}
+	for _, t := range r.Taxes {
+		if r.TaxMode == model.TaxExclusive {
+			row(fmt.Sprintf("%s（税抜）", t.Label), yen(t.Taxable))
+			row(fmt.Sprintf("消費税（%d%%）", t.Rate), yen(t.Tax))
+		} else {
+			row(fmt.Sprintf("%s（税込）", t.Label), yen(t.Taxable))
+			row(fmt.Sprintf("内消費税（%d%%）", t.Rate), yen(t.Tax))
+		}
+	}
+	pdf.SetFont(pdfFont, "", 10)
+	row("合計", yen(r.Total))
+	pdf.SetFont(pdfFont, "", 8)
+	if !invoice {
+		for _, p := range r.Payments {
+			row(p.Label, yen(p.Amount))
+		}
+	}
+
+	// 注記
+	pdf.Ln(2)
+	for _, n := range r.Notes {
+		pdf.CellFormat(pdfWidth, 5, n, "", 1, "L", false, 0, "")
+	}
+
+	// 発行者
+	pdf.Ln(4)
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(pdfWidth, 6, r.IssuerName, "", 1, "R", false, 0, "")
+	if r.InvoiceNumber != "" {
+		pdf.SetFont(pdfFont, "", 8)
+		pdf.CellFormat(pdfWidth, 5, "登録番号 "+r.InvoiceNumber, "", 1, "R", false, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+// receiptTestSale は税込価格の店舗で、標準税率のカット（チケット利用）と軽減税率の物販を値引きして会計した売上
+func receiptTestSale() model.Sale {
+	remaining := 2
+	sale := model.Sale{
+		SoldAt: time.Date(2026, 10, 18, 5, 5, 0, 0, time.UTC), Subtotal: 5300, DiscountAmount: 300, Total: 5000,
+		TaxMode: model.TaxInclusive, IssuerName: "サロン本店", InvoiceNumber: "T1234567890123", CustomerName: "山田 花子",
+		Lines: []model.SaleLine{
+			{Description: "カット", Quantity: 1, UnitPrice: 4000, Amount: 4000, TaxRate: 10, TaxCategory: model.TaxStandard, TicketRemaining: &remaining},
+			{Description: "ハーブティー", Quantity: 2, UnitPrice: 650, Amount: 1300, TaxRate: 8, TaxCategory: model.TaxReduced},
+		},
+		Payments: []model.SalePayment{{Method: model.PaymentCash, Amount: 5000}},
+		Status:   model.SaleCompleted,
+	}
+	sale.ID = 42
+	sale.Taxes, sale.TaxTotal = computeSaleTaxes(sale.Lines, sale.DiscountAmount, sale.TaxMode, model.RoundFloor)
+	return sale
+}
+
+func TestBuildReceipt(t *testing.T) {
+	sale := receiptTestSale()
+	store := model.Store{Name: "新しい店名", InvoiceNumber: "T9999999999999"}
+
+	r := buildReceipt(sale, store)
+	// 会計時のスナップショットを使い、今の店舗の情報では上書きしない
+	if r.IssuerName != "サロン本店" || r.InvoiceNumber != "T1234567890123" || !r.Qualified {
+		t.Errorf("issuer = %q, invoice = %q, qualified = %v, want the snapshot", r.IssuerName, r.InvoiceNumber, r.Qualified)
+	}
+	if !r.SoldAt.Equal(sale.SoldAt) || r.SoldAt.Location() != utils.JST {
+		t.Errorf("sold_at = %v, want %v in JST", r.SoldAt, sale.SoldAt)
+	}
+	wantTaxes := []ReceiptTax{{Label: "10%対象", Rate: 10, Taxable: 3773, Tax: 343}, {Label: "8%対象", Rate: 8, Taxable: 1227, Tax: 90}}
+	if !reflect.DeepEqual(r.Taxes, wantTaxes) || r.TaxTotal != 433 {
+		t.Errorf("taxes = %+v (total %d), want %+v (total 433)", r.Taxes, r.TaxTotal, wantTaxes)
+	}
+	if r.Lines[0].Reduced || !r.Lines[1].Reduced || !reflect.DeepEqual(r.Notes, []string{"※は軽減税率対象"}) {
+		t.Errorf("reduced = %v / %v, notes = %v, want only the second line reduced", r.Lines[0].Reduced, r.Lines[1].Reduced, r.Notes)
+	}
+	if r.Payments[0].Label != "現金" {
+		t.Errorf("payment label = %q, want 現金", r.Payments[0].Label)
+	}
+
+	// スナップショットのない古い売上は今の店舗・顧客の情報で補う。登録番号がなければ適格請求書にならない
+	old := receiptTestSale()
+	old.IssuerName, old.InvoiceNumber, old.CustomerName = "", "", ""
+	old.Customer = &model.Customer{LastName: "佐藤", FirstName: "一郎"}
+	old.TaxMode = model.TaxExclusive
+	old.Status = model.SaleVoided
+	r = buildReceipt(old, model.Store{Name: "新しい店名"})
+	if r.IssuerName != "新しい店名" || r.CustomerName != "佐藤 一郎" || r.Qualified {
+		t.Errorf("issuer = %q, customer = %q, qualified = %v, want the store and customer fallback, not qualified", r.IssuerName, r.CustomerName, r.Qualified)
+	}
+	wantNotes := []string{"※は軽減税率対象", "価格は税抜表示です", "この取引は取り消されています"}
+	if !r.Voided || !reflect.DeepEqual(r.Notes, wantNotes) {
+		t.Errorf("voided = %v, notes = %v, want voided with %v", r.Voided, r.Notes, wantNotes)
+	}
+}
+
+func TestRenderReceiptText(t *testing.T) {
+	want := strings.Join([]string{
+		"             領収書",
+		"           サロン本店",
+		"    登録番号 T1234567890123",
+		"2026年10月18日 14:05  No.42",
+		"山田 花子 様",
+		"--------------------------------",
+		"カット                    ¥4,000",
+		"  (チケット残り 2 回)",
+		"ハーブティー※",
+		"  ¥650 × 2               ¥1,300",
+		"--------------------------------",
+		"小計                      ¥5,300",
+		"値引                       -¥300",
+		"10%対象                   ¥3,773",
+		"  (内消費税                ¥343)",
+		"8%対象                    ¥1,227",
+		"  (内消費税                 ¥90)",
+		"合計                      ¥5,000",
+		"現金                      ¥5,000",
+		"--------------------------------",
+		"※は軽減税率対象",
+	}, "\n") + "\n"
+	if got := renderReceiptText(buildReceipt(receiptTestSale(), model.Store{})); got != want {
+		t.Errorf("renderReceiptText =\n%s\nwant\n%s", got, want)
+	}
+
+	// 税抜価格の店舗では外税の行になり、取消済みならタイトルに（取消）が付く
+	sale := receiptTestSale()
+	sale.TaxMode = model.TaxExclusive
+	sale.Status = model.SaleVoided
+	got := renderReceiptText(buildReceipt(sale, model.Store{}))
+	for _, s := range []string{"         領収書（取消）\n", "  消費税                    ¥343\n", "この取引は取り消されています\n"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("renderReceiptText missing %q in\n%s", s, got)
+		}
+	}
+}
+
+func TestYen(t *testing.T) {
+	tests := map[int]string{0: "¥0", 999: "¥999", 1000: "¥1,000", 1234567: "¥1,234,567", -300: "-¥300"}
+	for n, want := range tests {
+		if got := yen(n); got != want {
+			t.Errorf("yen(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
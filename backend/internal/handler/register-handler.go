@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
+	"salon-app/backend/internal/model"
+)
+
+var errRegisterClosed = errors.New("register session closed")
+
+// @Summary      レジ開局
+// @Description  店舗のレジを釣銭準備金を指定して開きます。1店舗で同時に開けるレジセッションは1つです
+// @Tags         register
+// @Accept       json
+// @Produce      json
+// @Param        body body RegisterOpenRequest true "開局内容"
+// @Success      200 {object} model.RegisterSession
+// @Router       /register-sessions [post]
+func OpenRegisterHandler(c *gin.Context) {
+    var req RegisterOpenRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+
+    session := model.RegisterSession{
+        StoreID:      req.StoreID,
+        Status:       model.RegisterOpen,
+        OpenedAt:     time.Now(),
+        OpenedByID:   middleware.CurrentUserID(c),
+        OpeningFloat: req.OpeningFloat,
+        Note:         req.Note,
+    }
+    errAlreadyOpen := errors.New("register already open")
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        // 同じ店舗で同時に開局しないよう店舗の行をロックする
+        var store model.Store
+        if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&store, req.StoreID).Error; err != nil {
+            return err
+        }
+        var count int64
+        if err := tx.Model(&model.RegisterSession{}).Where("store_id = ? AND status = ?", req.StoreID, model.RegisterOpen).
+            Count(&count).Error; err != nil {
+            return err
+        }
+        if count > 0 {
+            return errAlreadyOpen
+        }
+        return tx.Create(&session).Error
+    })
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(400, gin.H{"error": "店舗が見つかりません"})
+        return
+    }
+    if errors.Is(err, errAlreadyOpen) {
+        c.JSON(409, gin.H{"error": "この店舗のレジはすでに開いています（先にレジ締めをしてください）"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to open register"})
+        return
+    }
+    c.JSON(200, session)
+}
+
+// registerSessionListSpec はレジセッション一覧の並び替え・絞り込み
+var registerSessionListSpec = listSpec{
+    Sorts:       map[string]string{"opened_at": "register_sessions.opened_at"},
+    DefaultSort: "-opened_at",
+    Filters: []listFilter{
+        {Param: "store_id", Column: "register_sessions.store_id", Kind: filterID},
+        {Param: "status", Column: "register_sessions.status", Kind: filterEnum, Values: []string{model.RegisterOpen, model.RegisterClosed}},
+        {Param: "from", Column: "register_sessions.opened_at", Kind: filterSince},
+        {Param: "to", Column: "register_sessions.opened_at", Kind: filterUntil},
+    },
+}
+
+// @Summary      レジセッション一覧
+// @Description  レジセッション（締めレポート）の一覧を新しい順に返します。from / to は開局日で絞り込みます
+// @Tags         register
+// @Accept       json
+// @Produce      json
+// @Param        store_id  query  int     false  "店舗ID"
+// @Param        status    query  string  false  "open / closed"
+// @Param        from      query  string  false  "開始日 YYYY-MM-DD"
+// @Param        to        query  string  false  "終了日 YYYY-MM-DD（この日を含む）"
+// @Param        sort      query  string  false  "opened_at（既定は -opened_at）。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {array} model.RegisterSession
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
+// @Router       /register-sessions [get]
+func GetRegisterSessionListHandler(c *gin.Context) {
+    var sessions []model.RegisterSession
+    if !paginate(c, db.DB.Scopes(middleware.StoreScope(c)), registerSessionListSpec, &sessions) {
+        return
+    }
+    c.JSON(200, sessions)
+}
+
+// @Summary      レジセッション詳細（締めレポート）
+// @Description  締めたセッションは締めの時点で確定した集計を返します。開いているセッションは現時点までの集計を計算して返します（保存はしません）
+// @Tags         register
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Register session ID"
+// @Success      200 {object} model.RegisterSession
+// @Router       /register-sessions/{id} [get]
+func GetRegisterSessionHandler(c *gin.Context) {
+    var session model.RegisterSession
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&session, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Register session not found"})
+        return
+    }
+    var err error
+    if session.Status == model.RegisterOpen {
+        err = computeRegisterTotals(db.DB, &session, time.Now())
+    } else {
+        err = db.DB.Where("session_id = ?", session.ID).Order("created_at, id").Find(&session.Movements).Error
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to compute register totals"})
+        return
+    }
+    c.JSON(200, session)
+}
+
+// @Summary      レジ入出金
+// @Description  売上以外のレジの現金の出し入れ（両替用の補充・小口の支払など）を記録します。締めたセッションには記録できません
+// @Tags         register
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Register session ID"
+// @Param        body body RegisterMovementRequest true "入出金内容"
+// @Success      200 {object} model.RegisterMovement
+// @Router       /register-sessions/{id}/movements [post]
+func AddRegisterMovementHandler(c *gin.Context) {
+    var req RegisterMovementRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+
+    movement := model.RegisterMovement{
+        Kind:   req.Kind,
+        Amount: req.Amount,
+        Reason: req.Reason,
+        UserID: middleware.CurrentUserID(c),
+    }
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        var session model.RegisterSession
+        if err := tx.Scopes(middleware.StoreScope(c)).Clauses(clause.Locking{Strength: "UPDATE"}).
+            First(&session, c.Param("id")).Error; err != nil {
+            return err
+        }
+        if session.Status != model.RegisterOpen {
+            return errRegisterClosed
+        }
+        movement.SessionID = session.ID
+        return tx.Create(&movement).Error
+    })
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(404, gin.H{"error": "Register session not found"})
+        return
+    }
+    if errors.Is(err, errRegisterClosed) {
+        c.JSON(409, gin.H{"error": "締めたレジセッションは変更できません"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to record register movement"})
+        return
+    }
+    c.JSON(200, movement)
+}
+
+// @Summary      レジ締め
+// @Description  数えた現金を入力してレジを締めます。期間中の現金の売上（会計を通さずに登録した回数券を含む）・取消・返金と入出金から理論上の現金残高を計算し、過不足とともに保存します。
+// @Description  締めた後はセッションを変更できません
+// @Tags         register
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Register session ID"
+// @Param        body body RegisterCloseRequest true "締めの内容"
+// @Success      200 {object} model.RegisterSession
+// @Router       /register-sessions/{id}/close [post]
+func CloseRegisterHandler(c *gin.Context) {
+    var req RegisterCloseRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+
+    var session model.RegisterSession
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Scopes(middleware.StoreScope(c)).Clauses(clause.Locking{Strength: "UPDATE"}).
+            First(&session, c.Param("id")).Error; err != nil {
+            return err
+        }
+        if session.Status != model.RegisterOpen {
+            return errRegisterClosed
+        }
+        now := time.Now()
+        session.CountedCash = req.CountedCash
+        if err := computeRegisterTotals(tx, &session, now); err != nil {
+            return err
+        }
+        session.Status = model.RegisterClosed
+        session.ClosedAt = &now
+        if userID := middleware.CurrentUserID(c); userID != 0 {
+            session.ClosedByID = &userID
+        }
+        if req.Note != "" {
+            session.Note = req.Note
+        }
+        return tx.Omit(clause.Associations).Save(&session).Error
+    })
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(404, gin.H{"error": "Register session not found"})
+        return
+    }
+    if errors.Is(err, errRegisterClosed) {
+        c.JSON(409, gin.H{"error": "このレジセッションはすでに締めています"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to close register"})
+        return
+    }
+    c.JSON(200, session)
+}
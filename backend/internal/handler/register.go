@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"salon-app/backend/internal/model"
+)
+
+// computeRegisterTotals はレジセッションの開局から until までの現金の動きを集計し、理論上の現金残高を計算する。
+// 売上は登録した日時（created_at）で数える。会計日時（sold_at）を過去にした売上も、現金を受け取ったのは登録した時点のため。
+// 取り消した売上は、元の売上がどのセッションのものでも取り消した時点で現金を返したものとして差し引く。
+// 会計を通さずに現金で登録した回数券（POST /tickets）も、登録した時点の現金の入金として数える。
+func computeRegisterTotals(tx *gorm.DB, s *model.RegisterSession, until time.Time) error {
+	if err := tx.Model(&model.SalePayment{}).
+		Joins("JOIN sales ON sales.id = sale_payments.sale_id AND sales.deleted_at IS NULL").
+		Where("sales.store_id = ? AND sale_payments.method = ?", s.StoreID, model.PaymentCash).
+		Where("sales.created_at >= ? AND sales.created_at < ?", s.OpenedAt, until).
+		Select("COALESCE(SUM(sale_payments.amount), 0)").Scan(&s.CashSales).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.SalePayment{}).
+		Joins("JOIN sales ON sales.id = sale_payments.sale_id AND sales.deleted_at IS NULL").
+		Where("sales.store_id = ? AND sale_payments.method = ? AND sales.status = ?", s.StoreID, model.PaymentCash, model.SaleVoided).
+		Where("sales.voided_at >= ? AND sales.voided_at < ?", s.OpenedAt, until).
+		Select("COALESCE(SUM(sale_payments.amount), 0)").Scan(&s.CashVoids).Error; err != nil {
+		return err
+	}
+	// 会計を通さずに登録した回数券の現金の支払。会計の明細にあるチケット（会計で発行した、または後から会計で代金を受け取った）は
+	// 会計の支払で数えているので除く。譲渡で作られたチケットは現金の受け取りがないので除き、
+	// 譲渡元のチケットは譲渡で減った支払額を足し戻して購入時の額で数える
+	if err := tx.Model(&model.Ticket{}).
+		Where("store_id = ? AND payment_method = ? AND transferred_from_id IS NULL", s.StoreID, model.PaymentCash).
+		Where("created_at >= ? AND created_at < ?", s.OpenedAt, until).
+		Where("NOT EXISTS (SELECT 1 FROM sale_lines WHERE sale_lines.ticket_id = tickets.id AND sale_lines.deleted_at IS NULL)").
+		Select("COALESCE(SUM(price_paid + (SELECT COALESCE(SUM(amount), 0) FROM ticket_transfers " +
+			"WHERE ticket_transfers.from_ticket_id = tickets.id AND ticket_transfers.deleted_at IS NULL)), 0)").
+		Scan(&s.CashTicketSales).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.TicketAdjustment{}).
+		Where("store_id = ? AND kind = ? AND refund_method = ?", s.StoreID, model.AdjustRefund, model.PaymentCash).
+		Where("created_at >= ? AND created_at < ?", s.OpenedAt, until).
+		Select("COALESCE(SUM(refund_amount), 0)").Scan(&s.CashRefunds).Error; err != nil {
+		return err
+	}
+	var movements []model.RegisterMovement
+	if err := tx.Where("session_id = ?", s.ID).Order("created_at, id").Find(&movements).Error; err != nil {
+		return err
+	}
+	s.Movements = movements
+	s.CashIn, s.CashOut = 0, 0
+	for _, m := range movements {
+		if m.Kind == model.RegisterCashIn {
+			s.CashIn += m.Amount
+		} else {
+			s.CashOut += m.Amount
+		}
+	}
+	s.ExpectedCash = s.OpeningFloat + s.CashSales + s.CashTicketSales - s.CashVoids - s.CashRefunds + s.CashIn - s.CashOut
+	if s.CountedCash != nil {
+		s.Variance = *s.CountedCash - s.ExpectedCash
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// TestComputeRegisterTotals は会計の現金、会計を通さない回数券の現金、入出金からレジの理論残高を計算し、
+// 譲渡で作られたチケットを現金の入金として数えず、譲渡元は購入時の支払額で数えることを確認する。
+func TestComputeRegisterTotals(t *testing.T) {
+	openTestDB(t)
+
+	now := time.Now()
+	sale, saleTicket := seedTicketSale(t) // 会計で現金 50,000 円の5回券を販売
+	var course model.Course
+	mustExec(t, db.DB.First(&course, saleTicket.CourseID))
+
+	// 会計を通さず現金で登録した回数券（30,000 円）から2回分（12,000 円）を譲渡する
+	direct := newTicketFromCourse(saleTicket.CustomerID, course, now, model.PaymentCash, 0)
+	direct.PricePaid = 30000
+	mustExec(t, db.DB.Create(&direct))
+	transferred := newTicketFromCourse(saleTicket.CustomerID, course, now, model.PaymentCash, 0)
+	transferred.TotalCount, transferred.PricePaid, transferred.TransferredFromID = 2, 12000, &direct.ID
+	mustExec(t, db.DB.Create(&transferred))
+	mustExec(t, db.DB.Model(&direct).Updates(map[string]interface{}{"total_count": 3, "price_paid": 18000}))
+	transfer := model.TicketTransfer{FromTicketID: direct.ID, ToTicketID: transferred.ID, StoreID: sale.StoreID,
+		FromCustomerID: direct.CustomerID, ToCustomerID: transferred.CustomerID, Sessions: 2, Amount: 12000, ReasonCode: model.ReasonOther}
+	mustExec(t, db.DB.Create(&transfer))
+	// カードで登録した回数券は現金に含めない
+	card := newTicketFromCourse(saleTicket.CustomerID, course, now, model.PaymentCard, 0)
+	mustExec(t, db.DB.Create(&card))
+
+	session := model.RegisterSession{StoreID: sale.StoreID, OpenedAt: now.Add(-time.Hour), OpeningFloat: 10000}
+	mustExec(t, db.DB.Create(&session))
+	movements := []model.RegisterMovement{
+		{SessionID: session.ID, Kind: model.RegisterCashIn, Amount: 5000, Reason: "両替"},
+		{SessionID: session.ID, Kind: model.RegisterCashOut, Amount: 2000, Reason: "備品"},
+	}
+	mustExec(t, db.DB.Create(&movements))
+	t.Cleanup(func() {
+		db.DB.Unscoped().Where("session_id = ?", session.ID).Delete(&model.RegisterMovement{})
+		db.DB.Unscoped().Delete(&session)
+		db.DB.Unscoped().Delete(&transfer)
+		db.DB.Unscoped().Delete(&[]model.Ticket{direct, transferred, card})
+	})
+
+	if err := computeRegisterTotals(db.DB, &session, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	want := model.RegisterSession{CashSales: 50000, CashTicketSales: 30000, CashIn: 5000, CashOut: 2000,
+		ExpectedCash: 10000 + 50000 + 30000 + 5000 - 2000}
+	if session.CashSales != want.CashSales || session.CashTicketSales != want.CashTicketSales ||
+		session.CashVoids != 0 || session.CashRefunds != 0 ||
+		session.CashIn != want.CashIn || session.CashOut != want.CashOut || session.ExpectedCash != want.ExpectedCash {
+		t.Errorf("totals = sales %d, tickets %d, voids %d, refunds %d, in %d, out %d, expected %d; want sales %d, tickets %d, in %d, out %d, expected %d",
+			session.CashSales, session.CashTicketSales, session.CashVoids, session.CashRefunds, session.CashIn, session.CashOut, session.ExpectedCash,
+			want.CashSales, want.CashTicketSales, want.CashIn, want.CashOut, want.ExpectedCash)
+	}
+}
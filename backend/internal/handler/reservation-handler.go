@@ -0,0 +1,405 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+// reservationListSpec は予約一覧の並び替え・絞り込み（from / to は日時も指定できるので GetReservationListHandler で扱う）
+var reservationListSpec = listSpec{
+    Sorts:       map[string]string{"start_at": "reservations.start_at", "created_at": "reservations.created_at", "id": "reservations.id"},
+    DefaultSort: "start_at",
+    Filters: []listFilter{
+        {Param: "staff_id", Column: "reservations.staff_id", Kind: filterID},
+        {Param: "customer_id", Column: "reservations.customer_id", Kind: filterID},
+        {Param: "store_id", Column: "reservations.store_id", Kind: filterID},
+        {Param: "status", Column: "reservations.status", Kind: filterEnum, Values: []string{
+            model.ReservationBooked, model.ReservationConfirmed, model.ReservationArrived,
+            model.ReservationCompleted, model.ReservationCancelled, model.ReservationNoShow,
+        }},
+    },
+}
+
+// @Summary      予約一覧
+// @Description  予約一覧取得。from / to（RFC3339 または YYYY-MM-DD）、staff_id、customer_id、status で絞り込めます
+// @Tags         reservation
+// @Accept       json
+// @Produce      json
+// @Param        from         query  string  false  "開始日時(以降)"
+// @Param        to           query  string  false  "終了日時(より前)"
+// @Param        staff_id     query  int     false  "担当スタッフID"
+// @Param        customer_id  query  int     false  "顧客ID"
+// @Param        store_id     query  int     false  "店舗ID"
+// @Param        status       query  string  false  "ステータス"
+// @Param        sort         query  string  false  "start_at（既定）/ created_at / id。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {array} model.Reservation
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
+// @Router       /reservation [get]
+func GetReservationListHandler(c *gin.Context) {
+    query := db.DB.Scopes(middleware.StoreScope(c))
+    if from := c.Query("from"); from != "" {
+        t, err := utils.ParseTimeParam(from)
+        if err != nil {
+            c.JSON(400, gin.H{"error": "from の形式が正しくありません"})
+            return
+        }
+        query = query.Where("start_at >= ?", t)
+    }
+    if to := c.Query("to"); to != "" {
+        t, err := utils.ParseTimeParam(to)
+        if err != nil {
+            c.JSON(400, gin.H{"error": "to の形式が正しくありません"})
+            return
+        }
+        query = query.Where("start_at < ?", t)
+    }
+
+    var reservations []model.Reservation
+    if !paginate(c, query, reservationListSpec, &reservations, "Customer", "Course", "Staff") {
+        return
+    }
+    c.JSON(200, reservations)
+}
+
+// @Summary      予約詳細
+// @Description  予約詳細取得
+// @Tags         reservation
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Reservation ID"
+// @Success      200 {object} model.Reservation
+// @Router       /reservation/{id} [get]
+func GetReservationHandler(c *gin.Context) {
+    var reservation model.Reservation
+    if err := db.DB.Scopes(middleware.StoreScope(c)).Preload("Customer").Preload("Course").Preload("Staff").Preload("Visit").
+        First(&reservation, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Reservation not found"})
+        return
+    }
+    c.JSON(200, reservation)
+}
+
+// @Summary      予約登録
+// @Description  予約を登録します。担当スタッフの重複・店舗の席数超過がある場合は 409 を返します
+// @Tags         reservation
+// @Accept       json
+// @Produce      json
+// @Param        body body ReservationRequest true "予約内容"
+// @Success      200 {object} model.Reservation
+// @Router       /reservation [post]
+func CreateReservationHandler(c *gin.Context) {
+    var req ReservationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    course, msg, err := validateReservationRefs(req)
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to register reservation"})
+        return
+    }
+    if msg != "" {
+        c.JSON(400, gin.H{"error": msg})
+        return
+    }
+    endAt, ok := reservationEndAt(req, course)
+    if !ok {
+        c.JSON(400, gin.H{"error": "コースに施術時間が設定されていないため、終了時刻を指定してください"})
+        return
+    }
+
+    reservation := model.Reservation{
+        CustomerID:    req.CustomerID,
+        CourseID:      req.CourseID,
+        StaffID:       req.StaffID,
+        StoreID:       req.StoreID,
+        StartAt:       req.StartAt,
+        EndAt:         endAt,
+        BufferMinutes: course.BufferMinutes,
+        Status:        model.ReservationBooked,
+        Memo:          req.Memo,
+    }
+    conflict, err := saveReservation(&reservation)
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to register reservation"})
+        return
+    }
+    if conflict != "" {
+        c.JSON(409, gin.H{"error": conflict})
+        return
+    }
+    c.JSON(200, reservation)
+}
+
+// @Summary      予約変更
+// @Description  予約の日時・担当・コース・メモを変更します（受付中・確定済みの予約のみ）
+// @Tags         reservation
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Reservation ID"
+// @Param        body body ReservationRequest true "予約内容"
+// @Success      200 {object} model.Reservation
+// @Router       /reservation/{id} [put]
+func UpdateReservationHandler(c *gin.Context) {
+    var reservation model.Reservation
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&reservation, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Reservation not found"})
+        return
+    }
+
+    var req ReservationRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "Invalid input"})
+        return
+    }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+    course, msg, err := validateReservationRefs(req)
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to update reservation"})
+        return
+    }
+    if msg != "" {
+        c.JSON(400, gin.H{"error": msg})
+        return
+    }
+    endAt, ok := reservationEndAt(req, course)
+    if !ok {
+        c.JSON(400, gin.H{"error": "コースに施術時間が設定されていないため、終了時刻を指定してください"})
+        return
+    }
+
+    reservation.CustomerID = req.CustomerID
+    reservation.CourseID = req.CourseID
+    reservation.StaffID = req.StaffID
+    reservation.StoreID = req.StoreID
+    reservation.StartAt = req.StartAt
+    reservation.EndAt = endAt
+    reservation.BufferMinutes = course.BufferMinutes
+    reservation.Memo = req.Memo
+
+    conflict, err := saveReservation(&reservation)
+    if errors.Is(err, errReservationNotEditable) {
+        c.JSON(409, gin.H{"error": "この予約は変更できません"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to update reservation"})
+        return
+    }
+    if conflict != "" {
+        c.JSON(409, gin.H{"error": conflict})
+        return
+    }
+    c.JSON(200, reservation)
+}
+
+// @Summary      予約ステータス変更
+// @Description  予約のステータスを変更します。completed にすると来店記録を作成し、ticket_id で指定した回数券を消化します（null なら都度払い）
+// @Tags         reservation
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Reservation ID"
+// @Param        body body ReservationStatusRequest true "変更後のステータス"
+// @Success      200 {object} model.Reservation
+// @Router       /reservation/{id}/status [put]
+func UpdateReservationStatusHandler(c *gin.Context) {
+    var req ReservationStatusRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "Invalid input"})
+        return
+    }
+
+    var reservation model.Reservation
+    errInvalidTransition := errors.New("invalid transition")
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Scopes(middleware.StoreScope(c)).Clauses(clause.Locking{Strength: "UPDATE"}).
+            First(&reservation, c.Param("id")).Error; err != nil {
+            return err
+        }
+        if !reservation.CanTransitionTo(req.Status) {
+            return errInvalidTransition
+        }
+
+        switch req.Status {
+        case model.ReservationCompleted:
+            // 来店記録を作成してチケットを消化する（来店登録と同じロジック）
+            staffID := reservation.StaffID
+            visit := model.Visit{
+                CustomerID: reservation.CustomerID,
+                CourseID:   reservation.CourseID,
+                TicketID:   req.TicketID,
+                StoreID:    reservation.StoreID,
+                StaffID:    &staffID,
+                Memo:       req.Memo,
+            }
+            if err := registerVisit(tx, &visit); err != nil {
+                return err
+            }
+            reservation.VisitID = &visit.ID
+        case model.ReservationCancelled, model.ReservationNoShow:
+            now := time.Now()
+            reservation.CancelledAt = &now
+        }
+        reservation.Status = req.Status
+        return tx.Omit(clause.Associations).Save(&reservation).Error
+    })
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(404, gin.H{"error": "Reservation not found"})
+        return
+    }
+    if errors.Is(err, errInvalidTransition) {
+        c.JSON(409, gin.H{"error": "現在のステータス(" + reservation.Status + ")からは変更できません"})
+        return
+    }
+    if status, msg, ok := ticketErrorResponse(err); ok {
+        c.JSON(status, gin.H{"error": msg})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to update reservation status"})
+        return
+    }
+    c.JSON(200, reservation)
+}
+
+// validateReservationRefs は顧客・コース・担当スタッフが予約店舗のものか確認し、問題があればメッセージを返す
+// （DBエラーなら err を返す）
+func validateReservationRefs(req ReservationRequest) (model.Course, string, error) {
+    var course model.Course
+    var n int64
+    if err := db.DB.Model(&model.Customer{}).Where("id = ? AND store_id = ?", req.CustomerID, req.StoreID).Count(&n).Error; err != nil {
+        return course, "", err
+    }
+    if n == 0 {
+        return course, "顧客と予約店舗が一致しません", nil
+    }
+    if err := db.DB.Where("store_id = ?", req.StoreID).First(&course, req.CourseID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return course, "コースと予約店舗が一致しません", nil
+        }
+        return course, "", err
+    }
+    ok, err := staffBelongsToStore(db.DB, req.StaffID, req.StoreID)
+    if err != nil {
+        return course, "", err
+    }
+    if !ok {
+        return course, "担当スタッフと予約店舗が一致しません", nil
+    }
+    return course, "", nil
+}
+
+// reservationEndAt は予約の終了時刻を決める。未指定ならコースの施術時間から計算する。
+func reservationEndAt(req ReservationRequest, course model.Course) (time.Time, bool) {
+    if !req.EndAt.IsZero() {
+        return req.EndAt, true
+    }
+    if course.DurationMinutes <= 0 {
+        return time.Time{}, false
+    }
+    return req.StartAt.Add(time.Duration(course.DurationMinutes) * time.Minute), true
+}
+
+var errReservationNotEditable = errors.New("reservation not editable")
+
+// saveReservation は重複チェックをして予約を保存する。重複があれば保存せずに理由を返す。
+// 既存の予約は行ロックを取ってから受付中・確定済みのままかを確認し、そうでなければ errReservationNotEditable を返す。
+func saveReservation(reservation *model.Reservation) (string, error) {
+    var conflict string
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        store, err := lockStore(tx, reservation.StoreID)
+        if err != nil {
+            return err
+        }
+        if reservation.ID != 0 {
+            var current model.Reservation
+            if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, reservation.ID).Error; err != nil {
+                return err
+            }
+            if current.Status != model.ReservationBooked && current.Status != model.ReservationConfirmed {
+                return errReservationNotEditable
+            }
+            // 読み込んだ後に受付中 → 確定済みに変わっていても戻さない
+            reservation.Status = current.Status
+        }
+        if conflict, err = reservationConflict(tx, store, *reservation); err != nil || conflict != "" {
+            return err
+        }
+        return tx.Omit(clause.Associations).Save(reservation).Error
+    })
+    return conflict, err
+}
+
+// @Summary      空き枠検索
+// @Description  コース・店舗・期間・担当スタッフ（任意）を指定して、予約可能な開始時刻を返します。admin は store_id の指定が必要です
+// @Tags         reservation
+// @Accept       json
+// @Produce      json
+// @Param        course_id  query  int     true   "コースID"
+// @Param        store_id   query  int     false  "店舗ID（admin のみ）"
+// @Param        from       query  string  false  "開始日 YYYY-MM-DD（省略時は今日）"
+// @Param        to         query  string  false  "終了日 YYYY-MM-DD（省略時は開始日から1週間、最大31日）"
+// @Param        staff_id   query  int     false  "担当スタッフID"
+// @Success      200 {object} Slot
+// @Router       /availability [get]
+func GetAvailabilityHandler(c *gin.Context) {
+    storeID := middleware.TenantStoreID(c)
+    if storeID == 0 {
+        c.JSON(400, gin.H{"error": "store_id を指定してください"})
+        return
+    }
+    from, to, ok := parseDateRange(c.Query("from"), c.Query("to"))
+    if !ok {
+        c.JSON(400, gin.H{"error": "期間の指定が正しくありません（YYYY-MM-DD、最大31日）"})
+        return
+    }
+    staffID, _ := strconv.ParseUint(c.Query("staff_id"), 10, 64)
+
+    var store model.Store
+    if err := db.DB.First(&store, storeID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "店舗が見つかりません"})
+        return
+    }
+    var course model.Course
+    if err := db.DB.Where("store_id = ?", storeID).First(&course, c.Query("course_id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "コースが見つかりません"})
+        return
+    }
+    if course.DurationMinutes <= 0 {
+        c.JSON(400, gin.H{"error": "コースに施術時間が設定されていません"})
+        return
+    }
+
+    slots, err := findAvailableSlots(db.DB, store, course, from, to, uint(staffID), 0)
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to search availability"})
+        return
+    }
+    c.JSON(200, slots)
+}
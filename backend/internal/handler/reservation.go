@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/model"
+)
+
+// staffBelongsToStore は担当スタッフがその店舗の所属かを返す
+func staffBelongsToStore(tx *gorm.DB, staffID, storeID uint) (bool, error) {
+	var n int64
+	err := tx.Model(&model.User{}).Where("id = ? AND store_id = ?", staffID, storeID).Count(&n).Error
+	return n > 0, err
+}
+
+// lockStore は店舗の行をロックする。予約の重複チェックと登録の間に、別の予約が割り込まないようにするため。
+func lockStore(tx *gorm.DB, storeID uint) (model.Store, error) {
+	var store model.Store
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&store, storeID).Error
+	return store, err
+}
+
+// activeOverlaps は [start, end) と重なる、枠を占有中の予約を返す（exceptID の予約は除く）。
+// 各予約は施術後の準備時間（BufferMinutes）まで枠を占有しているものとして扱う。
+func activeOverlaps(tx *gorm.DB, storeID uint, start, end time.Time, exceptID uint) ([]model.Reservation, error) {
+	var rs []model.Reservation
+	err := tx.Where("store_id = ? AND status IN ? AND start_at < ? AND end_at + buffer_minutes * interval '1 minute' > ? AND id <> ?",
+		storeID, model.ReservationActiveStatuses, end, start, exceptID).Find(&rs).Error
+	return rs, err
+}
+
+// reservationConflict は予約 r が他の予約と重複していないか確認し、重複していれば理由を返す（問題なければ空文字）。
+// 担当スタッフの二重予約と、店舗の席数（同時施術数）の超過をチェックする。
+// 呼び出し側で lockStore しておくこと。
+func reservationConflict(tx *gorm.DB, store model.Store, r model.Reservation) (string, error) {
+	overlaps, err := activeOverlaps(tx, r.StoreID, r.StartAt, r.BlockedUntil(), r.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, o := range overlaps {
+		if o.StaffID == r.StaffID {
+			return "担当スタッフのこの時間帯にはすでに予約があります", nil
+		}
+	}
+	if store.Capacity > 0 && maxConcurrent(overlaps)+1 > store.Capacity {
+		return "この時間帯は満席です", nil
+	}
+	return "", nil
+}
+
+// maxConcurrent は予約の集合のうち、同時に重なっている件数の最大値を返す
+func maxConcurrent(rs []model.Reservation) int {
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	events := make([]event, 0, len(rs)*2)
+	for _, r := range rs {
+		events = append(events, event{r.StartAt, 1}, event{r.BlockedUntil(), -1})
+	}
+	// 同時刻なら終了を先に処理する（10:00終了と10:00開始は重ならない）
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+	cur, peak := 0, 0
+	for _, e := range events {
+		cur += e.delta
+		if cur > peak {
+			peak = cur
+		}
+	}
+	return peak
+}
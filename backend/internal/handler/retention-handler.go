@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// RetentionRow は顧客ごとの RFM とセグメント（計算方法は retentionQuery を参照）
+type RetentionRow struct {
+	CustomerID   uint      `json:"customer_id"`
+	StoreID      uint      `json:"store_id"`
+	LastName     string    `json:"last_name"`
+	FirstName    string    `json:"first_name"`
+	Phone        string    `json:"phone"`
+	LastVisitAt  time.Time `json:"last_visit_at"`
+	RecencyDays  int       `json:"recency_days"`
+	Frequency    int       `json:"frequency"`
+	Monetary     int       `json:"monetary"`
+	RScore       int       `json:"r_score"`
+	FScore       int       `json:"f_score"`
+	MScore       int       `json:"m_score"`
+	IntervalDays *int      `json:"interval_days"` // いつもの来店間隔（日）。来店1回なら null
+	Overdue      bool      `json:"overdue"`       // いつもの間隔を過ぎても来店がない
+	Segment      string    `json:"segment"`       // Segment* 定数のいずれか
+}
+
+// @Summary      顧客の離反分析（RFM）
+// @Description  来店履歴から顧客ごとの R（最終来店からの日数）・F（来店回数）・M（会計の合計）と店舗内の5段階スコアを計算し、
+// @Description  店舗のしきい値で loyal / active / new / at_risk / lapsed に分けます。segment で絞り込めます
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        store_id  query  int     false  "店舗ID"
+// @Param        segment   query  string  false  "loyal / active / new / at_risk / lapsed"
+// @Success      200 {object} map[string]interface{}
+// @Router       /analytics/rfm [get]
+func GetRFMAnalyticsHandler(c *gin.Context) {
+    r := analyticsRange{StoreID: c.Query("store_id")}
+    query := db.DB.Table("(?) AS r", retentionQuery(r.stores(c, "customers.store_id"), time.Now()))
+    if segment := c.Query("segment"); segment != "" {
+        query = query.Where("r.segment = ?", segment)
+    }
+
+    var rows []RetentionRow
+    if err := query.Order("r.store_id, r.recency_days, r.customer_id").Scan(&rows).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to compute RFM"})
+        return
+    }
+    summary := map[string]int{
+        model.SegmentLoyal: 0, model.SegmentActive: 0, model.SegmentNew: 0, model.SegmentAtRisk: 0, model.SegmentLapsed: 0,
+    }
+    for _, row := range rows {
+        summary[row.Segment]++
+    }
+    c.JSON(200, gin.H{"customers": rows, "summary": summary})
+}
+
+// @Summary      来店遅れの顧客
+// @Description  いつもの来店間隔（店舗の設定の割合）を過ぎても来店がない顧客を、遅れの大きい順に返します。離反（lapsed）の顧客は含めません
+// @Tags         analytics
+// @Accept       json
+// @Produce      json
+// @Param        store_id  query  int  false  "店舗ID"
+// @Success      200 {array} RetentionRow
+// @Router       /analytics/overdue [get]
+func GetOverdueCustomersHandler(c *gin.Context) {
+    r := analyticsRange{StoreID: c.Query("store_id")}
+    var rows []RetentionRow
+    if err := db.DB.Table("(?) AS r", retentionQuery(r.stores(c, "customers.store_id"), time.Now())).
+        Where("r.overdue AND r.segment <> ?", model.SegmentLapsed).
+        Order("r.recency_days::float / r.interval_days DESC, r.customer_id").Scan(&rows).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch overdue customers"})
+        return
+    }
+    c.JSON(200, rows)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// applyStoreRetentionSettings はリクエストの離反分析のしきい値を店舗に反映する。
+// 省略した項目は店舗の今の値のまま（新規の店舗なら既定値）、0 の項目は既定値。
+// 離反予備軍の日数が離反の日数以上なら 400 を返して false。
+func applyStoreRetentionSettings(c *gin.Context, store *model.Store, req StoreRetentionSettings) bool {
+	for _, f := range []struct {
+		dst *int
+		req *int
+		def int
+	}{
+		{&store.RetentionLoyalVisits, req.RetentionLoyalVisits, 5},
+		{&store.RetentionAtRiskDays, req.RetentionAtRiskDays, 60},
+		{&store.RetentionLapsedDays, req.RetentionLapsedDays, 180},
+		{&store.RetentionOverduePercent, req.RetentionOverduePercent, 150},
+	} {
+		if f.req != nil {
+			*f.dst = *f.req
+		}
+		if *f.dst == 0 {
+			*f.dst = f.def
+		}
+	}
+	if store.RetentionAtRiskDays >= store.RetentionLapsedDays {
+		c.JSON(400, gin.H{"error": "離反予備軍の日数は離反の日数より短くしてください"})
+		return false
+	}
+	return true
+}
+
+// retentionQuery は顧客ごとの RFM と来店遅れの判定を計算するクエリを返す（取り消した来店・売上は含めない）。
+// 1回以上来店した顧客が対象。各行の列は RetentionRow と同じ。
+//
+//   - R（recency_days）: 最終来店から now までの日数
+//   - F（frequency）: 来店回数
+//   - M（monetary）: 会計の合計（税込）
+//   - r_score / f_score / m_score: 店舗内で5段階（5が最も良い）に分けた値
+//   - interval_days: いつもの来店間隔（初回から最終来店までの日数 / (来店回数 - 1)）。来店1回、または1日未満なら null
+//   - overdue: 最終来店からの日数が、いつもの間隔の店舗の設定の割合を超えている
+//
+// セグメントは店舗の設定で 離反 → 離反予備軍（期間の経過または来店遅れ）→ 優良 → 新規 → 来店中 の順に判定する。
+func retentionQuery(scopes func(*gorm.DB) *gorm.DB, now time.Time) *gorm.DB {
+	visits := db.DB.Model(&model.Visit{}).Where("voided_at IS NULL").
+		Select("customer_id, COUNT(*) AS frequency, MIN(created_at) AS first_visit_at, MAX(created_at) AS last_visit_at").
+		Group("customer_id")
+	sales := db.DB.Model(&model.Sale{}).Where("status = ? AND customer_id IS NOT NULL", model.SaleCompleted).
+		Select("customer_id, SUM(total) AS monetary").Group("customer_id")
+
+	stats := db.DB.Model(&model.Customer{}).Scopes(scopes).
+		Joins("JOIN (?) AS v ON v.customer_id = customers.id", visits).
+		Joins("LEFT JOIN (?) AS m ON m.customer_id = customers.id", sales).
+		Joins("JOIN stores ON stores.id = customers.store_id").
+		Select("customers.id AS customer_id, customers.store_id, customers.last_name, customers.first_name, "+
+			"customers.phone, v.frequency, v.last_visit_at, COALESCE(m.monetary, 0) AS monetary, "+
+			"FLOOR(EXTRACT(EPOCH FROM (?::timestamptz - v.last_visit_at)) / 86400)::int AS recency_days, "+
+			"CASE WHEN v.frequency > 1 THEN NULLIF(ROUND(EXTRACT(EPOCH FROM (v.last_visit_at - v.first_visit_at)) / 86400 / (v.frequency - 1))::int, 0) END AS interval_days, "+
+			"stores.retention_loyal_visits, stores.retention_at_risk_days, stores.retention_lapsed_days, stores.retention_overdue_percent", now)
+
+	const overdue = "(s.interval_days IS NOT NULL AND s.recency_days * 100 > s.interval_days * s.retention_overdue_percent)"
+	return db.DB.Table("(?) AS s", stats).
+		Select("s.customer_id, s.store_id, s.last_name, s.first_name, s.phone, s.frequency, s.last_visit_at, s.monetary, " +
+			"s.recency_days, s.interval_days, " +
+			"NTILE(5) OVER (PARTITION BY s.store_id ORDER BY s.recency_days DESC) AS r_score, " +
+			"NTILE(5) OVER (PARTITION BY s.store_id ORDER BY s.frequency) AS f_score, " +
+			"NTILE(5) OVER (PARTITION BY s.store_id ORDER BY s.monetary) AS m_score, " +
+			overdue + " AS overdue, " +
+			"CASE WHEN s.recency_days >= s.retention_lapsed_days THEN '" + model.SegmentLapsed + "' " +
+			"WHEN s.recency_days >= s.retention_at_risk_days OR " + overdue + " THEN '" + model.SegmentAtRisk + "' " +
+			"WHEN s.frequency >= s.retention_loyal_visits THEN '" + model.SegmentLoyal + "' " +
+			"WHEN s.frequency = 1 THEN '" + model.SegmentNew + "' " +
+			"ELSE '" + model.SegmentActive + "' END AS segment")
+}
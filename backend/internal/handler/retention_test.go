@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"salon-app/backend/internal/model"
+)
+
+func TestApplyStoreRetentionSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	n := func(v int) *int { return &v }
+	current := model.Store{RetentionLoyalVisits: 8, RetentionAtRiskDays: 90, RetentionLapsedDays: 365, RetentionOverduePercent: 200}
+
+	tests := []struct {
+		name  string
+		store model.Store
+		req   StoreRetentionSettings
+		ok    bool
+		want  [4]int // loyal visits, at-risk days, lapsed days, overdue percent
+	}{
+		{"new store gets defaults", model.Store{}, StoreRetentionSettings{}, true, [4]int{5, 60, 180, 150}},
+		{"omitted keeps current", current, StoreRetentionSettings{RetentionAtRiskDays: n(120)}, true, [4]int{8, 120, 365, 200}},
+		{"zero resets to default", current, StoreRetentionSettings{RetentionLoyalVisits: n(0)}, true, [4]int{5, 90, 365, 200}},
+		{"at-risk not before lapsed", current, StoreRetentionSettings{RetentionAtRiskDays: n(365)}, false, [4]int{}},
+		{"lapsed lowered below current at-risk", current, StoreRetentionSettings{RetentionLapsedDays: n(60)}, false, [4]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			store := tt.store
+			if ok := applyStoreRetentionSettings(c, &store, tt.req); ok != tt.ok {
+				t.Fatalf("ok = %v (status %d), want %v", ok, w.Code, tt.ok)
+			}
+			if !tt.ok {
+				if w.Code != 400 {
+					t.Errorf("status = %d, want 400", w.Code)
+				}
+				return
+			}
+			got := [4]int{store.RetentionLoyalVisits, store.RetentionAtRiskDays, store.RetentionLapsedDays, store.RetentionOverduePercent}
+			if got != tt.want {
+				t.Errorf("settings = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+// @Summary      会計（売上登録）
+// @Description  明細（回数券・施術・物販）と支払（現金・カード・QR・その他、複数で併用払い）を登録します。
+// @Description  回数券の明細で ticket_id がなければその場で回数券を発行します。支払の合計は会計金額と一致する必要があります
+// @Tags         sale
+// @Accept       json
+// @Produce      json
+// @Param        body body SaleRequest true "会計内容"
+// @Success      200 {object} model.Sale
+// @Router       /sales [post]
+func CreateSaleHandler(c *gin.Context) {
+    var req SaleRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    if !checkStoreAccess(c, req.StoreID) {
+        return
+    }
+
+    var sale model.Sale
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        var err error
+        sale, err = createSale(tx, req, middleware.CurrentUserID(c))
+        return err
+    })
+    var invalid errInvalidSale
+    if errors.As(err, &invalid) {
+        c.JSON(400, gin.H{"error": invalid.msg})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to create sale"})
+        return
+    }
+    c.JSON(200, sale)
+}
+
+// saleListSpec は売上一覧の並び替え・絞り込み
+var saleListSpec = listSpec{
+    Sorts:       map[string]string{"sold_at": "sales.sold_at", "id": "sales.id", "total": "sales.total"},
+    DefaultSort: "sold_at",
+    Filters: []listFilter{
+        {Param: "store_id", Column: "sales.store_id", Kind: filterID},
+        {Param: "customer_id", Column: "sales.customer_id", Kind: filterID},
+        {Param: "user_id", Column: "sales.user_id", Kind: filterID},
+        {Param: "status", Column: "sales.status", Kind: filterEnum, Values: []string{model.SaleCompleted, model.SaleVoided}},
+        {Param: "from", Column: "sales.sold_at", Kind: filterSince},
+        {Param: "to", Column: "sales.sold_at", Kind: filterUntil},
+    },
+}
+
+// @Summary      売上一覧
+// @Description  売上一覧と、絞り込んだ売上のうち取り消されていないものの合計（支払方法別の内訳付き。合計はページングの前の全件が対象）。
+// @Description  date・from・to をどれも指定しなければ今日の売上
+// @Tags         sale
+// @Accept       json
+// @Produce      json
+// @Param        date         query  string  false  "日付 YYYY-MM-DD（1日分）"
+// @Param        from         query  string  false  "会計日 YYYY-MM-DD 以降"
+// @Param        to           query  string  false  "会計日 YYYY-MM-DD まで"
+// @Param        store_id     query  int     false  "店舗ID"
+// @Param        customer_id  query  int     false  "顧客ID"
+// @Param        user_id      query  int     false  "会計したスタッフID"
+// @Param        status       query  string  false  "completed / voided"
+// @Param        sort         query  string  false  "sold_at（既定）/ id / total。- を付けると降順"
+// @Param        limit        query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor       query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {object} map[string]interface{}
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
+// @Router       /sales [get]
+func GetSaleListHandler(c *gin.Context) {
+    query := db.DB.Scopes(middleware.StoreScope(c))
+    response := gin.H{}
+    if v := c.Query("date"); v != "" || (c.Query("from") == "" && c.Query("to") == "") {
+        now := time.Now().In(utils.JST)
+        day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, utils.JST)
+        if v != "" {
+            t, err := time.ParseInLocation(dateLayout, v, utils.JST)
+            if err != nil {
+                c.JSON(400, gin.H{"error": "date の形式が正しくありません（YYYY-MM-DD）"})
+                return
+            }
+            day = t
+        }
+        query = query.Where("sales.sold_at >= ? AND sales.sold_at < ?", day, day.AddDate(0, 0, 1))
+        response["date"] = day.Format(dateLayout)
+    }
+    query = query.Session(&gorm.Session{})
+
+    var sales []model.Sale
+    if !paginate(c, query, saleListSpec, &sales, "Lines", "Payments", "Taxes", "Customer") {
+        return
+    }
+
+    // 合計は取り消されていない売上のみ
+    completed, _ := applyListFilters(c, query, saleListSpec)
+    completed = completed.Model(&model.Sale{}).Where("sales.status = ?", model.SaleCompleted).Session(&gorm.Session{})
+    var summary struct {
+        Count int `json:"count"`
+        Total int `json:"total"`
+    }
+    if err := completed.Select("COUNT(*) AS count, COALESCE(SUM(sales.total), 0) AS total").Scan(&summary).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch sales"})
+        return
+    }
+    var methods []struct {
+        Method string
+        Amount int
+    }
+    if err := db.DB.Model(&model.SalePayment{}).Where("sale_id IN (?)", completed.Select("sales.id")).
+        Select("method, SUM(amount) AS amount").Group("method").Scan(&methods).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch sales"})
+        return
+    }
+    byMethod := map[string]int{}
+    for _, m := range methods {
+        byMethod[m.Method] = m.Amount
+    }
+
+    response["sales"] = sales
+    response["summary"] = gin.H{"count": summary.Count, "total": summary.Total, "by_method": byMethod}
+    c.JSON(200, response)
+}
+
+// @Summary      売上詳細
+// @Description  売上詳細（明細・支払付き）
+// @Tags         sale
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Sale ID"
+// @Success      200 {object} model.Sale
+// @Router       /sales/{id} [get]
+func GetSaleHandler(c *gin.Context) {
+    var sale model.Sale
+    if err := db.DB.Scopes(middleware.StoreScope(c)).Preload("Lines").Preload("Payments").Preload("Taxes").Preload("Customer").
+        First(&sale, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Sale not found"})
+        return
+    }
+    c.JSON(200, sale)
+}
+
+// @Summary      売上取消
+// @Description  売上を取り消します（記録は理由とともに残ります）。この売上で発行した回数券は、未使用であれば無効にします（発行済みの回数券の代金を受け取った明細のチケットはそのまま残ります）。発行した回数券に消化・返金・調整・譲渡があった売上は取り消せないので、チケット調整の refund を使ってください
+// @Tags         sale
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Sale ID"
+// @Param        body body SaleVoidRequest true "取消理由"
+// @Success      200 {object} model.Sale
+// @Router       /sales/{id}/void [post]
+func VoidSaleHandler(c *gin.Context) {
+    var req SaleVoidRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "取消理由を入力してください"})
+        return
+    }
+
+    var sale model.Sale
+    errVoided := errors.New("sale already voided")
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Scopes(middleware.StoreScope(c)).Clauses(clause.Locking{Strength: "UPDATE"}).
+            First(&sale, c.Param("id")).Error; err != nil {
+            return err
+        }
+        if sale.Status == model.SaleVoided {
+            return errVoided
+        }
+        var lines []model.SaleLine
+        // この会計で発行した回数券だけを削除する（発行済みの回数券の代金を受け取った明細はチケットを残す）
+        if err := tx.Where("sale_id = ? AND ticket_id IS NOT NULL AND issued_by_sale = ?", sale.ID, true).Find(&lines).Error; err != nil {
+            return err
+        }
+        for _, line := range lines {
+            var ticket model.Ticket
+            if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticket, *line.TicketID).Error; err != nil {
+                return err
+            }
+            if err := checkIssuedTicketUnchanged(tx, ticket, line); err != nil {
+                return err
+            }
+            if err := tx.Delete(&ticket).Error; err != nil {
+                return err
+            }
+        }
+
+        now := time.Now()
+        sale.Status = model.SaleVoided
+        sale.VoidedAt = &now
+        sale.VoidReason = req.Reason
+        if userID := middleware.CurrentUserID(c); userID != 0 {
+            sale.VoidedByID = &userID
+        }
+        return tx.Omit(clause.Associations).Save(&sale).Error
+    })
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(404, gin.H{"error": "Sale not found"})
+        return
+    }
+    if errors.Is(err, errVoided) {
+        c.JSON(409, gin.H{"error": "この売上はすでに取り消されています"})
+        return
+    }
+    if errors.Is(err, errTicketUsed) {
+        c.JSON(409, gin.H{"error": "消化・返金・調整・譲渡のあった回数券を含む売上は取り消せません（チケット調整の返金を使ってください）"})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to void sale"})
+        return
+    }
+    c.JSON(200, sale)
+}
+
+// @Summary      領収書
+// @Description  売上の領収書（適格簡易請求書）。登録番号・税率ごとの対象額と消費税額を含みます。format=text でレシートプリンター向けのテキストを返します
+// @Tags         sale
+// @Accept       json
+// @Produce      json
+// @Produce      plain
+// @Param        id      path   int     true   "Sale ID"
+// @Param        format  query  string  false  "json（既定）/ text"
+// @Success      200 {object} Receipt
+// @Router       /sales/{id}/receipt [get]
+func GetSaleReceiptHandler(c *gin.Context) {
+    receipt, ok := loadReceipt(c)
+    if !ok {
+        return
+    }
+    if c.Query("format") == "text" {
+        c.String(200, renderReceiptText(receipt))
+        return
+    }
+    c.JSON(200, receipt)
+}
+
+// @Summary      領収書PDF
+// @Description  売上の領収書（type=invoice なら請求書）をPDFで返します。会計時に保存した内容から作るため、同じ売上からは常に同じPDFになります
+// @Tags         sale
+// @Produce      application/pdf
+// @Param        id    path   int     true   "Sale ID"
+// @Param        type  query  string  false  "receipt（既定）/ invoice"
+// @Success      200 {file} file
+// @Router       /sales/{id}/receipt.pdf [get]
+func GetSaleReceiptPDFHandler(c *gin.Context) {
+    receipt, ok := loadReceipt(c)
+    if !ok {
+        return
+    }
+    invoice := c.Query("type") == "invoice"
+    pdf, err := renderReceiptPDF(receipt, invoice)
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to render receipt"})
+        return
+    }
+    name := fmt.Sprintf("receipt-%d.pdf", receipt.SaleID)
+    if invoice {
+        name = fmt.Sprintf("invoice-%d.pdf", receipt.SaleID)
+    }
+    c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, name))
+    c.Data(200, "application/pdf", pdf)
+}
+
+// loadReceipt は URL の売上IDから領収書の内容を作る。見つからなければ 404 を返して false。
+func loadReceipt(c *gin.Context) (Receipt, bool) {
+    var sale model.Sale
+    if err := db.DB.Scopes(middleware.StoreScope(c)).Preload("Lines", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+        Preload("Payments", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+        Preload("Taxes", func(tx *gorm.DB) *gorm.DB { return tx.Order("rate DESC") }).
+        Preload("Customer").First(&sale, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Sale not found"})
+        return Receipt{}, false
+    }
+    var store model.Store
+    if err := db.DB.First(&store, sale.StoreID).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to load store"})
+        return Receipt{}, false
+    }
+    return buildReceipt(sale, store), true
+}
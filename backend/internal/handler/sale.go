@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/model"
+)
+
+// errInvalidSale は会計の内容が正しくない場合のエラー（メッセージはそのままレスポンスに使う）
+type errInvalidSale struct{ msg string }
+
+func (e errInvalidSale) Error() string { return e.msg }
+
+// salePaymentMethod は支払の内訳から売上の支払方法を決める（複数の方法を併用していれば split）
+func salePaymentMethod(payments []SalePaymentRequest) string {
+	method := payments[0].Method
+	for _, p := range payments[1:] {
+		if p.Method != method {
+			return model.PaymentSplit
+		}
+	}
+	return method
+}
+
+// createSale は会計を検証して売上を作成する。必ずトランザクション内で呼ぶこと。
+// 回数券の明細で ticket_id がなければ、その場で回数券を発行する（支払額は明細の金額）。
+func createSale(tx *gorm.DB, req SaleRequest, userID uint) (model.Sale, error) {
+	var store model.Store
+	if err := tx.First(&store, req.StoreID).Error; err != nil {
+		return model.Sale{}, notFoundAsInvalid(err, "店舗が見つかりません")
+	}
+	sale := model.Sale{
+		StoreID:        req.StoreID,
+		CustomerID:     req.CustomerID,
+		UserID:         userID,
+		SoldAt:         time.Now(),
+		DiscountAmount: req.DiscountAmount,
+		PaymentMethod:  salePaymentMethod(req.Payments),
+		Status:         model.SaleCompleted,
+		Memo:           req.Memo,
+		TaxMode:        store.PriceTaxMode,
+		IssuerName:     store.Name,
+		InvoiceNumber:  store.InvoiceNumber,
+	}
+	if req.SoldAt != nil {
+		sale.SoldAt = *req.SoldAt
+	}
+	if sale.CustomerID != nil {
+		var count int64
+		if err := tx.Model(&model.Customer{}).Where("id = ? AND store_id = ?", *sale.CustomerID, sale.StoreID).Count(&count).Error; err != nil {
+			return sale, err
+		}
+		if count == 0 {
+			return sale, errInvalidSale{"顧客と会計店舗が一致しません"}
+		}
+	}
+
+	// 回数券の発行は金額の確定後に行う
+	var issues []int
+	seenTickets, seenVisits := map[uint]bool{}, map[uint]bool{}
+	for i, lr := range req.Lines {
+		line, issue, err := buildSaleLine(tx, store, &sale, lr)
+		if err != nil {
+			return sale, err
+		}
+		if (line.TicketID != nil && seenTickets[*line.TicketID]) || (line.VisitID != nil && seenVisits[*line.VisitID]) {
+			return sale, errInvalidSale{"同じ回数券・来店記録が複数の明細に含まれています"}
+		}
+		if line.TicketID != nil {
+			seenTickets[*line.TicketID] = true
+		}
+		if line.VisitID != nil {
+			seenVisits[*line.VisitID] = true
+		}
+		if issue {
+			issues = append(issues, i)
+		}
+		sale.Lines = append(sale.Lines, line)
+		sale.Subtotal += line.Amount
+	}
+	if sale.DiscountAmount > sale.Subtotal {
+		return sale, errInvalidSale{"値引きが合計金額を超えています"}
+	}
+	if sale.CustomerID != nil {
+		var customer model.Customer
+		if err := tx.First(&customer, *sale.CustomerID).Error; err != nil {
+			return sale, err
+		}
+		sale.CustomerName = customer.LastName + " " + customer.FirstName
+	}
+	sale.Taxes, sale.TaxTotal = computeSaleTaxes(sale.Lines, sale.DiscountAmount, store.PriceTaxMode, store.TaxRounding)
+	sale.Total = sale.Subtotal - sale.DiscountAmount
+	if store.PriceTaxMode == model.TaxExclusive {
+		sale.Total += sale.TaxTotal
+	}
+
+	paid := 0
+	for _, p := range req.Payments {
+		sale.Payments = append(sale.Payments, model.SalePayment{Method: p.Method, Amount: p.Amount})
+		paid += p.Amount
+	}
+	if paid != sale.Total {
+		return sale, errInvalidSale{"支払金額の合計が会計金額と一致しません"}
+	}
+
+	for _, i := range issues {
+		line := &sale.Lines[i]
+		var course model.Course
+		if err := tx.First(&course, *line.CourseID).Error; err != nil {
+			return sale, err
+		}
+		ticket := newTicketFromCourse(*sale.CustomerID, course, sale.SoldAt, sale.PaymentMethod, userID)
+		ticket.PricePaid = line.Amount
+		if err := tx.Create(&ticket).Error; err != nil {
+			return sale, err
+		}
+		line.TicketID = &ticket.ID
+		line.IssuedBySale = true
+	}
+
+	return sale, tx.Create(&sale).Error
+}
+
+// buildSaleLine は明細1行分を検証して金額を計算する。issue が true なら回数券を新しく発行する明細。
+func buildSaleLine(tx *gorm.DB, store model.Store, sale *model.Sale, req SaleLineRequest) (model.SaleLine, bool, error) {
+	line := model.SaleLine{
+		Kind:           req.Kind,
+		CourseID:       req.CourseID,
+		Description:    req.Description,
+		Quantity:       req.Quantity,
+		DiscountAmount: req.DiscountAmount,
+	}
+	if line.Quantity == 0 {
+		line.Quantity = 1
+	}
+	unitPrice := -1 // 未指定
+	if req.UnitPrice != nil {
+		unitPrice = *req.UnitPrice
+	}
+	taxCategory := req.TaxCategory // 未指定ならコースの税率区分、それもなければ標準税率
+	issue := false
+
+	switch req.Kind {
+	case model.SaleLineTicket:
+		if line.Quantity != 1 {
+			return line, false, errInvalidSale{"回数券は1明細につき1枚で登録してください"}
+		}
+		if req.TicketID != nil {
+			// 発行済みの回数券の代金を受け取る
+			var ticket model.Ticket
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("store_id = ?", sale.StoreID).First(&ticket, *req.TicketID).Error; err != nil {
+				return line, false, notFoundAsInvalid(err, "回数券が見つかりません")
+			}
+			if sale.CustomerID == nil {
+				sale.CustomerID = &ticket.CustomerID
+			} else if *sale.CustomerID != ticket.CustomerID {
+				return line, false, errInvalidSale{"回数券の購入者と会計の顧客が一致しません"}
+			}
+			if paid, err := alreadyPaid(tx, "ticket_id", ticket.ID); err != nil || paid {
+				return line, false, paidAsInvalid(err, "この回数券の代金はすでに計上されています")
+			}
+			line.TicketID = &ticket.ID
+			line.CourseID = &ticket.CourseID
+			remaining := ticket.Remaining()
+			line.TicketRemaining = &remaining
+			line.Description = defaultString(line.Description, ticket.CourseName)
+			if unitPrice < 0 {
+				unitPrice = ticket.PricePaid
+			}
+			break
+		}
+		if req.CourseID == nil {
+			return line, false, errInvalidSale{"回数券の明細には course_id か ticket_id が必要です"}
+		}
+		if sale.CustomerID == nil {
+			return line, false, errInvalidSale{"回数券を販売するには顧客の指定が必要です"}
+		}
+		var course model.Course
+		if err := tx.Where("store_id = ?", sale.StoreID).First(&course, *req.CourseID).Error; err != nil {
+			return line, false, notFoundAsInvalid(err, "コースが見つかりません")
+		}
+		if course.TotalCount <= 0 {
+			return line, false, errInvalidSale{"このコースは回数券として販売できません"}
+		}
+		line.Description = defaultString(line.Description, course.Name)
+		if unitPrice < 0 {
+			unitPrice = course.Price
+		}
+		taxCategory = defaultString(taxCategory, course.TaxCategory)
+		remaining := course.TotalCount
+		line.TicketRemaining = &remaining
+		issue = true
+
+	case model.SaleLineTreatment:
+		if req.VisitID != nil {
+			var visit model.Visit
+			// 同じ来店の施術代を同時に二重計上しないよう、来店記録をロックしてから確認する
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("store_id = ? AND voided_at IS NULL", sale.StoreID).First(&visit, *req.VisitID).Error; err != nil {
+				return line, false, notFoundAsInvalid(err, "来店記録が見つかりません")
+			}
+			if sale.CustomerID == nil {
+				sale.CustomerID = &visit.CustomerID
+			} else if *sale.CustomerID != visit.CustomerID {
+				return line, false, errInvalidSale{"来店記録の顧客と会計の顧客が一致しません"}
+			}
+			if paid, err := alreadyPaid(tx, "visit_id", visit.ID); err != nil || paid {
+				return line, false, paidAsInvalid(err, "この来店の施術代はすでに計上されています")
+			}
+			line.VisitID = &visit.ID
+			if line.CourseID == nil {
+				line.CourseID = &visit.CourseID
+			}
+			if visit.TicketID != nil {
+				var ticket model.Ticket
+				if err := tx.Unscoped().First(&ticket, *visit.TicketID).Error; err != nil {
+					return line, false, err
+				}
+				remaining := ticket.Remaining()
+				line.TicketRemaining = &remaining
+			}
+		}
+		if line.CourseID != nil {
+			var course model.Course
+			if err := tx.Where("store_id = ?", sale.StoreID).First(&course, *line.CourseID).Error; err != nil {
+				return line, false, notFoundAsInvalid(err, "コースが見つかりません")
+			}
+			line.Description = defaultString(line.Description, course.Name)
+			if unitPrice < 0 {
+				unitPrice = course.Price
+			}
+			taxCategory = defaultString(taxCategory, course.TaxCategory)
+		}
+
+	case model.SaleLineRetail:
+		line.CourseID = nil
+	}
+
+	if line.Description == "" || unitPrice < 0 {
+		return line, false, errInvalidSale{"明細の品名（description）と単価（unit_price）を指定してください"}
+	}
+	line.UnitPrice = unitPrice
+	line.TaxCategory = defaultString(taxCategory, model.TaxStandard)
+	line.TaxRate = store.TaxRate(line.TaxCategory)
+	line.Amount = line.UnitPrice*line.Quantity - line.DiscountAmount
+	if line.Amount < 0 {
+		return line, false, errInvalidSale{"明細の値引きが金額を超えています"}
+	}
+	return line, issue, nil
+}
+
+// errTicketUsed は取り消そうとした売上で発行した回数券が、すでに発行時の状態から変わっていることを表す
+var errTicketUsed = errors.New("ticket in use")
+
+// checkIssuedTicketUnchanged は売上で発行した回数券が発行時のまま（消化・返金・調整・譲渡がなく、
+// 回数も販売した回数のまま）かを確認する。変わっていれば、削除すると返金や譲渡先の記録と食い違うので errTicketUsed を返す。
+func checkIssuedTicketUnchanged(tx *gorm.DB, ticket model.Ticket, line model.SaleLine) error {
+	if ticket.CurrentCount > 0 || ticket.RefundedAmount > 0 {
+		return errTicketUsed
+	}
+	if line.TicketRemaining != nil && ticket.TotalCount != *line.TicketRemaining {
+		return errTicketUsed
+	}
+	var count int64
+	if err := tx.Model(&model.TicketAdjustment{}).Where("ticket_id = ?", ticket.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errTicketUsed
+	}
+	if err := tx.Model(&model.TicketTransfer{}).
+		Where("from_ticket_id = ? OR to_ticket_id = ?", ticket.ID, ticket.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errTicketUsed
+	}
+	return nil
+}
+
+// alreadyPaid は取り消されていない売上に column の値が id の明細があるかを返す
+func alreadyPaid(tx *gorm.DB, column string, id uint) (bool, error) {
+	var count int64
+	err := tx.Model(&model.SaleLine{}).
+		Joins("JOIN sales ON sales.id = sale_lines.sale_id AND sales.deleted_at IS NULL").
+		Where("sale_lines."+column+" = ? AND sales.status = ?", id, model.SaleCompleted).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func notFoundAsInvalid(err error, msg string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errInvalidSale{msg}
+	}
+	return err
+}
+
+func paidAsInvalid(err error, msg string) error {
+	if err != nil {
+		return err
+	}
+	return errInvalidSale{msg}
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// TestVoidSaleIssuedTicket は売上の取消で、発行した回数券が発行時のままなら削除され、
+// 消化・返金・調整・譲渡・回数の変更があれば 409 で取り消せない（売上も回数券も残る）ことを確認する。
+func TestVoidSaleIssuedTicket(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T, ticket *model.Ticket)
+		want   int
+	}{
+		{"unchanged", func(t *testing.T, ticket *model.Ticket) {}, http.StatusOK},
+		{"consumed", func(t *testing.T, ticket *model.Ticket) {
+			mustExec(t, db.DB.Model(ticket).UpdateColumn("current_count", 1))
+		}, http.StatusConflict},
+		{"refunded", func(t *testing.T, ticket *model.Ticket) {
+			mustExec(t, db.DB.Model(ticket).UpdateColumn("refunded_amount", 10000))
+		}, http.StatusConflict},
+		{"adjusted", func(t *testing.T, ticket *model.Ticket) {
+			mustExec(t, db.DB.Create(&model.TicketAdjustment{TicketID: ticket.ID, StoreID: ticket.StoreID,
+				Kind: model.AdjustComplimentary, ReasonCode: model.ReasonOther, TotalBefore: 5, TotalAfter: 5}))
+		}, http.StatusConflict},
+		{"transferred", func(t *testing.T, ticket *model.Ticket) {
+			mustExec(t, db.DB.Create(&model.TicketTransfer{FromTicketID: ticket.ID, ToTicketID: ticket.ID, StoreID: ticket.StoreID,
+				FromCustomerID: ticket.CustomerID, ToCustomerID: ticket.CustomerID, Sessions: 1, ReasonCode: model.ReasonOther}))
+		}, http.StatusConflict},
+		{"total count changed", func(t *testing.T, ticket *model.Ticket) {
+			mustExec(t, db.DB.Model(ticket).UpdateColumn("total_count", 6))
+		}, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sale, ticket := seedTicketSale(t)
+			tt.change(t, &ticket)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"reason":"入力ミス"}`))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(sale.ID))}}
+			VoidSaleHandler(c)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d (%s), want %d", w.Code, w.Body.String(), tt.want)
+			}
+
+			var got model.Sale
+			if err := db.DB.First(&got, sale.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			var tickets int64
+			if err := db.DB.Model(&model.Ticket{}).Where("id = ?", ticket.ID).Count(&tickets).Error; err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == http.StatusOK && (got.Status != model.SaleVoided || tickets != 0) {
+				t.Errorf("sale status = %q, tickets = %d, want voided and ticket deleted", got.Status, tickets)
+			}
+			if tt.want != http.StatusOK && (got.Status == model.SaleVoided || tickets != 1) {
+				t.Errorf("sale status = %q, tickets = %d, want sale and ticket kept", got.Status, tickets)
+			}
+		})
+	}
+}
+
+// seedTicketSale は5回券を1枚販売した売上を作成する（テスト終了時に関連データごと削除する）
+func seedTicketSale(t *testing.T) (model.Sale, model.Ticket) {
+	t.Helper()
+	store := model.Store{Name: "test store"}
+	mustExec(t, db.DB.Create(&store))
+	customer := model.Customer{LastName: "山田", FirstName: "花子", StoreID: store.ID}
+	mustExec(t, db.DB.Create(&customer))
+	course := model.Course{Name: "5回券", Price: 50000, TotalCount: 5, StoreID: store.ID}
+	mustExec(t, db.DB.Create(&course))
+
+	var sale model.Sale
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		sale, err = createSale(tx, SaleRequest{
+			StoreID:    store.ID,
+			CustomerID: &customer.ID,
+			Lines:      []SaleLineRequest{{Kind: model.SaleLineTicket, CourseID: &course.ID}},
+			Payments:   []SalePaymentRequest{{Method: model.PaymentCash, Amount: course.Price}},
+		}, 0)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ticket model.Ticket
+	if err := db.DB.First(&ticket, *sale.Lines[0].TicketID).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.DB.Unscoped().Where("ticket_id = ?", ticket.ID).Delete(&model.TicketAdjustment{})
+		db.DB.Unscoped().Where("from_ticket_id = ?", ticket.ID).Delete(&model.TicketTransfer{})
+		for _, m := range []interface{}{&model.SaleLine{}, &model.SalePayment{}, &model.SaleTax{}} {
+			db.DB.Unscoped().Where("sale_id = ?", sale.ID).Delete(m)
+		}
+		db.DB.Unscoped().Delete(&sale)
+		db.DB.Unscoped().Delete(&ticket)
+		db.DB.Unscoped().Delete(&course)
+		db.DB.Unscoped().Delete(&customer)
+		db.DB.Unscoped().Delete(&store)
+	})
+	return sale, ticket
+}
+
+func mustExec(t *testing.T, tx *gorm.DB) {
+	t.Helper()
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
+	"salon-app/backend/internal/model"
+)
+
+// @Summary      営業時間更新
+// @Description  店舗の営業時間・予約枠の刻み・定休日を更新します
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Store ID"
+// @Param        body body BusinessHoursRequest true "営業時間"
+// @Success      200 {object} model.Store
+// @Router       /store/{id}/business-hours [put]
+func UpdateBusinessHoursHandler(c *gin.Context) {
+    var store model.Store
+    if err := db.DB.Scopes(middleware.StoreScopeColumn(c, "id")).First(&store, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "店舗が見つかりません"})
+        return
+    }
+    if !checkStoreAccess(c, store.ID) {
+        return
+    }
+
+    var req BusinessHoursRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    // "9:00" も受け付けるので文字列ではなく分に直して比べる
+    openAt, okOpen := parseClock(req.OpenTime)
+    closeAt, okClose := parseClock(req.CloseTime)
+    if !okOpen || !okClose || openAt >= closeAt {
+        c.JSON(400, gin.H{"error": "閉店時刻は開店時刻より後にしてください"})
+        return
+    }
+
+    weekdays := make([]string, 0, len(req.RegularHolidays))
+    for _, d := range req.RegularHolidays {
+        weekdays = append(weekdays, strconv.Itoa(d))
+    }
+    store.OpenTime = formatClock(openAt)
+    store.CloseTime = formatClock(closeAt)
+    store.SlotMinutes = req.SlotMinutes
+    store.RegularHolidays = strings.Join(weekdays, ",")
+    if err := db.DB.Save(&store).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to update business hours"})
+        return
+    }
+    c.JSON(200, store)
+}
+
+// @Summary      臨時休業日一覧
+// @Description  店舗の臨時休業日一覧
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Store ID"
+// @Success      200 {object} model.StoreHoliday
+// @Router       /store/{id}/holidays [get]
+func GetStoreHolidaysHandler(c *gin.Context) {
+    var holidays []model.StoreHoliday
+    if err := db.DB.Scopes(middleware.StoreScope(c)).Where("store_id = ?", c.Param("id")).
+        Order("date").Find(&holidays).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch holidays"})
+        return
+    }
+    c.JSON(200, holidays)
+}
+
+// @Summary      臨時休業日登録
+// @Description  臨時休業日を登録します（同じ日付が登録済みの場合は理由を上書き）
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Store ID"
+// @Param        body body StoreHolidayRequest true "休業日"
+// @Success      200 {object} model.StoreHoliday
+// @Router       /store/{id}/holidays [post]
+func CreateStoreHolidayHandler(c *gin.Context) {
+    var store model.Store
+    if err := db.DB.Scopes(middleware.StoreScopeColumn(c, "id")).First(&store, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "店舗が見つかりません"})
+        return
+    }
+    if !checkStoreAccess(c, store.ID) {
+        return
+    }
+    var req StoreHolidayRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    date, _ := time.Parse(dateLayout, req.Date)
+
+    holiday := model.StoreHoliday{StoreID: store.ID, Date: date, Reason: req.Reason}
+    if err := db.DB.Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "store_id"}, {Name: "date"}},
+        DoUpdates: clause.AssignmentColumns([]string{"reason", "updated_at"}),
+    }).Create(&holiday).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to register holiday"})
+        return
+    }
+    c.JSON(200, holiday)
+}
+
+// @Summary      臨時休業日削除
+// @Description  臨時休業日を削除します
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        id          path  int  true  "Store ID"
+// @Param        holiday_id  path  int  true  "Holiday ID"
+// @Success      200 {object} map[string]string
+// @Router       /store/{id}/holidays/{holiday_id} [delete]
+func DeleteStoreHolidayHandler(c *gin.Context) {
+    // 同じ日付を登録し直せるよう物理削除する
+    result := db.DB.Unscoped().Scopes(middleware.StoreScope(c)).
+        Where("store_id = ?", c.Param("id")).Delete(&model.StoreHoliday{}, c.Param("holiday_id"))
+    if result.Error != nil {
+        c.Error(result.Error)
+        c.JSON(500, gin.H{"error": "Failed to delete holiday"})
+        return
+    }
+    if result.RowsAffected == 0 {
+        c.JSON(404, gin.H{"error": "Holiday not found"})
+        return
+    }
+    c.JSON(200, gin.H{"message": "Holiday deleted successfully", "id": c.Param("holiday_id")})
+}
+
+// shiftListSpec はシフト一覧の並び替え・絞り込み
+var shiftListSpec = listSpec{
+    Sorts:       map[string]string{"date": "staff_shifts.date"},
+    DefaultSort: "date",
+    Filters: []listFilter{
+        {Param: "from", Column: "staff_shifts.date", Kind: filterDaySince},
+        {Param: "to", Column: "staff_shifts.date", Kind: filterDayUntil},
+        {Param: "staff_id", Column: "staff_shifts.user_id", Kind: filterID},
+    },
+}
+
+// @Summary      シフト一覧
+// @Description  スタッフの勤務シフト一覧。from / to（YYYY-MM-DD）、staff_id で絞り込めます
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        from      query  string  false  "開始日"
+// @Param        to        query  string  false  "終了日"
+// @Param        staff_id  query  int     false  "スタッフID"
+// @Param        sort      query  string  false  "date（既定）。- を付けると降順"
+// @Param        limit    query  int     false  "1ページの件数（既定 100、最大 500）"
+// @Param        cursor   query  string  false  "次のページのカーソル（前のレスポンスの X-Next-Cursor）"
+// @Success      200 {array} model.StaffShift
+// @Header       200 {string}  X-Next-Cursor  "次のページのカーソル（最後のページではなし）"
+// @Header       200 {integer} X-Total-Count  "絞り込み後の全件数"
+// @Router       /shift [get]
+func GetShiftListHandler(c *gin.Context) {
+    var shifts []model.StaffShift
+    if !paginate(c, db.DB.Scopes(middleware.StoreScope(c)), shiftListSpec, &shifts, "User") {
+        return
+    }
+    c.JSON(200, shifts)
+}
+
+// @Summary      シフト登録
+// @Description  スタッフの勤務シフトを登録します（同じスタッフ・同じ日付が登録済みの場合は上書き）
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        body body ShiftRequest true "シフト"
+// @Success      200 {object} model.StaffShift
+// @Router       /shift [post]
+func CreateShiftHandler(c *gin.Context) {
+    var req ShiftRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    start, okStart := parseClock(req.StartTime)
+    end, okEnd := parseClock(req.EndTime)
+    if !okStart || !okEnd || start >= end {
+        c.JSON(400, gin.H{"error": "終了時刻は開始時刻より後にしてください"})
+        return
+    }
+    var staff model.User
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&staff, req.UserID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "User not found"})
+        return
+    }
+    date, _ := time.Parse(dateLayout, req.Date)
+
+    shift := model.StaffShift{UserID: staff.ID, StoreID: staff.StoreID, Date: date, StartTime: formatClock(start), EndTime: formatClock(end)}
+    if err := db.DB.Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "user_id"}, {Name: "date"}},
+        DoUpdates: clause.AssignmentColumns([]string{"store_id", "start_time", "end_time", "updated_at"}),
+    }).Create(&shift).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to register shift"})
+        return
+    }
+    c.JSON(200, shift)
+}
+
+// @Summary      シフト削除
+// @Description  勤務シフトを削除します
+// @Tags         schedule
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Shift ID"
+// @Success      200 {object} map[string]string
+// @Router       /shift/{id} [delete]
+func DeleteShiftHandler(c *gin.Context) {
+    // 同じ日付を登録し直せるよう物理削除する
+    result := db.DB.Unscoped().Scopes(middleware.StoreScope(c)).Delete(&model.StaffShift{}, c.Param("id"))
+    if result.Error != nil {
+        c.Error(result.Error)
+        c.JSON(500, gin.H{"error": "Failed to delete shift"})
+        return
+    }
+    if result.RowsAffected == 0 {
+        c.JSON(404, gin.H{"error": "Shift not found"})
+        return
+    }
+    c.JSON(200, gin.H{"message": "Shift deleted successfully", "id": c.Param("id")})
+}
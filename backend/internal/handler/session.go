@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+// issueSession はアクセストークンとリフレッシュトークンを発行し、レスポンス用のJSONを返す。
+// user は Store を Preload 済みであること。
+func issueSession(tx *gorm.DB, user model.User) (gin.H, *model.RefreshToken, error) {
+	token, err := utils.GenerateToken(user.ID, user.Name, user.Role, user.StoreID, user.Store.Name, user.TokenVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plain, hash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	refresh := model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL),
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return gin.H{
+		"token":         token, // フロントエンドはこれを受け取って保存する
+		"refresh_token": plain,
+		"expires_in":    int(utils.AccessTokenTTL.Seconds()),
+		"user": gin.H{
+			"name": user.Name,
+			"role": user.Role,
+		},
+	}, &refresh, nil
+}
+
+// revokeAllSessions はユーザーの全セッションを無効化する。
+// TokenVersion を進めて発行済みアクセストークンを無効にし、未失効のリフレッシュトークンも失効させる。
+func revokeAllSessions(tx *gorm.DB, userID uint) error {
+	if err := tx.Model(&model.User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"salon-app/backend/internal/model"
+)
+
+// invoiceNumberPattern は適格請求書発行事業者の登録番号 ("T" + 13桁)
+var invoiceNumberPattern = regexp.MustCompile(`^T[0-9]{13}$`)
+
+// applyStoreTaxSettings はリクエストの消費税・インボイスの設定を店舗に反映する。
+// 省略した項目は店舗の今の値のまま（新規の店舗なら既定値）。登録番号の形式が正しくなければ 400 を返して false。
+func applyStoreTaxSettings(c *gin.Context, store *model.Store, req StoreTaxSettings) bool {
+	if req.InvoiceNumber != nil {
+		if *req.InvoiceNumber != "" && !invoiceNumberPattern.MatchString(*req.InvoiceNumber) {
+			c.JSON(400, gin.H{"error": "登録番号は T に続けて13桁の数字で入力してください"})
+			return false
+		}
+		store.InvoiceNumber = *req.InvoiceNumber
+	}
+	if req.PriceTaxMode != nil {
+		store.PriceTaxMode = *req.PriceTaxMode
+	}
+	if req.TaxRounding != nil {
+		store.TaxRounding = *req.TaxRounding
+	}
+	if req.StandardTaxRate != nil {
+		store.StandardTaxRate = *req.StandardTaxRate
+	}
+	if req.ReducedTaxRate != nil {
+		store.ReducedTaxRate = *req.ReducedTaxRate
+	}
+	store.PriceTaxMode = defaultString(store.PriceTaxMode, model.TaxInclusive)
+	store.TaxRounding = defaultString(store.TaxRounding, model.RoundFloor)
+	if store.StandardTaxRate == 0 {
+		store.StandardTaxRate = 10
+	}
+	if store.ReducedTaxRate == 0 {
+		store.ReducedTaxRate = 8
+	}
+	return true
+}
+
+// computeSaleTaxes は明細と会計全体の値引きから、税率ごとの対象額と消費税額を計算する。
+// 適格請求書のルールに合わせ、端数処理は税率ごとに1会計1回だけ行う（明細ごとには丸めない）。
+// 値引きは税率ごとの金額の比率で按分し、割り切れない分は金額の大きい税率に寄せる。
+// 返り値は税率の高い順。
+func computeSaleTaxes(lines []model.SaleLine, discount int, mode, rounding string) ([]model.SaleTax, int) {
+	byRate := map[int]int{}
+	subtotal := 0
+	for _, l := range lines {
+		byRate[l.TaxRate] += l.Amount
+		subtotal += l.Amount
+	}
+	rates := make([]int, 0, len(byRate))
+	for r := range byRate {
+		rates = append(rates, r)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rates)))
+
+	// 値引きの按分
+	alloc := map[int]int{}
+	if discount > 0 && subtotal > 0 {
+		rest, largest := discount, rates[0]
+		for _, r := range rates {
+			alloc[r] = discount * byRate[r] / subtotal
+			rest -= alloc[r]
+			if byRate[r] > byRate[largest] {
+				largest = r
+			}
+		}
+		alloc[largest] += rest
+	}
+
+	taxes := make([]model.SaleTax, 0, len(rates))
+	total := 0
+	for _, r := range rates {
+		taxable := byRate[r] - alloc[r]
+		var tax int
+		if mode == model.TaxExclusive {
+			tax = roundDiv(taxable*r, 100, rounding)
+		} else {
+			tax = roundDiv(taxable*r, 100+r, rounding)
+		}
+		taxes = append(taxes, model.SaleTax{Rate: r, Taxable: taxable, Tax: tax})
+		total += tax
+	}
+	return taxes, total
+}
+
+// roundDiv は a / b（どちらも0以上）を rounding の方法で整数に丸める
+func roundDiv(a, b int, rounding string) int {
+	switch rounding {
+	case model.RoundCeil:
+		return (a + b - 1) / b
+	case model.RoundHalfUp:
+		return (2*a + b) / (2 * b)
+	}
+	return a / b
+}
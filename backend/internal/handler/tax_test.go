@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+
+	"salon-app/backend/internal/model"
+)
+
+func TestRoundDiv(t *testing.T) {
+	tests := []struct {
+		a, b                int
+		floor, halfUp, ceil int
+	}{
+		{7, 2, 3, 4, 4}, // 3.5
+		{5, 3, 1, 2, 2}, // 1.67
+		{4, 3, 1, 1, 2}, // 1.33
+		{6, 3, 2, 2, 2},
+		{0, 110, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		for rounding, want := range map[string]int{model.RoundFloor: tt.floor, model.RoundHalfUp: tt.halfUp, model.RoundCeil: tt.ceil} {
+			if got := roundDiv(tt.a, tt.b, rounding); got != want {
+				t.Errorf("roundDiv(%d, %d, %s) = %d, want %d", tt.a, tt.b, rounding, got, want)
+			}
+		}
+	}
+}
+
+func TestComputeSaleTaxes(t *testing.T) {
+	line := func(rate, amount int) model.SaleLine { return model.SaleLine{TaxRate: rate, Amount: amount} }
+	tax := func(rate, taxable, tax int) model.SaleTax {
+		return model.SaleTax{Rate: rate, Taxable: taxable, Tax: tax}
+	}
+
+	tests := []struct {
+		name     string
+		lines    []model.SaleLine
+		discount int
+		mode     string
+		rounding string
+		want     []model.SaleTax
+		total    int
+	}{
+		// 税込 1,000 円の内税は 90.9 円
+		{"inclusive floor", []model.SaleLine{line(10, 1000)}, 0, model.TaxInclusive, model.RoundFloor,
+			[]model.SaleTax{tax(10, 1000, 90)}, 90},
+		{"inclusive half up", []model.SaleLine{line(10, 1000)}, 0, model.TaxInclusive, model.RoundHalfUp,
+			[]model.SaleTax{tax(10, 1000, 91)}, 91},
+		{"inclusive ceil", []model.SaleLine{line(10, 1000)}, 0, model.TaxInclusive, model.RoundCeil,
+			[]model.SaleTax{tax(10, 1000, 91)}, 91},
+		// 明細ごとではなく税率ごとに合計してから1回だけ丸める（333 円 × 3 の内税は 90.8 円）
+		{"rounded once per rate", []model.SaleLine{line(10, 333), line(10, 333), line(10, 334)}, 0, model.TaxInclusive, model.RoundFloor,
+			[]model.SaleTax{tax(10, 1000, 90)}, 90},
+		{"mixed rates", []model.SaleLine{line(8, 1080), line(10, 3300)}, 0, model.TaxInclusive, model.RoundFloor,
+			[]model.SaleTax{tax(10, 3300, 300), tax(8, 1080, 80)}, 380},
+		// 値引き 100 円を 1000:500 で按分（66 / 33）し、残りの 1 円は金額の大きい 10% に寄せる
+		{"discount remainder inclusive", []model.SaleLine{line(10, 1000), line(8, 500)}, 100, model.TaxInclusive, model.RoundFloor,
+			[]model.SaleTax{tax(10, 933, 84), tax(8, 467, 34)}, 118},
+		{"discount remainder inclusive half up", []model.SaleLine{line(10, 1000), line(8, 500)}, 100, model.TaxInclusive, model.RoundHalfUp,
+			[]model.SaleTax{tax(10, 933, 85), tax(8, 467, 35)}, 120},
+		{"discount remainder exclusive floor", []model.SaleLine{line(10, 1000), line(8, 500)}, 100, model.TaxExclusive, model.RoundFloor,
+			[]model.SaleTax{tax(10, 933, 93), tax(8, 467, 37)}, 130},
+		{"discount remainder exclusive ceil", []model.SaleLine{line(10, 1000), line(8, 500)}, 100, model.TaxExclusive, model.RoundCeil,
+			[]model.SaleTax{tax(10, 933, 94), tax(8, 467, 38)}, 132},
+		// 軽減税率の方が金額が大きければ、残りは 8% に寄せる（50 円を 300:900 で按分して 12 / 37、残り 1 円）
+		{"discount remainder to reduced rate", []model.SaleLine{line(10, 300), line(8, 900)}, 50, model.TaxExclusive, model.RoundHalfUp,
+			[]model.SaleTax{tax(10, 288, 29), tax(8, 862, 69)}, 98},
+		{"no lines", nil, 0, model.TaxInclusive, model.RoundFloor, []model.SaleTax{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := computeSaleTaxes(tt.lines, tt.discount, tt.mode, tt.rounding)
+			if !reflect.DeepEqual(got, tt.want) || total != tt.total {
+				t.Errorf("computeSaleTaxes = %+v, %d, want %+v, %d", got, total, tt.want, tt.total)
+			}
+		})
+	}
+}
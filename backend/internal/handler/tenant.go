@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"salon-app/backend/internal/middleware"
+)
+
+// checkStoreAccess は書き込み先の店舗がログインユーザーの操作範囲内か確認する。
+// 範囲外の場合は 403 を返して false を返すので、呼び出し側はそのまま return すること。
+func checkStoreAccess(c *gin.Context, storeID uint) bool {
+	if !middleware.CanAccessStore(c, storeID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "他店舗のデータは操作できません"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// ログイン失敗時のロック設定
+const (
+	accountLockThreshold = 5              // アカウント単位: この回数失敗したらロック開始
+	ipLockThreshold      = 20             // IP単位: 複数アカウントへの総当たり対策
+	lockBaseDuration     = time.Minute    // 初回ロック時間。以降、失敗のたびに倍になる
+	lockMaxDuration      = time.Hour      // ロック時間の上限
+	failureResetAfter    = 24 * time.Hour // 最後の失敗からこれだけ経てば失敗回数をリセット
+)
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// loginLockedUntil は指定キーのうち、最も遅いロック解除時刻を返す（ロック中でなければ false）
+func loginLockedUntil(tx *gorm.DB, keys ...string) (time.Time, bool, error) {
+	var throttles []model.LoginThrottle
+	if err := tx.Where("key IN ? AND locked_until > ?", keys, time.Now()).Find(&throttles).Error; err != nil {
+		return time.Time{}, false, err
+	}
+	var until time.Time
+	for _, t := range throttles {
+		if t.LockedUntil.After(until) {
+			until = *t.LockedUntil
+		}
+	}
+	return until, !until.IsZero(), nil
+}
+
+// recordLoginFailure は失敗回数を加算し、閾値を超えていれば指数的に伸びるロックを設定する
+func recordLoginFailure(email, ip string) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := bumpThrottle(tx, accountThrottleKey(email), accountLockThreshold); err != nil {
+			return err
+		}
+		return bumpThrottle(tx, ipThrottleKey(ip), ipLockThreshold)
+	})
+}
+
+func bumpThrottle(tx *gorm.DB, key string, threshold int) error {
+	// 行がなければ作ってから行ロックを取る（同時失敗でも回数を取りこぼさない）
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.LoginThrottle{Key: key}).Error; err != nil {
+		return err
+	}
+	var t model.LoginThrottle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&t).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if now.Sub(t.LastFailedAt) > failureResetAfter {
+		t.Failures = 0
+	}
+	t.Failures++
+	t.LastFailedAt = now
+	if t.Failures >= threshold {
+		until := now.Add(lockDuration(t.Failures - threshold))
+		t.LockedUntil = &until
+	}
+	return tx.Save(&t).Error
+}
+
+// lockDuration は閾値超過回数 n に対するロック時間（1分, 2分, 4分, ... 最大1時間）
+func lockDuration(n int) time.Duration {
+	d := float64(lockBaseDuration) * math.Pow(2, float64(n))
+	if d > float64(lockMaxDuration) {
+		return lockMaxDuration
+	}
+	return time.Duration(d)
+}
+
+// clearLoginThrottle は指定キーの失敗回数とロックを解除する
+func clearLoginThrottle(tx *gorm.DB, keys ...string) (int64, error) {
+	result := tx.Unscoped().Where("key IN ?", keys).Delete(&model.LoginThrottle{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/middleware"
+	"salon-app/backend/internal/model"
+)
+
+// @Summary      チケット調整
+// @Description  来店以外でチケットの回数を変更し、理由コード・メモ・操作者を調整履歴に記録します。
+// @Description  complimentary: sessions 回を無料で追加 / correction: 消化回数を sessions 回修正（マイナス可）/
+// @Description  refund: 未使用の sessions 回分を返金（省略時は残り全部、金額の省略時は返金済みを除いた支払額を按分。返金額は支払額 - 返金済みの額まで）/
+// @Description  reopen: 使い切り・期限切れのチケットを再開（sessions 回追加、expires_at で期限を延長）
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Ticket ID"
+// @Param        body body TicketAdjustmentRequest true "調整内容"
+// @Success      200 {object} map[string]interface{}
+// @Router       /ticket/{id}/adjustments [post]
+func AdjustTicketHandler(c *gin.Context) {
+    var req TicketAdjustmentRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    var ticket model.Ticket
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&ticket, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Ticket not found"})
+        return
+    }
+
+    now := time.Now()
+    adj := model.TicketAdjustment{
+        Kind:       req.Kind,
+        ReasonCode: req.ReasonCode,
+        Note:       req.Note,
+        UserID:     middleware.CurrentUserID(c),
+    }
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        return adjustTicket(tx, &ticket, &adj, ticketAdjustmentApplier(req, now))
+    })
+    var invalid errInvalidAdjustment
+    if errors.As(err, &invalid) {
+        c.JSON(400, gin.H{"error": invalid.msg})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to adjust ticket"})
+        return
+    }
+    c.JSON(200, gin.H{"ticket": newTicketView(ticket, now), "adjustment": adj})
+}
+
+// @Summary      チケット調整履歴
+// @Description  チケットの調整履歴を古い順に返します
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Ticket ID"
+// @Success      200 {object} model.TicketAdjustment
+// @Router       /ticket/{id}/adjustments [get]
+func GetTicketAdjustmentsHandler(c *gin.Context) {
+    var ticket model.Ticket
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&ticket, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Ticket not found"})
+        return
+    }
+    var adjustments []model.TicketAdjustment
+    if err := db.DB.Where("ticket_id = ?", ticket.ID).Preload("User").Order("created_at, id").
+        Find(&adjustments).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch adjustments"})
+        return
+    }
+    c.JSON(200, adjustments)
+}
+
+// @Summary      チケット譲渡
+// @Description  チケットの残り回数を別の顧客へ譲渡します。譲渡先には同じ内容（コース・有効期限）の新しいチケットを作成し、支払額（返金済みの額を除く）も回数で按分して移します
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Ticket ID"
+// @Param        body body TicketTransferRequest true "譲渡内容"
+// @Success      200 {object} map[string]interface{}
+// @Router       /ticket/{id}/transfer [post]
+func TransferTicketHandler(c *gin.Context) {
+    var req TicketTransferRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    var ticket model.Ticket
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&ticket, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Ticket not found"})
+        return
+    }
+    var to model.Customer
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&to, req.ToCustomerID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "譲渡先の顧客が見つかりません"})
+        return
+    }
+    if to.StoreID != ticket.StoreID || to.ID == ticket.CustomerID {
+        c.JSON(400, gin.H{"error": "譲渡先は同じ店舗の別の顧客を指定してください"})
+        return
+    }
+
+    now := time.Now()
+    userID := middleware.CurrentUserID(c)
+    adj := model.TicketAdjustment{
+        Kind:       model.AdjustTransfer,
+        ReasonCode: req.ReasonCode,
+        Note:       req.Note,
+        UserID:     userID,
+    }
+    var newTicket model.Ticket
+    var transfer model.TicketTransfer
+    err := db.DB.Transaction(func(tx *gorm.DB) error {
+        var sessions, amount int
+        err := adjustTicket(tx, &ticket, &adj, func(t *model.Ticket, _ *model.TicketAdjustment) string {
+            if t.Status(now) != model.TicketActive {
+                return "使い切り・期限切れのチケットは譲渡できません"
+            }
+            sessions = req.Sessions
+            if sessions == 0 {
+                sessions = t.Remaining()
+            }
+            if sessions < 1 || sessions > t.Remaining() {
+                return "譲渡する回数は1以上、残り回数以下で指定してください"
+            }
+            amount = t.SessionValue(sessions) // 返金済みの額を除いた1回あたりの額で按分する
+            t.TotalCount -= sessions
+            t.PricePaid -= amount
+            return ""
+        })
+        if err != nil {
+            return err
+        }
+
+        // 譲渡先のチケット（購入時の内容は譲渡元から引き継ぐ）
+        newTicket = model.Ticket{
+            CustomerID:        to.ID,
+            CourseID:          ticket.CourseID,
+            TotalCount:        sessions,
+            StoreID:           ticket.StoreID,
+            CourseName:        ticket.CourseName,
+            ListPrice:         ticket.ListPrice,
+            PricePaid:         amount,
+            PaymentMethod:     ticket.PaymentMethod,
+            PurchasedAt:       ticket.PurchasedAt,
+            SoldByID:          ticket.SoldByID,
+            ExpiresAt:         ticket.ExpiresAt,
+            TransferredFromID: &ticket.ID,
+        }
+        if err := tx.Create(&newTicket).Error; err != nil {
+            return err
+        }
+        transfer = model.TicketTransfer{
+            FromTicketID:   ticket.ID,
+            ToTicketID:     newTicket.ID,
+            FromCustomerID: ticket.CustomerID,
+            ToCustomerID:   to.ID,
+            StoreID:        ticket.StoreID,
+            Sessions:       sessions,
+            Amount:         amount,
+            ReasonCode:     req.ReasonCode,
+            Note:           req.Note,
+            UserID:         userID,
+        }
+        return tx.Omit(clause.Associations).Create(&transfer).Error
+    })
+    var invalid errInvalidAdjustment
+    if errors.As(err, &invalid) {
+        c.JSON(400, gin.H{"error": invalid.msg})
+        return
+    }
+    if err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to transfer ticket"})
+        return
+    }
+    c.JSON(200, gin.H{
+        "from_ticket": newTicketView(ticket, now),
+        "to_ticket":   newTicketView(newTicket, now),
+        "transfer":    transfer,
+    })
+}
+
+// @Summary      チケット共有メンバー追加
+// @Description  チケットを共有する顧客（家族など）を追加します。共有メンバーも来店登録でこのチケットを消化できます
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Ticket ID"
+// @Param        body body TicketMemberRequest true "追加する顧客"
+// @Success      200 {object} model.Ticket
+// @Router       /ticket/{id}/members [post]
+func AddTicketMemberHandler(c *gin.Context) {
+    var req TicketMemberRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.Error(err)
+        c.JSON(400, gin.H{"error": "入力が正しくありません"})
+        return
+    }
+    var ticket model.Ticket
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&ticket, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Ticket not found"})
+        return
+    }
+    var customer model.Customer
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&customer, req.CustomerID).Error; err != nil {
+        c.JSON(404, gin.H{"error": "顧客が見つかりません"})
+        return
+    }
+    if customer.StoreID != ticket.StoreID || customer.ID == ticket.CustomerID {
+        c.JSON(400, gin.H{"error": "共有メンバーは同じ店舗の別の顧客を指定してください"})
+        return
+    }
+    if err := db.DB.Model(&ticket).Omit("SharedWith.*").Association("SharedWith").Append(&customer); err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to add ticket member"})
+        return
+    }
+    respondTicketWithMembers(c, ticket.ID)
+}
+
+// @Summary      チケット共有メンバー削除
+// @Description  チケットの共有メンバーを外します
+// @Tags         ticket
+// @Accept       json
+// @Produce      json
+// @Param        id           path  int  true  "Ticket ID"
+// @Param        customer_id  path  int  true  "Customer ID"
+// @Success      200 {object} model.Ticket
+// @Router       /ticket/{id}/members/{customer_id} [delete]
+func RemoveTicketMemberHandler(c *gin.Context) {
+    var ticket model.Ticket
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&ticket, c.Param("id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "Ticket not found"})
+        return
+    }
+    var customer model.Customer
+    if err := db.DB.Scopes(middleware.StoreScope(c)).First(&customer, c.Param("customer_id")).Error; err != nil {
+        c.JSON(404, gin.H{"error": "顧客が見つかりません"})
+        return
+    }
+    if err := db.DB.Model(&ticket).Association("SharedWith").Delete(&customer); err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to remove ticket member"})
+        return
+    }
+    respondTicketWithMembers(c, ticket.ID)
+}
+
+// respondTicketWithMembers は共有メンバー付きのチケットを返す
+func respondTicketWithMembers(c *gin.Context, id uint) {
+    var ticket model.Ticket
+    if err := db.DB.Preload("Customer").Preload("SharedWith").First(&ticket, id).Error; err != nil {
+        c.Error(err)
+        c.JSON(500, gin.H{"error": "Failed to fetch ticket"})
+        return
+    }
+    c.JSON(200, newTicketView(ticket, time.Now()))
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
+)
+
+// TicketView はチケット一覧・詳細のレスポンス（残り回数と状態を付けて返す）
+type TicketView struct {
+	model.Ticket
+	Remaining int    `json:"remaining"`
+	Status    string `json:"status"` // active / completed / expired
+}
+
+// newTicketFromCourse はコースの内容（コース名・価格・回数・有効期間）を購入時点の値でコピーしたチケットを作る（保存はしない）。
+// PricePaid はコースの販売価格で初期化するので、値引きがあれば呼び出し側で上書きする。
+func newTicketFromCourse(customerID uint, course model.Course, purchasedAt time.Time, paymentMethod string, soldBy uint) model.Ticket {
+	ticket := model.Ticket{
+		CustomerID:    customerID,
+		CourseID:      course.ID,
+		TotalCount:    course.TotalCount,
+		StoreID:       course.StoreID,
+		CourseName:    course.Name,
+		ListPrice:     course.Price,
+		PricePaid:     course.Price,
+		PaymentMethod: paymentMethod,
+		PurchasedAt:   purchasedAt,
+	}
+	if soldBy != 0 {
+		ticket.SoldByID = &soldBy
+	}
+	if course.ValidityDays > 0 {
+		// 購入日を含めて ValidityDays 日間有効（最終日の翌日0時 JST に失効）
+		day := purchasedAt.In(utils.JST)
+		expiresAt := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, utils.JST).AddDate(0, 0, course.ValidityDays)
+		ticket.ExpiresAt = &expiresAt
+	}
+	return ticket
+}
+
+func newTicketView(t model.Ticket, now time.Time) TicketView {
+	return TicketView{Ticket: t, Remaining: t.Remaining(), Status: t.Status(now)}
+}
+
+// ticketStatusScope は model.Ticket.Status と同じ条件でチケットを絞り込む。不明な状態なら false。
+func ticketStatusScope(status string, now time.Time) (func(*gorm.DB) *gorm.DB, bool) {
+	switch status {
+	case model.TicketCompleted:
+		return func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("is_completed = ?", true)
+		}, true
+	case model.TicketExpired:
+		return func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("is_completed = ? AND (expired_at IS NOT NULL OR expires_at <= ?)", false, now)
+		}, true
+	case model.TicketActive:
+		return func(tx *gorm.DB) *gorm.DB {
+			return tx.Where("is_completed = ? AND expired_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", false, now)
+		}, true
+	}
+	return nil, false
+}
+
+// errInvalidAdjustment は調整内容がチケットの状態と合わない場合のエラー（メッセージはそのままレスポンスに使う）
+type errInvalidAdjustment struct{ msg string }
+
+func (e errInvalidAdjustment) Error() string { return e.msg }
+
+// adjustTicket はチケットの行をロックして apply で回数を変更し、調整履歴を記録する。
+// IsCompleted は apply の後に RecomputeCompletion で設定し直す。
+// apply が返したメッセージ、または回数の整合性エラーは errInvalidAdjustment として返す。
+func adjustTicket(tx *gorm.DB, ticket *model.Ticket, adj *model.TicketAdjustment, apply func(t *model.Ticket, adj *model.TicketAdjustment) string) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(ticket, ticket.ID).Error; err != nil {
+		return err
+	}
+	adj.TicketID = ticket.ID
+	adj.StoreID = ticket.StoreID
+	adj.TotalBefore, adj.CurrentBefore = ticket.TotalCount, ticket.CurrentCount
+
+	if msg := apply(ticket, adj); msg != "" {
+		return errInvalidAdjustment{msg}
+	}
+	if ticket.CurrentCount < 0 || ticket.TotalCount < 0 || ticket.CurrentCount > ticket.TotalCount {
+		return errInvalidAdjustment{"消化回数は0以上、規定回数以下である必要があります"}
+	}
+	ticket.RecomputeCompletion()
+
+	adj.TotalAfter, adj.CurrentAfter = ticket.TotalCount, ticket.CurrentCount
+	if err := tx.Omit(clause.Associations).Save(ticket).Error; err != nil {
+		return err
+	}
+	return tx.Omit(clause.Associations).Create(adj).Error
+}
+
+// ticketAdjustmentApplier は TicketAdjustmentRequest の kind ごとの回数の変更内容
+func ticketAdjustmentApplier(req TicketAdjustmentRequest, now time.Time) func(t *model.Ticket, adj *model.TicketAdjustment) string {
+	return func(t *model.Ticket, adj *model.TicketAdjustment) string {
+		switch req.Kind {
+		case model.AdjustComplimentary:
+			if req.Sessions < 1 {
+				return "追加する回数（sessions）を1以上で指定してください"
+			}
+			t.TotalCount += req.Sessions
+		case model.AdjustCorrection:
+			if req.Sessions == 0 {
+				return "修正する消化回数（sessions、マイナス可）を指定してください"
+			}
+			t.CurrentCount += req.Sessions
+		case model.AdjustRefund:
+			sessions := req.Sessions
+			if sessions == 0 {
+				sessions = t.Remaining() // 省略時は未使用分すべて
+			}
+			if sessions < 1 || sessions > t.Remaining() {
+				return "返金する回数は1以上、残り回数以下で指定してください"
+			}
+			amount := t.SessionValue(sessions)
+			if req.RefundAmount != nil {
+				amount = *req.RefundAmount
+			}
+			if amount > t.PricePaid-t.RefundedAmount {
+				return "返金額は支払額から返金済みの額を引いた額以下で指定してください"
+			}
+			t.TotalCount -= sessions
+			t.RefundedAmount += amount
+			adj.RefundAmount = amount
+			adj.RefundMethod = defaultString(req.RefundMethod, model.PaymentCash)
+		case model.AdjustReopen:
+			if t.Status(now) == model.TicketActive {
+				return "消化中のチケットは再開できません"
+			}
+			if req.Sessions < 0 {
+				return "追加する回数（sessions）は0以上で指定してください"
+			}
+			t.TotalCount += req.Sessions
+			if req.ExpiresAt != nil {
+				t.ExpiresAt = req.ExpiresAt
+			}
+			t.ExpiredAt, t.UnusedCount, t.UnusedAmount = nil, 0, 0
+			if t.Remaining() == 0 || t.IsExpired(now) {
+				return "再開後も残り回数があり、有効期限内である必要があります（sessions / expires_at を指定してください）"
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/model"
+)
+
+var (
+	errTicketNotFound = errors.New("ticket not found")
+	errTicketUsedUp   = errors.New("ticket already used up")
+	errTicketExpired  = errors.New("ticket expired")
+)
+
+// registerVisit は来店記録を作成し、visit.TicketID で指定された回数券を1回分消化する。
+// TicketID が nil の場合は都度払いとして来店記録だけを作成する。
+// 来店登録（VisitRegistrationHandler）と予約の完了処理で共通のロジック。
+// 必ずトランザクション内で呼ぶこと。
+// visit.VisitCount は実際に消化した回数（何回目か）で上書きする。
+func registerVisit(tx *gorm.DB, visit *model.Visit) error {
+	count, err := consumeTicket(tx, visit)
+	if err != nil {
+		return err
+	}
+	//　来店記録の作成（消化した回数をスナップショットとして保存）
+	visit.VisitCount = count
+	return tx.Create(visit).Error
+}
+
+// consumeTicket は visit.TicketID の回数券を1回分消化し、何回目の消化かを返す（TicketID が nil なら 0）。
+// 購入者本人のほか、チケットの共有メンバー（Ticket.SharedWith）も消化できる。
+// チケットの行をロックしてから消化するため、同じチケットへの来店登録が同時に来ても二重消化は起きない。
+func consumeTicket(tx *gorm.DB, visit *model.Visit) (int, error) {
+	if visit.TicketID == nil {
+		return 0, nil
+	}
+
+	// チケットの検索（コース・店舗が一致し、本人のチケットか共有メンバーになっているもののみ）
+	var ticket model.Ticket
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("course_id = ? AND store_id = ?", visit.CourseID, visit.StoreID).
+		Where("customer_id = ? OR id IN (SELECT ticket_id FROM ticket_members WHERE customer_id = ?)", visit.CustomerID, visit.CustomerID).
+		First(&ticket, *visit.TicketID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errTicketNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if ticket.IsCompleted || ticket.CurrentCount >= ticket.TotalCount {
+		return 0, errTicketUsedUp
+	}
+	if ticket.IsExpired(time.Now()) {
+		return 0, errTicketExpired
+	}
+
+	// チケットの更新
+	ticket.CurrentCount += 1
+	ticket.RecomputeCompletion()
+	// 今回の＋1で規定回数に達したかチェック
+	if ticket.IsCompleted {
+		log.Printf("チケットを使い切りました！ (ID: %d, Count: %d/%d)",
+			ticket.ID, ticket.CurrentCount, ticket.TotalCount)
+	}
+	if err := tx.Omit(clause.Associations).Save(&ticket).Error; err != nil {
+		log.Printf("チケットの更新に失敗: %v", err)
+		return 0, err
+	}
+	return ticket.CurrentCount, nil
+}
+
+// releaseTicket は visit が消化した回数券の1回分を戻す（取消・付け替え用）。
+// 使い切りになっていたチケットは IsCompleted が外れる。TicketID が nil なら何もしない。
+// 同じチケットで visit より後に消化した来店の VisitCount（何回目か）は1つずつ繰り上げる。
+func releaseTicket(tx *gorm.DB, visit model.Visit) error {
+	if visit.TicketID == nil {
+		return nil
+	}
+	var ticket model.Ticket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticket, *visit.TicketID).Error; err != nil {
+		return err
+	}
+	ticket.CurrentCount = max(ticket.CurrentCount-1, 0)
+	ticket.RecomputeCompletion()
+	if err := tx.Omit(clause.Associations).Save(&ticket).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.Visit{}).
+		Where("ticket_id = ? AND id <> ? AND voided_at IS NULL AND visit_count > ?", ticket.ID, visit.ID, visit.VisitCount).
+		UpdateColumn("visit_count", gorm.Expr("visit_count - 1")).Error
+}
+
+// placeMovedVisit は付け替えで consumeTicket した visit を、付け替え先のチケットの来店日時（created_at）の順に並べ直す。
+// consumeTicket は最後の回として数えるので、visit より後に来店した分の VisitCount を1つずつ繰り下げ、
+// visit をその前の回にする（releaseTicket で元のチケットを繰り上げるのと対になる）。TicketID が nil なら何もしない。
+func placeMovedVisit(tx *gorm.DB, visit *model.Visit) error {
+	if visit.TicketID == nil {
+		return nil
+	}
+	res := tx.Model(&model.Visit{}).
+		Where("ticket_id = ? AND id <> ? AND voided_at IS NULL", *visit.TicketID, visit.ID).
+		Where("created_at > ? OR (created_at = ? AND id > ?)", visit.CreatedAt, visit.CreatedAt, visit.ID).
+		UpdateColumn("visit_count", gorm.Expr("visit_count + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	visit.VisitCount -= int(res.RowsAffected)
+	return nil
+}
+
+// ticketErrorResponse は registerVisit のチケット関連のエラーをレスポンスに変換する。該当しなければ false。
+func ticketErrorResponse(err error) (int, string, bool) {
+	switch {
+	case errors.Is(err, errTicketNotFound):
+		return 400, "指定された回数券が見つかりません（顧客・コース・店舗が一致しないか、共有メンバーではありません）", true
+	case errors.Is(err, errTicketUsedUp):
+		return 409, "指定された回数券は使い切っています", true
+	case errors.Is(err, errTicketExpired):
+		return 409, "指定された回数券は有効期限が切れています", true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// openTestDB は TEST_DATABASE_DSN の PostgreSQL に接続して db.DB に設定する。
+// 行ロックの動作を確かめるので実際の PostgreSQL が必要（未設定ならスキップ）。
+//
+//	TEST_DATABASE_DSN="host=localhost user=postgres password=postgres dbname=salon_test sslmode=disable" go test ./internal/handler
+func openTestDB(t *testing.T) {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN が未設定のためスキップ")
+	}
+	g, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.AutoMigrate(&model.Store{}, &model.Customer{}, &model.Course{}, &model.Ticket{}, &model.Visit{},
+		&model.TicketAdjustment{}, &model.TicketTransfer{},
+		&model.Sale{}, &model.SaleLine{}, &model.SalePayment{}, &model.SaleTax{},
+		&model.RegisterSession{}, &model.RegisterMovement{}); err != nil {
+		t.Fatal(err)
+	}
+	prev := db.DB
+	db.DB = g
+	t.Cleanup(func() { db.DB = prev })
+}
+
+// TestRegisterVisitConcurrentConsumption は同じチケットへの来店登録を同時に送っても
+// 規定回数を超えて消化されず、各来店の VisitCount（何回目か）が重複しないことを確認する。
+func TestRegisterVisitConcurrentConsumption(t *testing.T) {
+	openTestDB(t)
+
+	const totalCount, attempts = 5, 20
+	store := model.Store{Name: "test store"}
+	if err := db.DB.Create(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+	customer := model.Customer{LastName: "山田", FirstName: "花子", StoreID: store.ID}
+	course := model.Course{Name: "5回券", Price: 50000, TotalCount: totalCount, StoreID: store.ID}
+	if err := db.DB.Create(&customer).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.Create(&course).Error; err != nil {
+		t.Fatal(err)
+	}
+	ticket := newTicketFromCourse(customer.ID, course, time.Now(), model.PaymentCash, 0)
+	if err := db.DB.Create(&ticket).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.DB.Unscoped().Where("store_id = ?", store.ID).Delete(&model.Visit{})
+		db.DB.Unscoped().Delete(&ticket)
+		db.DB.Unscoped().Delete(&course)
+		db.DB.Unscoped().Delete(&customer)
+		db.DB.Unscoped().Delete(&store)
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, attempts)
+	start := make(chan struct{})
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			errs <- db.DB.Transaction(func(tx *gorm.DB) error {
+				visit := model.Visit{CustomerID: customer.ID, CourseID: course.ID, TicketID: &ticket.ID, StoreID: store.ID}
+				return registerVisit(tx, &visit)
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, errTicketUsedUp):
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != totalCount {
+		t.Errorf("succeeded = %d, want %d", succeeded, totalCount)
+	}
+
+	var got model.Ticket
+	if err := db.DB.First(&got, ticket.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.CurrentCount != totalCount || !got.IsCompleted {
+		t.Errorf("ticket current_count = %d, is_completed = %v, want %d, true", got.CurrentCount, got.IsCompleted, totalCount)
+	}
+
+	var counts []int
+	if err := db.DB.Model(&model.Visit{}).Where("ticket_id = ?", ticket.ID).Pluck("visit_count", &counts).Error; err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(counts)
+	if len(counts) != totalCount {
+		t.Fatalf("visits = %v, want %d visits", counts, totalCount)
+	}
+	for i, n := range counts {
+		if n != i+1 {
+			t.Errorf("visit_count = %v, want 1..%d without duplicates", counts, totalCount)
+			break
+		}
+	}
+}
+
+// TestUpdateVisitMoveRenumbers は過去の来店を別のチケットへ付け替えると、元のチケットの後の来店は繰り上がり、
+// 付け替え先では来店日時の順に何回目か（VisitCount）が振り直されることを確認する。
+func TestUpdateVisitMoveRenumbers(t *testing.T) {
+	openTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	store := model.Store{Name: "test store"}
+	if err := db.DB.Create(&store).Error; err != nil {
+		t.Fatal(err)
+	}
+	customer := model.Customer{LastName: "山田", FirstName: "花子", StoreID: store.ID}
+	course := model.Course{Name: "5回券", Price: 50000, TotalCount: 5, StoreID: store.ID}
+	if err := db.DB.Create(&customer).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.Create(&course).Error; err != nil {
+		t.Fatal(err)
+	}
+	tickets := []model.Ticket{
+		newTicketFromCourse(customer.ID, course, time.Now(), model.PaymentCash, 0),
+		newTicketFromCourse(customer.ID, course, time.Now(), model.PaymentCash, 0),
+	}
+	if err := db.DB.Create(&tickets).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.DB.Unscoped().Where("store_id = ?", store.ID).Delete(&model.Visit{})
+		db.DB.Unscoped().Delete(&tickets)
+		db.DB.Unscoped().Delete(&course)
+		db.DB.Unscoped().Delete(&customer)
+		db.DB.Unscoped().Delete(&store)
+	})
+
+	// 1日おきに A, B, A, B, A, B の順で来店（A: 1〜3回目、B: 1〜3回目）
+	base := time.Now().AddDate(0, 0, -10)
+	visits := make([]model.Visit, 6)
+	for i := range visits {
+		visits[i] = model.Visit{CustomerID: customer.ID, CourseID: course.ID, TicketID: &tickets[i%2].ID, StoreID: store.ID}
+		visits[i].CreatedAt = base.AddDate(0, 0, i)
+		if err := db.DB.Transaction(func(tx *gorm.DB) error { return registerVisit(tx, &visits[i]) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A の2回目（3日目の来店）を B へ付け替える
+	moved := visits[2]
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("role", model.RoleAdmin)
+	c.Params = gin.Params{{Key: "id", Value: strconv.Itoa(int(moved.ID))}}
+	body := fmt.Sprintf(`{"customer_id":%d,"course_id":%d,"ticket_id":%d,"store_id":%d}`, customer.ID, course.ID, tickets[1].ID, store.ID)
+	c.Request = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	UpdateVisitHandler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want 200", w.Code, w.Body.String())
+	}
+
+	want := map[uint][]uint{
+		tickets[0].ID: {visits[0].ID, visits[4].ID},
+		tickets[1].ID: {visits[1].ID, moved.ID, visits[3].ID, visits[5].ID},
+	}
+	for ticketID, ids := range want {
+		var got []model.Visit
+		if err := db.DB.Where("ticket_id = ?", ticketID).Order("created_at, id").Find(&got).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(ids) {
+			t.Fatalf("ticket %d: %d visits, want %d", ticketID, len(got), len(ids))
+		}
+		for i, v := range got {
+			if v.ID != ids[i] || v.VisitCount != i+1 {
+				t.Errorf("ticket %d: visit %d = id %d (visit_count %d), want id %d (visit_count %d)", ticketID, i, v.ID, v.VisitCount, ids[i], i+1)
+			}
+		}
+		var ticket model.Ticket
+		if err := db.DB.First(&ticket, ticketID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if ticket.CurrentCount != len(ids) {
+			t.Errorf("ticket %d: current_count = %d, want %d", ticketID, ticket.CurrentCount, len(ids))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package job
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// expireBatchSize は1回のトランザクションで処理するチケット数
+const expireBatchSize = 500
+
+// StartTicketExpiry は有効期限を過ぎたチケットの期限切れ処理を interval ごとに実行する。
+// 起動直後に1回実行し、その後はバックグラウンドで繰り返す。
+// 複数台で動かしても、処理中の行は SKIP LOCKED で飛ばすため二重には処理されない。
+func StartTicketExpiry(interval time.Duration) {
+	go func() {
+		for {
+			if n, err := ExpireTickets(time.Now()); err != nil {
+				log.Printf("チケットの期限切れ処理に失敗: %v", err)
+			} else if n > 0 {
+				log.Printf("チケットの期限切れ処理: %d件", n)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// ExpireTickets は now の時点で有効期限を過ぎた未処理のチケットに期限切れを記録し、件数を返す。
+// 未使用の残回数と、その金額（支払額を回数で按分、1円未満切り捨て）をレポート用に保存する。
+func ExpireTickets(now time.Time) (int, error) {
+	total := 0
+	for {
+		var n int
+		err := db.DB.Transaction(func(tx *gorm.DB) error {
+			var tickets []model.Ticket
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("expires_at <= ? AND expired_at IS NULL AND is_completed = ?", now, false).
+				Limit(expireBatchSize).Find(&tickets).Error; err != nil {
+				return err
+			}
+			for _, t := range tickets {
+				unused := max(t.TotalCount-t.CurrentCount, 0)
+				amount := 0
+				if t.TotalCount > 0 {
+					amount = t.PricePaid * unused / t.TotalCount
+				}
+				if err := tx.Model(&model.Ticket{}).Where("id = ?", t.ID).Updates(map[string]interface{}{
+					"expired_at":    now,
+					"unused_count":  unused,
+					"unused_amount": amount,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			n = len(tickets)
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < expireBatchSize {
+			return total, nil
+		}
+	}
+}
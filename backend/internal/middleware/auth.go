@@ -3,14 +3,28 @@ package middleware
 import (
 	"fmt"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+	"salon-app/backend/internal/utils"
 )
 
+// AuthRequired は通常のアクセストークンを要求する
 func AuthRequired() gin.HandlerFunc {
+	return authenticate("access")
+}
+
+// EnrollmentAuthRequired は二段階認証の登録用トークンも受け付ける（/2fa 以下のみで使う）
+func EnrollmentAuthRequired() gin.HandlerFunc {
+	return authenticate("access", "mfa_enroll")
+}
+
+// authenticate は allowed に含まれる種別(typ)のトークンだけを受け付ける
+func authenticate(allowed ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. HeaderからAuthorizationを取得
 		authHeader := c.GetHeader("Authorization")
@@ -30,32 +44,51 @@ func AuthRequired() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		// 3. トークンの検証
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// 署名アルゴリズムの確認
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			// 環境変数などから秘密鍵を取得（GenerateToken時と同じ鍵）
-			return []byte(os.Getenv("JWT_SECRET_KEY")), nil
-		})
-
-		// 4. エラーチェックとClaimsの取得
-		if err != nil || !token.Valid {
+		// 3. トークンの検証（署名・有効期限）
+		claims, err := utils.ParseToken(tokenString)
+
+		// 4. エラーチェック（許可された種別以外のトークンは受け付けない）
+		typ, _ := claims["typ"].(string)
+		if err != nil || !slices.Contains(allowed, typ) {
 			fmt.Printf("Auth Error for %s: %v\n", c.Request.URL.Path, err)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "有効なトークンではありません"})
 			c.Abort()
 			return
 		}
 
-		// 5. 中身（Claims）をContextに保存（後続のHandlerで使うため）
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			// これで各Handlerで c.Get("user_id") のように取り出せる
-			c.Set("user_id", claims["user_id"])
-			c.Set("role", claims["role"])
-			c.Set("store_id", claims["store_id"])
+		// 5. 失効チェック（ログアウト済み / 全セッション無効化済み / 削除済みユーザー）
+		if revoked(claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "セッションが無効になりました。再度ログインしてください"})
+			c.Abort()
+			return
 		}
 
+		// 6. 中身（Claims）をContextに保存（後続のHandlerで使うため）
+		// これで各Handlerで c.Get("user_id") のように取り出せる
+		c.Set("user_id", claims["user_id"])
+		c.Set("role", claims["role"])
+		c.Set("store_id", claims["store_id"])
+		c.Set("jti", claims["jti"])
+		c.Set("exp", claims["exp"])
+		c.Set("token_type", typ)
+
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// revoked はトークンがサーバー側で失効させられているかを確認する
+func revoked(claims map[string]interface{}) bool {
+	jti, _ := claims["jti"].(string)
+	var count int64
+	if err := db.DB.Model(&model.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil || count > 0 {
+		return true
+	}
+
+	userID, _ := claims["user_id"].(float64)
+	ver, _ := claims["ver"].(float64)
+	var user model.User
+	if err := db.DB.Select("id", "token_version").First(&user, uint(userID)).Error; err != nil {
+		return true
+	}
+	return user.TokenVersion != int(ver)
+}
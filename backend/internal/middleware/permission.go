@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"salon-app/backend/internal/permission"
+)
+
+// RequirePermission はルートに必要な権限を宣言するミドルウェア。
+// ログインユーザーのロールが権限を持っていなければ 403 を返す。
+//
+//	v1.DELETE("/customer/:id", middleware.RequirePermission(permission.CustomerDelete), handler.DeleteCustomerHandler)
+func RequirePermission(p permission.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !permission.Has(CurrentRole(c), p) {
+			Forbidden(c, p)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Forbidden は権限不足時の共通レスポンスを返して処理を中断する
+func Forbidden(c *gin.Context, p permission.Permission) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":      "この操作を行う権限がありません",
+		"permission": p,
+	})
+}
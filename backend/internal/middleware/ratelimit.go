@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRateLimitClients は1つの RateLimit が覚えておくクライアント数の上限。
+// 超えたら最も長く使われていないクライアントから忘れる（忘れたクライアントは満タンのバケットからやり直し）。
+const maxRateLimitClients = 10000
+
+// bucket はクライアント1件分のトークンバケット
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// limiter はクライアントごとのバケットを、最後に使われた順の LRU で持つ
+type limiter struct {
+	mu         sync.Mutex
+	rate       float64 // 1秒あたりの補充数
+	burst      float64
+	idle       time.Duration // これだけ使われなければバケットは満タンに戻っているので捨ててよい
+	maxClients int
+	order      *list.List // 先頭ほど最近使われた *bucket
+	buckets    map[string]*list.Element
+}
+
+func newLimiter(perMinute, burst, maxClients int) *limiter {
+	rate := float64(perMinute) / 60
+	return &limiter{
+		rate:       rate,
+		burst:      float64(burst),
+		idle:       time.Duration(float64(burst) / rate * float64(time.Second)),
+		maxClients: maxClients,
+		order:      list.New(),
+		buckets:    map[string]*list.Element{},
+	}
+}
+
+// take は key のトークンを1つ使う。使えなければ false と、次に使えるまでの秒数を返す
+func (l *limiter) take(key string, now time.Time) (bool, float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 満タンに戻ったバケットを古い方から捨てる（古い順に並んでいるので、まだ新しいものに当たれば止める）
+	for e := l.order.Back(); e != nil && now.Sub(e.Value.(*bucket).last) > l.idle; e = l.order.Back() {
+		l.remove(e)
+	}
+
+	var b *bucket
+	if e, ok := l.buckets[key]; ok {
+		b = e.Value.(*bucket)
+		l.order.MoveToFront(e)
+	} else {
+		if l.order.Len() >= l.maxClients {
+			l.remove(l.order.Back())
+		}
+		b = &bucket{key: key, tokens: l.burst, last: now}
+		l.buckets[key] = l.order.PushFront(b)
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false, (1 - b.tokens) / l.rate
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *limiter) remove(e *list.Element) {
+	delete(l.buckets, e.Value.(*bucket).key)
+	l.order.Remove(e)
+}
+
+// RateLimit はクライアントIPごとのリクエスト数を制限するミドルウェア（トークンバケット方式）。
+// perMinute は1分あたりの補充数、burst は連続で受け付ける最大数。
+// クライアントIPは信用するプロキシ（TRUSTED_PROXIES）経由でなければ接続元のアドレス。
+// 状態はプロセス内のメモリに持つため、複数台構成では台ごとの制限になる。
+func RateLimit(perMinute, burst int) gin.HandlerFunc {
+	l := newLimiter(perMinute, burst, maxRateLimitClients)
+	return func(c *gin.Context) {
+		allowed, wait := l.take(c.ClientIP(), time.Now())
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "リクエストが多すぎます。しばらくしてから再度お試しください"})
+			return
+		}
+		c.Next()
+	}
+}
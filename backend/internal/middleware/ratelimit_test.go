@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLimiterTake(t *testing.T) {
+	l := newLimiter(60, 3, 100) // 1秒に1回補充、連続3回まで
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.take("a", now); !ok {
+			t.Fatalf("request %d rejected, want allowed within burst", i+1)
+		}
+	}
+	ok, wait := l.take("a", now)
+	if ok || wait != 1 {
+		t.Errorf("4th request = %v (wait %v), want rejected with wait 1s", ok, wait)
+	}
+	if ok, _ := l.take("b", now); !ok {
+		t.Error("another client rejected, want a separate bucket")
+	}
+	if ok, _ := l.take("a", now.Add(time.Second)); !ok {
+		t.Error("request after refill rejected")
+	}
+}
+
+// TestLimiterBounded はクライアントが増えても覚えておく数が上限を超えず、
+// 使われなくなったバケットが捨てられることを確認する。
+func TestLimiterBounded(t *testing.T) {
+	l := newLimiter(60, 3, 100)
+	now := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 1000; i++ {
+		l.take(strconv.Itoa(i), now)
+	}
+	if n := len(l.buckets); n != 100 || l.order.Len() != 100 {
+		t.Fatalf("clients = %d (list %d), want capped at 100", n, l.order.Len())
+	}
+	if _, ok := l.buckets["0"]; ok {
+		t.Error("oldest client still tracked, want evicted first")
+	}
+	if _, ok := l.buckets["999"]; !ok {
+		t.Error("newest client evicted")
+	}
+
+	// 満タンに戻る時間（3秒）を過ぎれば、次のリクエストで古いバケットは捨てられる
+	l.take("new", now.Add(4*time.Second))
+	if n := len(l.buckets); n != 1 {
+		t.Errorf("clients after idle = %d, want 1", n)
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"salon-app/backend/internal/model"
+)
+
+// tenantStoreKey は操作対象の店舗IDをContextに保存する際のキー
+const tenantStoreKey = "tenant_store_id"
+
+// TenantScope はJWTの role / store_id から操作対象の店舗を決定し、Contextに保存する。
+// AuthRequired の後ろで使うこと。
+//   - staff / manager: 常に所属店舗に固定される
+//   - admin: X-Store-ID ヘッダ、または store_id クエリで店舗を明示的に選べる（未指定なら全店舗）
+func TenantScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if CurrentRole(c) == model.RoleAdmin {
+			selected := c.GetHeader("X-Store-ID")
+			if selected == "" {
+				selected = c.Query("store_id")
+			}
+			var storeID uint
+			if selected != "" {
+				id, err := strconv.ParseUint(selected, 10, 64)
+				if err != nil || id == 0 {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "店舗IDの指定が正しくありません"})
+					c.Abort()
+					return
+				}
+				storeID = uint(id)
+			}
+			c.Set(tenantStoreKey, storeID)
+			c.Next()
+			return
+		}
+
+		storeID := claimUint(c, "store_id")
+		if storeID == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "所属店舗が設定されていません"})
+			c.Abort()
+			return
+		}
+		c.Set(tenantStoreKey, storeID)
+		c.Next()
+	}
+}
+
+// CurrentRole はログインユーザーのロールを返す
+func CurrentRole(c *gin.Context) string {
+	role, _ := c.Get("role")
+	roleStr, _ := role.(string)
+	return roleStr
+}
+
+// CurrentUserID はログインユーザーのIDを返す
+func CurrentUserID(c *gin.Context) uint {
+	return claimUint(c, "user_id")
+}
+
+// TenantStoreID は操作対象の店舗IDを返す。0 は「全店舗」(adminが店舗を選択していない状態)を表す。
+func TenantStoreID(c *gin.Context) uint {
+	v, _ := c.Get(tenantStoreKey)
+	id, _ := v.(uint)
+	return id
+}
+
+// CanAccessStore は指定した店舗のデータを操作できるかを返す
+func CanAccessStore(c *gin.Context, storeID uint) bool {
+	scoped := TenantStoreID(c)
+	if scoped == 0 {
+		// 店舗を選択していない admin のみここに来る
+		return CurrentRole(c) == model.RoleAdmin
+	}
+	return scoped == storeID
+}
+
+// StoreScope は store_id カラムで絞り込む GORM の Scope を返す。
+// 例: db.DB.Scopes(middleware.StoreScope(c)).Find(&customers)
+func StoreScope(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return StoreScopeColumn(c, "store_id")
+}
+
+// StoreScopeColumn は任意のカラム名で店舗を絞り込む Scope を返す（JOIN時や stores.id 用）
+func StoreScopeColumn(c *gin.Context, column string) func(*gorm.DB) *gorm.DB {
+	storeID := TenantStoreID(c)
+	return func(tx *gorm.DB) *gorm.DB {
+		if storeID == 0 {
+			return tx
+		}
+		return tx.Where(column+" = ?", storeID)
+	}
+}
+
+// claimUint はJWTのClaims（JSONの数値なのでfloat64）を uint に変換して取り出す
+func claimUint(c *gin.Context, key string) uint {
+	v, ok := c.Get(key)
+	if !ok {
+		return 0
+	}
+	switch n := v.(type) {
+	case float64:
+		return uint(n)
+	case uint:
+		return n
+	case int:
+		return uint(n)
+	}
+	return 0
+}
@@ -1,9 +1,11 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
+	"salon-app/backend/internal/utils"
 )
 
 //gorm.Modelは以下の定義を含んでいる
@@ -22,12 +24,107 @@ type User struct {
     Role     string `json:"role" gorm:"default:customer"` 
     StoreID  uint   `json:"store_id"`
     Store    Store  `json:"store" gorm:"foreignKey:StoreID"`//外部から呼び出し
+    // TokenVersion:
+    // アクセストークンの "ver" と一致しない場合は認証エラーになる。
+    // インクリメントすることで、発行済みの全セッションを無効化できる。
+    TokenVersion int `json:"-" gorm:"not null;default:0"`
+
+    // 二段階認証 (TOTP)
+    // TOTPSecret はセットアップ時に保存し、コード確認後に TOTPEnabled を true にする。
+    TOTPSecret   string `json:"-" gorm:"size:64"`
+    TOTPEnabled  bool   `json:"totp_enabled" gorm:"not null;default:false"`
+    TOTPLastStep int64  `json:"-"` // 最後に使われたタイムステップ（同じコードの再利用防止）
 }
 
+// ロール定義
+// admin は全店舗を横断して操作でき、manager / staff は所属店舗(StoreID)のデータのみ扱える。
+const (
+    RoleAdmin   = "admin"
+    RoleManager = "manager"
+    RoleStaff   = "staff"
+)
+
 type Store struct {
     gorm.Model 
     Name     string `json:"name"` //店舗名
-} //user.Store.Nameで呼び出せる
+    Capacity int    `json:"capacity"` // 同時に施術できる席数。予約の重複チェックに使う (0 は無制限)
+
+    // 営業時間（空き枠検索に使用）
+    OpenTime        string `json:"open_time" gorm:"size:5;default:10:00"`  // 開店時刻 "HH:MM"
+    CloseTime       string `json:"close_time" gorm:"size:5;default:19:00"` // 閉店時刻 "HH:MM"
+    SlotMinutes     int    `json:"slot_minutes" gorm:"default:30"`         // 予約開始時刻の刻み（分）
+    RegularHolidays string `json:"regular_holidays" gorm:"size:20"`        // 定休日の曜日。0=日〜6=土 をカンマ区切り (例: "1,2")
+
+    // 消費税・インボイス（適格請求書）の設定
+    InvoiceNumber   string `json:"invoice_number" gorm:"size:14"`                    // 適格請求書発行事業者の登録番号 ("T" + 13桁)
+    PriceTaxMode    string `json:"price_tax_mode" gorm:"size:10;default:inclusive"` // 価格の表示方法 (TaxInclusive / TaxExclusive)
+    TaxRounding     string `json:"tax_rounding" gorm:"size:10;default:floor"`       // 消費税の端数処理 (RoundFloor / RoundHalfUp / RoundCeil)。税率ごとに1会計1回
+    StandardTaxRate int    `json:"standard_tax_rate" gorm:"default:10"`             // 標準税率 (%)
+    ReducedTaxRate  int    `json:"reduced_tax_rate" gorm:"default:8"`               // 軽減税率 (%)
+
+    // 顧客の離反分析（RFM）のしきい値
+    RetentionLoyalVisits    int `json:"retention_loyal_visits" gorm:"default:5"`       // この回数以上来店していれば優良 (loyal)
+    RetentionAtRiskDays     int `json:"retention_at_risk_days" gorm:"default:60"`      // 最終来店からこの日数が経つと離反予備軍 (at_risk)
+    RetentionLapsedDays     int `json:"retention_lapsed_days" gorm:"default:180"`      // 最終来店からこの日数が経つと離反 (lapsed)
+    RetentionOverduePercent int `json:"retention_overdue_percent" gorm:"default:150"`  // いつもの来店間隔のこの割合(%)を過ぎると来店遅れ
+}
+
+// 顧客の離反分析（RFM）のセグメント
+const (
+    SegmentLoyal  = "loyal"   // 来店回数が多く、最近も来ている
+    SegmentActive = "active"  // 来店中（優良の基準には届かない）
+    SegmentNew    = "new"     // 来店1回のみで、離反の基準に達していない
+    SegmentAtRisk = "at_risk" // 来店が途絶えかけている（期間の経過、またはいつもの間隔を過ぎた）
+    SegmentLapsed = "lapsed"  // 長期間来店がない
+)
+
+// 価格の表示方法
+const (
+    TaxInclusive = "inclusive" // 税込価格（価格に消費税を含む）
+    TaxExclusive = "exclusive" // 税抜価格（会計時に消費税を加算する）
+)
+
+// 消費税の端数処理
+const (
+    RoundFloor  = "floor" // 切り捨て
+    RoundHalfUp = "round" // 四捨五入
+    RoundCeil   = "ceil"  // 切り上げ
+)
+
+// 税率区分
+const (
+    TaxStandard = "standard" // 標準税率
+    TaxReduced  = "reduced"  // 軽減税率（飲食料品など）
+)
+
+// TaxRate は税率区分に対応する店舗の税率 (%) を返す
+func (s Store) TaxRate(category string) int {
+    if category == TaxReduced {
+        return s.ReducedTaxRate
+    }
+    return s.StandardTaxRate
+}
+
+// StoreHoliday (臨時休業日)
+// 定休日以外の休業日（年末年始・臨時休業など）。
+type StoreHoliday struct {
+    gorm.Model
+    StoreID uint      `json:"store_id" gorm:"uniqueIndex:idx_store_holiday"`
+    Date    time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_store_holiday"`
+    Reason  string    `json:"reason" gorm:"size:100"`
+}
+
+// StaffShift (スタッフの勤務シフト)
+// 1日1件。空き枠はシフトが登録されているスタッフ・時間帯からのみ計算する。
+type StaffShift struct {
+    gorm.Model
+    UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_staff_shift"`
+    StoreID   uint      `json:"store_id" gorm:"index"`
+    Date      time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_staff_shift"`
+    StartTime string    `json:"start_time" gorm:"size:5"` // "HH:MM"
+    EndTime   string    `json:"end_time" gorm:"size:5"`   // "HH:MM"
+    User      User      `json:"user" gorm:"foreignKey:UserID"`
+}
 
 type Customer struct {
     gorm.Model
@@ -47,23 +144,75 @@ type Customer struct {
     Sex       string    `json:"sex" gorm:"size:10"`
     BirthDate time.Time `json:"birth_date" gorm:"type:date"` // 日付型がおすすめ
     Phone     string    `json:"phone" gorm:"size:20"`      // uintからstringへ変更
+    Email     string    `json:"email" gorm:"size:255"`
+
+    // オンライン予約で既存顧客と照合できなかった場合に作成される仮登録の顧客
+    IsProvisional bool `json:"is_provisional" gorm:"not null;default:false"`
+
+    // 検索用に正規化したカラム（保存時に BeforeSave で作り直す。pg_trgm のインデックスは db.MigrateCustomerSearch）
+    SearchName  string `json:"-" gorm:"size:255;not null;default:''"` // 氏名と氏名カナ（カタカナ・空白なし）を空白でつないだもの
+    SearchPhone string `json:"-" gorm:"size:20;not null;default:''"`  // 電話番号の数字のみ
+    SearchEmail string `json:"-" gorm:"size:255;not null;default:''"` // 小文字のメールアドレス
 
     // 店舗紐付け
     StoreID uint  `json:"store_id"`
     Store   Store `json:"store" gorm:"foreignKey:StoreID"`
 }
 
+// RefreshSearchColumns は検索用のカラムを氏名・電話番号・メールアドレスから作り直す
+func (cu *Customer) RefreshSearchColumns() {
+    name := utils.NormalizeSearchText(cu.LastName + cu.FirstName)
+    kana := utils.NormalizeSearchText(cu.LastNameKana + cu.FirstNameKana)
+    cu.SearchName = strings.TrimSpace(name + " " + kana)
+    cu.SearchPhone = utils.PhoneDigits(cu.Phone)
+    cu.SearchEmail = utils.NormalizeSearchText(cu.Email)
+}
+
+// BeforeSave は作成・更新のたびに検索用のカラムをそろえる
+func (cu *Customer) BeforeSave(tx *gorm.DB) error {
+    cu.RefreshSearchColumns()
+    return nil
+}
+
+// PostalCode (郵便番号)
+// 日本郵便の KEN_ALL.CSV から取り込んだ郵便番号と住所（取り込みは cmd/import-postal で全件を入れ替える）。
+// 1つの郵便番号に複数の町域がある場合は町域ごとに1行。
+type PostalCode struct {
+    ID       uint   `json:"-" gorm:"primaryKey"`
+    ZipCode  string `json:"zip_code" gorm:"size:7;not null;index"` // ハイフンなしの7桁
+    PrefName string `json:"pref_name" gorm:"size:20;not null"`     // 都道府県（Customer.PrefName と同じ表記）
+    City     string `json:"city" gorm:"size:100;not null"`         // 市区町村
+    Town     string `json:"town" gorm:"size:255;not null"`         // 町域。「以下に掲載がない場合」などは空
+    PrefKana string `json:"pref_kana" gorm:"size:50"`
+    CityKana string `json:"city_kana" gorm:"size:100"`
+    TownKana string `json:"town_kana" gorm:"size:255"`
+}
+
 // Course (コースマスタ)
 // 店舗が提供するサービスのマスターデータ。単発メニューやセット回数券の基本情報を定義。
 type Course struct {
     gorm.Model
     Name       string `json:"name"`        // コース名称 (例: "全身脱毛 5回パック", "カット")
-    Price      int    `json:"price"`       // 販売価格 (税込み/税抜きは運用に合わせる)
+    Price      int    `json:"price"`       // 販売価格。税込か税抜かは店舗の PriceTaxMode に従う
+    TaxCategory string `json:"tax_category" gorm:"size:10;default:standard"` // 税率区分 (TaxStandard / TaxReduced)
     TotalCount int    `json:"total_count"` // 規定回数。単発は1、回数券は5や10などを設定
+    DurationMinutes int `json:"duration_minutes"` // 施術時間（分）。予約の終了時刻・空き枠の計算に使う
+    BufferMinutes   int `json:"buffer_minutes"`   // 施術後の片付け・準備時間（分）。この間は次の予約を入れない
+    OnlineBookable  bool `json:"online_bookable"` // オンライン予約（/api/public）で選択できるか
+    ValidityDays    int  `json:"validity_days"`   // 回数券の有効期間（購入日から何日間）。0 なら無期限
     StoreID    uint   `json:"store_id"`    // 所属店舗ID。多店舗展開時に使用
     Store      Store  `json:"store" gorm:"foreignKey:StoreID"` // 店舗情報へのリレーション
 }
 
+// 支払方法
+const (
+    PaymentCash  = "cash"  // 現金
+    PaymentCard  = "card"  // クレジットカード
+    PaymentQR    = "qr"    // QRコード決済
+    PaymentOther = "other" // その他（銀行振込など）
+    PaymentSplit = "split" // 複数の支払方法の併用（売上の Payments に内訳がある）
+)
+
 // Ticket (顧客保有チケット)
 // 顧客が購入した回数券の現在の消化状況を管理する。残数管理の核となるテーブル。
 type Ticket struct {
@@ -74,11 +223,139 @@ type Ticket struct {
     TotalCount   int    `json:"total_count"`   // 購入時の最大回数 (Courseマスタからコピー)
     IsCompleted  bool   `json:"is_completed"`  // 全回数を使い切ったかどうかのフラグ
     StoreID      uint   `json:"store_id"`      // 購入・発行した店舗のID
+
+    // 購入時のコースのスナップショット（後からコースを変更・削除しても購入内容は変わらない）
+    CourseName    string    `json:"course_name" gorm:"size:100"` // 購入時のコース名
+    ListPrice     int       `json:"list_price"`                  // 購入時のコースの販売価格
+    PricePaid     int       `json:"price_paid"`                  // 実際に支払った金額（値引き後）
+    PaymentMethod string    `json:"payment_method" gorm:"size:20"` // 支払方法 (Payment* 定数のいずれか)
+    PurchasedAt   time.Time `json:"purchased_at"`                // 購入日時
+    SoldByID      *uint     `json:"sold_by_id"`                  // 販売したスタッフ(User)のID
+
+    // 有効期限。ExpiresAt を過ぎたチケットは消化できない。nil なら無期限
+    ExpiresAt    *time.Time `json:"expires_at"`
+    // 期限切れ処理（job.ExpireTickets）の結果。未使用の残回数と、その金額（支払額を回数で按分）をレポート用に記録する
+    ExpiredAt    *time.Time `json:"expired_at"`
+    UnusedCount  int        `json:"unused_count"`
+    UnusedAmount int        `json:"unused_amount"`
+    RefundedAmount int      `json:"refunded_amount"` // 返金済みの金額の合計（調整の refund）
+
+    // 家族などで共有する場合の利用者。購入者(CustomerID)に加えて、ここに登録された顧客も消化できる
+    SharedWith   []Customer `json:"shared_with" gorm:"many2many:ticket_members"`
+    // 譲渡で作成されたチケットの場合、譲渡元のチケットID
+    TransferredFromID *uint `json:"transferred_from_id"`
+
     // リレーション
     Customer     Customer `json:"customer" gorm:"foreignKey:CustomerID"`
     Course       Course   `json:"course" gorm:"foreignKey:CourseID"`
 }
 
+// IsExpired は now の時点でチケットが有効期限を過ぎているかを返す
+func (t Ticket) IsExpired(now time.Time) bool {
+    return t.ExpiredAt != nil || (t.ExpiresAt != nil && !now.Before(*t.ExpiresAt))
+}
+
+// チケットの状態（一覧の絞り込み・表示用。DBには保存しない）
+const (
+    TicketActive    = "active"    // 消化中
+    TicketCompleted = "completed" // 使い切り
+    TicketExpired   = "expired"   // 有効期限切れ
+)
+
+// Remaining は残り回数を返す
+func (t Ticket) Remaining() int {
+    return max(t.TotalCount-t.CurrentCount, 0)
+}
+
+// SessionValue は sessions 回分の金額（返金済みを除いた支払額を規定回数で按分、1円未満切り捨て）を返す。
+// 返金で規定回数と返金済みの額が一緒に減るので、何回に分けて返金しても合計は支払額を超えない。
+func (t Ticket) SessionValue(sessions int) int {
+    if t.TotalCount <= 0 {
+        return 0
+    }
+    return (t.PricePaid - t.RefundedAmount) * sessions / t.TotalCount
+}
+
+// Status は now の時点でのチケットの状態を返す（使い切りを期限切れより優先する）
+func (t Ticket) Status(now time.Time) string {
+    switch {
+    case t.IsCompleted:
+        return TicketCompleted
+    case t.IsExpired(now):
+        return TicketExpired
+    }
+    return TicketActive
+}
+
+// RecomputeCompletion は消化回数と規定回数から IsCompleted を設定し直す。
+// 回数を変更する処理（来店登録・調整・取消）は必ずこれを通して IsCompleted を更新する。
+// 全回数を返金・譲渡して規定回数が0になったチケットも、残り回数がないので使い切り（終了）とする。
+func (t *Ticket) RecomputeCompletion() {
+    t.IsCompleted = t.CurrentCount >= t.TotalCount
+}
+
+// チケット調整の種類
+const (
+    AdjustComplimentary = "complimentary" // サービスで回数を追加
+    AdjustCorrection    = "correction"    // 消化回数・規定回数の修正
+    AdjustRefund        = "refund"        // 未使用分の返金（規定回数を減らす）
+    AdjustReopen        = "reopen"        // 使い切り・期限切れのチケットを再開
+    AdjustTransfer      = "transfer"      // 残り回数を別の顧客へ譲渡（TicketTransfer にも記録する）
+)
+
+// チケット調整の理由コード
+const (
+    ReasonApology         = "apology"          // お詫び・クレーム対応
+    ReasonCampaign        = "campaign"         // キャンペーン・特典
+    ReasonInputError      = "input_error"      // 入力ミスの修正
+    ReasonCustomerRequest = "customer_request" // お客様都合（解約・返金など）
+    ReasonMigration       = "migration"        // 旧システムからの移行
+    ReasonOther           = "other"            // その他（Note に詳細を記入）
+)
+
+// TicketAdjustment (チケット調整履歴)
+// 来店以外でチケットの回数を変更した記録。誰が・なぜ・どれだけ変更したかを残す。
+type TicketAdjustment struct {
+    gorm.Model
+    TicketID      uint   `json:"ticket_id" gorm:"index"`
+    StoreID       uint   `json:"store_id"`
+    Kind          string `json:"kind" gorm:"size:20"`        // Adjust* 定数のいずれか
+    ReasonCode    string `json:"reason_code" gorm:"size:30"` // Reason* 定数のいずれか
+    Note          string `json:"note" gorm:"size:500"`
+    UserID        uint   `json:"user_id"` // 操作したスタッフ(User)のID
+    // 変更前後の回数（差分ではなく両方を保存しておく）
+    TotalBefore   int `json:"total_before"`
+    TotalAfter    int `json:"total_after"`
+    CurrentBefore int `json:"current_before"`
+    CurrentAfter  int `json:"current_after"`
+    RefundAmount  int `json:"refund_amount"` // 返金額（refund のみ）
+    RefundMethod  string `json:"refund_method" gorm:"size:20"` // 返金方法（refund のみ。Payment* 定数のいずれか）
+
+    // リレーション
+    User          User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// TicketTransfer (チケット譲渡履歴)
+// 残り回数の一部または全部を別の顧客へ移した記録。譲渡先には新しいチケットを作成する。
+// 譲渡元・譲渡先どちらの顧客の履歴にも表示する。
+type TicketTransfer struct {
+    gorm.Model
+    FromTicketID   uint   `json:"from_ticket_id" gorm:"index"`
+    ToTicketID     uint   `json:"to_ticket_id" gorm:"index"`
+    FromCustomerID uint   `json:"from_customer_id" gorm:"index"`
+    ToCustomerID   uint   `json:"to_customer_id" gorm:"index"`
+    StoreID        uint   `json:"store_id"`
+    Sessions       int    `json:"sessions"` // 譲渡した回数
+    Amount         int    `json:"amount"`   // 譲渡先へ移した支払額（按分）
+    ReasonCode     string `json:"reason_code" gorm:"size:30"`
+    Note           string `json:"note" gorm:"size:500"`
+    UserID         uint   `json:"user_id"` // 操作したスタッフ(User)のID
+
+    // リレーション
+    FromCustomer   Customer `json:"from_customer" gorm:"foreignKey:FromCustomerID"`
+    ToCustomer     Customer `json:"to_customer" gorm:"foreignKey:ToCustomerID"`
+}
+
 // Visit (来店記録)
 // 実際の日々の施術・来店記録。チケット消化との紐付けも行う。
 type Visit struct {
@@ -94,13 +371,273 @@ type Visit struct {
     // VisitCount:
     // この来店がそのチケットにとって「何回目」だったかをスナップショットとして記録。
     // チケット残数計算の履歴の整合性を保つために保持。
+    // 前の来店を取り消し・付け替えた場合は、後の来店の番号を繰り上げる（releaseTicket）。
     VisitCount int    `json:"visit_count"`  
     
     StoreID    uint   `json:"store_id"`     // 来店した店舗のID
+    StaffID    *uint  `json:"staff_id"`     // 担当スタッフ(User)のID。未指定の場合は null
     Memo       string `json:"memo" gorm:"size:500"` // 施術内容や顧客の反応などのメモ
+
+    // IdempotencyKey:
+    // 来店登録APIの Idempotency-Key ヘッダーの値。同じキーで再送されても二重に登録・消化しない。
+    IdempotencyKey *string `json:"-" gorm:"size:100;uniqueIndex"`
+
+    // 取消（void）。取り消した来店記録も削除せずに残し、消化したチケットの回数は戻す
+    VoidedAt   *time.Time `json:"voided_at"`
+    VoidReason string     `json:"void_reason" gorm:"size:500"`
+    VoidedByID *uint      `json:"voided_by_id"` // 取り消したスタッフ(User)のID
     
     // リレーション
     Customer   Customer `json:"customer" gorm:"foreignKey:CustomerID"`
     Course     Course   `json:"course" gorm:"foreignKey:CourseID"`
     Ticket     *Ticket  `json:"ticket" gorm:"foreignKey:TicketID"`
-}
\ No newline at end of file
+    Store      Store    `json:"store" gorm:"foreignKey:StoreID"`
+}
+
+// RefreshToken (リフレッシュトークン)
+// 平文はクライアントにのみ返し、DBにはハッシュを保存する。使用のたびに新しいトークンへローテーションする。
+type RefreshToken struct {
+    gorm.Model
+    UserID       uint       `json:"user_id" gorm:"index;not null"`
+    TokenHash    string     `json:"-" gorm:"size:64;uniqueIndex;not null"` // SHA-256
+    ExpiresAt    time.Time  `json:"expires_at"`
+    RevokedAt    *time.Time `json:"revoked_at"`     // ログアウト・ローテーション・強制失効で設定
+    ReplacedByID *uint      `json:"replaced_by_id"` // ローテーション後の新しいトークン
+}
+
+// RevokedToken (失効済みアクセストークン)
+// ログアウトしたアクセストークンの jti を有効期限まで保持する。
+type RevokedToken struct {
+    gorm.Model
+    JTI       string    `json:"jti" gorm:"size:64;uniqueIndex;not null"`
+    ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
+
+// LoginThrottle (ログイン失敗回数)
+// アカウント(メールアドレス)単位・IP単位でログイン失敗を数え、一定回数を超えたらロックする。
+// Key は "account:<email>" または "ip:<address>"。
+type LoginThrottle struct {
+    gorm.Model
+    Key          string     `json:"key" gorm:"size:320;uniqueIndex;not null"`
+    Failures     int        `json:"failures"`       // 連続失敗回数
+    LastFailedAt time.Time  `json:"last_failed_at"`
+    LockedUntil  *time.Time `json:"locked_until"`   // この時刻まではログイン不可
+}
+
+// RecoveryCode (二段階認証のリカバリーコード)
+// 認証アプリを紛失した場合に一度だけ使えるコード。ハッシュのみ保存する。
+type RecoveryCode struct {
+    gorm.Model
+    UserID   uint       `json:"user_id" gorm:"index;not null"`
+    CodeHash string     `json:"-" gorm:"size:64;uniqueIndex;not null"` // SHA-256
+    UsedAt   *time.Time `json:"used_at"`
+}
+
+// 予約ステータス
+const (
+    ReservationBooked    = "booked"    // 予約受付
+    ReservationConfirmed = "confirmed" // 予約確定（前日確認済みなど）
+    ReservationArrived   = "arrived"   // 来店済み・施術待ち
+    ReservationCompleted = "completed" // 施術完了（来店記録を作成済み）
+    ReservationCancelled = "cancelled" // キャンセル
+    ReservationNoShow    = "no_show"   // 無断キャンセル
+)
+
+// ReservationTransitions は各ステータスから遷移できるステータスの一覧
+// completed / cancelled / no_show は終端で、以降は変更できない。
+var ReservationTransitions = map[string][]string{
+    ReservationBooked:    {ReservationConfirmed, ReservationArrived, ReservationCompleted, ReservationCancelled, ReservationNoShow},
+    ReservationConfirmed: {ReservationArrived, ReservationCompleted, ReservationCancelled, ReservationNoShow},
+    ReservationArrived:   {ReservationCompleted, ReservationCancelled},
+}
+
+// ReservationActiveStatuses は枠を占有しているステータス（重複チェックの対象）
+var ReservationActiveStatuses = []string{ReservationBooked, ReservationConfirmed, ReservationArrived}
+
+// Reservation (予約)
+// 将来の来店予約。施術完了時に Visit を作成し、チケットを消化する。
+type Reservation struct {
+    gorm.Model
+    CustomerID uint      `json:"customer_id" gorm:"index"`
+    CourseID   uint      `json:"course_id"`
+    StoreID    uint      `json:"store_id" gorm:"index"`
+    StaffID    uint      `json:"staff_id" gorm:"index"` // 担当スタッフ(User)
+    StartAt    time.Time `json:"start_at" gorm:"index"`
+    EndAt      time.Time `json:"end_at"`
+    BufferMinutes int    `json:"buffer_minutes"` // 予約時のコースの準備時間。EndAt からこの分数も枠を占有する
+    Status     string    `json:"status" gorm:"size:20;not null;default:booked;index"`
+    Memo       string    `json:"memo" gorm:"size:500"`
+    Source     string    `json:"source" gorm:"size:20;not null;default:staff"` // staff: 店頭・電話, online: オンライン予約
+
+    // ConfirmationTokenHash:
+    // オンライン予約の確認・キャンセル・変更リンク用トークンのハッシュ（平文は予約者にのみ返す）
+    ConfirmationTokenHash *string `json:"-" gorm:"size:64;uniqueIndex"`
+
+    VisitID     *uint      `json:"visit_id"`     // 完了時に作成した来店記録
+    CancelledAt *time.Time `json:"cancelled_at"` // キャンセル・無断キャンセルの記録日時
+
+    // リレーション
+    Customer Customer `json:"customer" gorm:"foreignKey:CustomerID"`
+    Course   Course   `json:"course" gorm:"foreignKey:CourseID"`
+    Store    Store    `json:"store" gorm:"foreignKey:StoreID"`
+    Staff    User     `json:"staff" gorm:"foreignKey:StaffID"`
+    Visit    *Visit   `json:"visit,omitempty" gorm:"foreignKey:VisitID"`
+}
+
+// BlockedUntil は枠を占有する終了時刻（施術終了 + 準備時間）を返す
+func (r Reservation) BlockedUntil() time.Time {
+    return r.EndAt.Add(time.Duration(r.BufferMinutes) * time.Minute)
+}
+
+// CanTransitionTo は現在のステータスから next へ変更できるかを返す
+func (r Reservation) CanTransitionTo(next string) bool {
+    for _, s := range ReservationTransitions[r.Status] {
+        if s == next {
+            return true
+        }
+    }
+    return false
+}
+
+// 売上明細の種類
+const (
+    SaleLineTicket    = "ticket"    // 回数券の販売
+    SaleLineTreatment = "treatment" // 施術（都度払い）
+    SaleLineRetail    = "retail"    // 物販
+)
+
+// 売上ステータス
+const (
+    SaleCompleted = "completed"
+    SaleVoided    = "voided"
+)
+
+// Sale (売上)
+// レジでの会計1回分。明細(SaleLine)と支払(SalePayment)を持つ。
+// 金額は Subtotal（明細の合計）- DiscountAmount（会計全体の値引き）で、税抜価格の店舗ではこれに TaxTotal を加えたものが Total。
+// 支払の合計は Total と一致する。
+type Sale struct {
+    gorm.Model
+    StoreID        uint      `json:"store_id" gorm:"index"`
+    CustomerID     *uint     `json:"customer_id"` // 顧客が特定できない物販のみの会計では null
+    UserID         uint      `json:"user_id"`     // 会計したスタッフ(User)のID
+    SoldAt         time.Time `json:"sold_at" gorm:"index"`
+    Subtotal       int       `json:"subtotal"`
+    DiscountAmount int       `json:"discount_amount"`
+    Total          int       `json:"total"`
+    PaymentMethod  string    `json:"payment_method" gorm:"size:20"` // 支払方法が1つならその方法、複数なら split
+    TaxMode        string    `json:"tax_mode" gorm:"size:10"` // 会計時の店舗の価格の表示方法（税込 / 税抜）
+    TaxTotal       int       `json:"tax_total"`               // 消費税額の合計（税込価格の場合は内税）
+    Status         string    `json:"status" gorm:"size:20;default:completed"`
+    Memo           string    `json:"memo" gorm:"size:500"`
+
+    // 領収書の記載内容のスナップショット（後から店舗名・顧客名が変わっても同じ領収書を再発行できる）
+    IssuerName    string `json:"issuer_name" gorm:"size:100"`
+    InvoiceNumber string `json:"invoice_number" gorm:"size:14"`
+    CustomerName  string `json:"customer_name" gorm:"size:101"`
+
+    // 取消（void）。取り消した売上も削除せずに残す
+    VoidedAt   *time.Time `json:"voided_at"`
+    VoidReason string     `json:"void_reason" gorm:"size:500"`
+    VoidedByID *uint      `json:"voided_by_id"`
+
+    // リレーション
+    Lines    []SaleLine    `json:"lines"`
+    Payments []SalePayment `json:"payments"`
+    Taxes    []SaleTax     `json:"taxes"`
+    Customer *Customer     `json:"customer,omitempty" gorm:"foreignKey:CustomerID"`
+}
+
+// SaleLine (売上明細)
+// Amount = UnitPrice × Quantity - DiscountAmount
+type SaleLine struct {
+    gorm.Model
+    SaleID         uint   `json:"sale_id" gorm:"index"`
+    Kind           string `json:"kind" gorm:"size:20"` // SaleLine* 定数のいずれか
+    Description    string `json:"description" gorm:"size:100"`
+    CourseID       *uint  `json:"course_id"`
+    TicketID       *uint  `json:"ticket_id" gorm:"index"` // 回数券の販売の場合、販売したチケット
+    IssuedBySale   bool   `json:"issued_by_sale"`         // TicketID のチケットがこの会計で発行されたものか（取消時に削除するのはこのチケットだけ）
+    VisitID        *uint  `json:"visit_id" gorm:"index"`  // 施術の場合、支払対象の来店記録
+    UnitPrice      int    `json:"unit_price"`
+    Quantity       int    `json:"quantity"`
+    DiscountAmount int    `json:"discount_amount"`
+    Amount         int    `json:"amount"`
+    TicketRemaining *int  `json:"ticket_remaining"` // 会計時点のチケットの残り回数（領収書に記載する。回数券・回数券利用の施術のみ）
+    TaxCategory    string `json:"tax_category" gorm:"size:10"` // 税率区分 (TaxStandard / TaxReduced)
+    TaxRate        int    `json:"tax_rate"`                    // 適用税率 (%)
+}
+
+// SalePayment (支払)
+// 1つの売上に複数あれば併用払い（例: 現金 + カード）
+type SalePayment struct {
+    gorm.Model
+    SaleID uint   `json:"sale_id" gorm:"index"`
+    Method string `json:"method" gorm:"size:20"` // Payment* 定数のいずれか（split 以外）
+    Amount int    `json:"amount"`
+}
+
+// SaleTax (税率ごとの消費税)
+// 適格請求書の記載事項である「税率ごとに区分した対価の額と消費税額」。会計時に計算して保存する。
+// Taxable は会計全体の値引きを按分した後の金額で、税込価格の店舗では税込、税抜価格の店舗では税抜の額。
+type SaleTax struct {
+    gorm.Model
+    SaleID  uint `json:"sale_id" gorm:"index"`
+    Rate    int  `json:"rate"`
+    Taxable int  `json:"taxable"`
+    Tax     int  `json:"tax"`
+}
+
+// レジセッションの状態
+const (
+    RegisterOpen   = "open"
+    RegisterClosed = "closed"
+)
+
+// レジの入出金の種類
+const (
+    RegisterCashIn  = "in"  // 入金（両替用の補充など）
+    RegisterCashOut = "out" // 出金（小口の支払・銀行への預け入れなど）
+)
+
+// RegisterSession (レジセッション)
+// 店舗ごとのレジの開局から締めまで。開局時の釣銭準備金と入出金、期間中の現金の売上・返金から
+// 理論上の現金残高（ExpectedCash）を計算し、締めで数えた実際の現金（CountedCash）との差額を残す。
+// 締めた後は内容を変更できない（集計値も締めの時点で保存し、後から売上を取り消しても変わらない）。
+type RegisterSession struct {
+    gorm.Model
+    StoreID      uint      `json:"store_id" gorm:"index"`
+    Status       string    `json:"status" gorm:"size:10;default:open"`
+    OpenedAt     time.Time `json:"opened_at"`
+    OpenedByID   uint      `json:"opened_by_id"`
+    OpeningFloat int       `json:"opening_float"` // 釣銭準備金
+
+    ClosedAt    *time.Time `json:"closed_at"`
+    ClosedByID  *uint      `json:"closed_by_id"`
+    CountedCash *int       `json:"counted_cash"` // 締めで数えた現金
+
+    // 集計（開いている間は照会のたびに計算し、締めで確定して保存する）
+    CashSales       int `json:"cash_sales"`        // 期間中の会計の現金支払
+    CashTicketSales int `json:"cash_ticket_sales"` // 期間中に会計を通さず登録した回数券（POST /tickets）の現金の支払額
+    CashVoids       int `json:"cash_voids"`        // 期間中に取り消した売上の現金支払（返した現金）
+    CashRefunds     int `json:"cash_refunds"`      // 期間中のチケット返金のうち現金の分
+    CashIn          int `json:"cash_in"`           // 入金の合計
+    CashOut         int `json:"cash_out"`          // 出金の合計
+    ExpectedCash    int `json:"expected_cash"`     // OpeningFloat + CashSales + CashTicketSales - CashVoids - CashRefunds + CashIn - CashOut
+    Variance        int `json:"variance"`          // CountedCash - ExpectedCash（過不足）
+    Note         string `json:"note" gorm:"size:500"`
+
+    // リレーション
+    Movements []RegisterMovement `json:"movements" gorm:"foreignKey:SessionID"`
+}
+
+// RegisterMovement (レジの入出金)
+// 売上以外でレジの現金を出し入れした記録
+type RegisterMovement struct {
+    gorm.Model
+    SessionID uint   `json:"session_id" gorm:"index"`
+    Kind      string `json:"kind" gorm:"size:10"` // RegisterCashIn / RegisterCashOut
+    Amount    int    `json:"amount"`
+    Reason    string `json:"reason" gorm:"size:200"`
+    UserID    uint   `json:"user_id"` // 操作したスタッフ(User)のID
+}
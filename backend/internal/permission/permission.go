@@ -0,0 +1,115 @@
+package permission
+
+import "salon-app/backend/internal/model"
+
+// Permission は操作単位の権限名（"リソース.操作" 形式）
+type Permission string
+
+const (
+	StoreRead   Permission = "store.read"   // 店舗一覧の参照
+	StoreManage Permission = "store.manage" // 店舗の登録・更新
+
+	CustomerRead   Permission = "customer.read"
+	CustomerWrite  Permission = "customer.write"
+	CustomerDelete Permission = "customer.delete"
+
+	CourseRead   Permission = "course.read"
+	CourseWrite  Permission = "course.write"
+	CourseDelete Permission = "course.delete"
+
+	VisitRead  Permission = "visit.read"
+	VisitWrite Permission = "visit.write"
+
+	TicketRead  Permission = "ticket.read"
+	TicketSell  Permission = "ticket.sell"  // 回数券の販売（受付で行う）
+	TicketWrite Permission = "ticket.write" // 回数・状態の修正
+
+	SaleRead  Permission = "sale.read"
+	SaleWrite Permission = "sale.write" // 会計（売上の登録）
+	SaleVoid  Permission = "sale.void"  // 売上の取消
+
+	RegisterRead    Permission = "register.read"    // レジセッション・締めレポートの参照
+	RegisterOperate Permission = "register.operate" // レジの開局・入出金
+	RegisterClose   Permission = "register.close"   // レジ締め
+
+	AnalyticsRead Permission = "analytics.read" // 売上・来店などの集計（ダッシュボード）
+
+	ReservationRead  Permission = "reservation.read"
+	ReservationWrite Permission = "reservation.write"
+
+	ScheduleRead   Permission = "schedule.read"   // シフト・休業日の参照
+	ScheduleManage Permission = "schedule.manage" // シフト・休業日・営業時間の管理
+
+	UserRead     Permission = "user.read"     // スタッフ一覧の参照
+	UserManage   Permission = "user.manage"   // スタッフの登録・更新
+	UserSecurity Permission = "user.security" // セッションの強制失効などアカウントの保護操作
+)
+
+// matrix はロールごとに許可される権限の一覧
+var matrix = map[string][]Permission{
+	model.RoleAdmin: {
+		StoreRead, StoreManage,
+		CustomerRead, CustomerWrite, CustomerDelete,
+		CourseRead, CourseWrite, CourseDelete,
+		VisitRead, VisitWrite,
+		TicketRead, TicketSell, TicketWrite,
+		SaleRead, SaleWrite, SaleVoid,
+		RegisterRead, RegisterOperate, RegisterClose,
+		AnalyticsRead,
+		ReservationRead, ReservationWrite,
+		ScheduleRead, ScheduleManage,
+		UserRead, UserManage, UserSecurity,
+	},
+	model.RoleManager: {
+		StoreRead,
+		CustomerRead, CustomerWrite, CustomerDelete,
+		CourseRead, CourseWrite, CourseDelete,
+		VisitRead, VisitWrite,
+		TicketRead, TicketSell, TicketWrite,
+		SaleRead, SaleWrite, SaleVoid,
+		RegisterRead, RegisterOperate, RegisterClose,
+		AnalyticsRead,
+		ReservationRead, ReservationWrite,
+		ScheduleRead, ScheduleManage,
+		UserRead, UserManage,
+	},
+	model.RoleStaff: {
+		StoreRead,
+		CustomerRead, CustomerWrite,
+		CourseRead,
+		VisitRead, VisitWrite,
+		TicketRead, TicketSell,
+		SaleRead, SaleWrite,
+		RegisterRead, RegisterOperate,
+		ReservationRead, ReservationWrite,
+		ScheduleRead,
+		UserRead,
+	},
+}
+
+// assignable はロールごとに、スタッフ登録・更新で付与できるロールの一覧
+// （自分より上位のロールは付与できない）
+var assignable = map[string][]string{
+	model.RoleAdmin:   {model.RoleAdmin, model.RoleManager, model.RoleStaff},
+	model.RoleManager: {model.RoleManager, model.RoleStaff},
+}
+
+// Has はロールが権限を持っているかを返す
+func Has(role string, p Permission) bool {
+	for _, granted := range matrix[role] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAssignRole は actor のロールで target のロールを付与（または変更）できるかを返す
+func CanAssignRole(actor, target string) bool {
+	for _, r := range assignable[actor] {
+		if r == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package postal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"salon-app/backend/internal/model"
+)
+
+// KEN_ALL.CSV（日本郵便の「読み仮名データの促音・拗音を小書きで表記するもの」、Shift_JIS）の列
+const (
+	colZip      = 2
+	colPrefKana = 3
+	colCityKana = 4
+	colTownKana = 5
+	colPref     = 6
+	colCity     = 7
+	colTown     = 8
+	kenAllCols  = 15
+)
+
+// importBatchSize は1回の INSERT で入れる行数（7列 × 1000 でプレースホルダーの上限に収まる）
+const importBatchSize = 1000
+
+// ParseKenAll は KEN_ALL.CSV を読み込み、郵便番号と町域ごとの行にする。
+//
+//   - 町域が長く複数行に分かれている行（「（」で始まり「）」で閉じるまで）は1行にまとめる
+//   - 「（１～１９丁目）」のような括弧内の番地・補足は取り除く（同じ郵便番号で同じ町域になった行は1つにする）
+//   - 「以下に掲載がない場合」「○○の次に番地がくる場合」「○○一円」は町域を空にする
+//   - 半角カナの読みは全角にする
+func ParseKenAll(r io.Reader) ([]model.PostalCode, error) {
+	cr := csv.NewReader(transform.NewReader(r, japanese.ShiftJIS.NewDecoder()))
+	cr.FieldsPerRecord = kenAllCols
+
+	var rows []model.PostalCode
+	seen := map[string]bool{}
+	var pending []string // 町域が閉じていない行
+	line := 0
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("KEN_ALL %d行目: %w", line, err)
+		}
+
+		if pending != nil {
+			if rec[colZip] != pending[colZip] {
+				return nil, fmt.Errorf("KEN_ALL %d行目: 前の行の町域が閉じていません（%s）", line, pending[colTown])
+			}
+			pending[colTown] += rec[colTown]
+			pending[colTownKana] += rec[colTownKana]
+			rec = pending
+			pending = nil
+		}
+		if strings.Contains(rec[colTown], "（") && !strings.Contains(rec[colTown], "）") {
+			pending = rec
+			continue
+		}
+
+		zip := rec[colZip]
+		if len(zip) != 7 {
+			return nil, fmt.Errorf("KEN_ALL %d行目: 郵便番号が正しくありません（%s）", line, zip)
+		}
+		town, townKana := cleanTown(rec[colTown], norm.NFKC.String(rec[colTownKana]))
+		key := zip + "\x00" + rec[colCity] + "\x00" + town
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		rows = append(rows, model.PostalCode{
+			ZipCode:  zip,
+			PrefName: rec[colPref],
+			City:     rec[colCity],
+			Town:     town,
+			PrefKana: norm.NFKC.String(rec[colPrefKana]),
+			CityKana: norm.NFKC.String(rec[colCityKana]),
+			TownKana: townKana,
+		})
+	}
+	if pending != nil {
+		return nil, fmt.Errorf("KEN_ALL: 最後の行の町域が閉じていません（%s）", pending[colTown])
+	}
+	return rows, nil
+}
+
+// cleanTown は町域と読みから住所の入力補完に使わない部分を取り除く
+func cleanTown(town, kana string) (string, string) {
+	switch {
+	case town == "以下に掲載がない場合",
+		strings.HasSuffix(town, "の次に番地がくる場合"),
+		strings.HasSuffix(town, "一円") && town != "一円":
+		return "", ""
+	}
+	if i := strings.Index(town, "（"); i >= 0 {
+		town = town[:i]
+	}
+	if i := strings.Index(kana, "("); i >= 0 {
+		kana = kana[:i]
+	}
+	return town, kana
+}
+
+// Replace は郵便番号のデータを rows で全件入れ替える（1つのトランザクションで行うので、途中で失敗しても元のデータが残る）
+func Replace(db *gorm.DB, rows []model.PostalCode) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&model.PostalCode{}).Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(rows, importBatchSize).Error
+	})
+}
@@ -0,0 +1,49 @@
+// Package postal は郵便番号から住所を引く（データは日本郵便の KEN_ALL.CSV を取り込んだ postal_codes テーブル）。
+package postal
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+	"salon-app/backend/internal/db"
+	"salon-app/backend/internal/model"
+)
+
+// NormalizeZip は郵便番号を7桁の数字にそろえる（全角数字・ハイフン・〒・空白を許す）。7桁にならなければ ok は false
+func NormalizeZip(s string) (zip string, ok bool) {
+	zip = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == '-' || r == '〒' || r == ' ' || r == 'ー' || r == '−':
+			return -1
+		}
+		return 'x'
+	}, norm.NFKC.String(strings.TrimSpace(s)))
+	if len(zip) != 7 || strings.Contains(zip, "x") {
+		return "", false
+	}
+	return zip, true
+}
+
+// Lookup は郵便番号（7桁）の住所を町域ごとに返す。見つからなければ空
+func Lookup(zip string) ([]model.PostalCode, error) {
+	var rows []model.PostalCode
+	err := db.DB.Where("zip_code = ?", zip).Order("id").Find(&rows).Error
+	return rows, err
+}
+
+// PrefMatches は郵便番号の住所に pref の都道府県が含まれるかを返す。
+// 郵便番号がデータにない場合（事業所の個別番号やデータを取り込む前）は判定できないので known は false。
+func PrefMatches(zip, pref string) (match bool, known bool, err error) {
+	rows, err := Lookup(zip)
+	if err != nil || len(rows) == 0 {
+		return false, false, err
+	}
+	for _, r := range rows {
+		if r.PrefName == pref {
+			return true, true, nil
+		}
+	}
+	return false, true, nil
+}
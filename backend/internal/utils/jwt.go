@@ -1,21 +1,119 @@
 package utils
 
 import (
-    "time"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/base64"
+    "encoding/hex"
+    "fmt"
     "os"
+    "time"
+
     "github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(userID uint, userName string, role string, storeID uint, storeName string) (string, error) {
-    claims := jwt.MapClaims{
+const (
+    AccessTokenTTL   = 15 * time.Minute    // アクセストークンの有効期限（短め）
+    RefreshTokenTTL  = 30 * 24 * time.Hour // リフレッシュトークンの有効期限
+    MFAChallengeTTL  = 5 * time.Minute     // 二段階認証コード入力までの猶予
+    MFAEnrollmentTTL = 10 * time.Minute    // 二段階認証の登録用トークン
+)
+
+// GenerateToken はアクセストークンを発行します。
+// tokenVersion は User.TokenVersion の値で、全セッション無効化時にインクリメントされる。
+func GenerateToken(userID uint, userName string, role string, storeID uint, storeName string, tokenVersion int) (string, error) {
+    return signToken("access", AccessTokenTTL, jwt.MapClaims{
+        "user_id": userID,
+        "user_name": userName,
+        "role":    role,
+        "store_id": storeID,
+        "store_name": storeName,
+        "ver":     tokenVersion,
+    })
+}
+
+// GenerateEnrollmentToken は二段階認証の登録専用トークンを発行します。
+// 二段階認証が必須のロールで未登録の場合にログインで返し、/2fa 以下のAPIにしか使えない。
+func GenerateEnrollmentToken(userID uint, userName string, role string, storeID uint, storeName string, tokenVersion int) (string, error) {
+    return signToken("mfa_enroll", MFAEnrollmentTTL, jwt.MapClaims{
         "user_id": userID,
         "user_name": userName,
         "role":    role,
         "store_id": storeID,
         "store_name": storeName,
-        "exp":     time.Now().Add(time.Hour * 24).Unix(), // 24時間有効
+        "ver":     tokenVersion,
+    })
+}
+
+// GenerateChallengeToken はパスワード認証済み・二段階認証待ちであることを示すトークンを発行します
+func GenerateChallengeToken(userID uint, tokenVersion int) (string, error) {
+    return signToken("mfa_challenge", MFAChallengeTTL, jwt.MapClaims{
+        "user_id": userID,
+        "ver":     tokenVersion,
+    })
+}
+
+// signToken は共通のClaims（種別・jti・発行/有効期限）を付けて署名します
+func signToken(typ string, ttl time.Duration, claims jwt.MapClaims) (string, error) {
+    jti, err := randomString(16)
+    if err != nil {
+        return "", err
     }
+    now := time.Now()
+    claims["typ"] = typ
+    claims["jti"] = jti // ログアウト時の失効リスト用
+    claims["iat"] = now.Unix()
+    claims["exp"] = now.Add(ttl).Unix()
 
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
     return token.SignedString([]byte(os.Getenv("JWT_SECRET_KEY")))
-}
\ No newline at end of file
+}
+
+// ParseToken はトークンの署名と有効期限を検証し、Claimsを返します
+func ParseToken(tokenString string) (jwt.MapClaims, error) {
+    token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+        // 署名アルゴリズムの確認
+        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+            return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+        }
+        return []byte(os.Getenv("JWT_SECRET_KEY")), nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    claims, ok := token.Claims.(jwt.MapClaims)
+    if !ok || !token.Valid {
+        return nil, fmt.Errorf("invalid token")
+    }
+    return claims, nil
+}
+
+// GenerateRefreshToken はリフレッシュトークンを発行します。
+// plain はクライアントに返す値、hash はDBに保存する値（平文は保存しない）。
+func GenerateRefreshToken() (plain string, hash string, err error) {
+    return GenerateOpaqueToken()
+}
+
+// GenerateOpaqueToken は推測できないランダムなトークンと、そのハッシュを返します。
+// 予約確認用のリンクなど、URLに含めるトークンにも使う。
+func GenerateOpaqueToken() (plain string, hash string, err error) {
+    plain, err = randomString(32)
+    if err != nil {
+        return "", "", err
+    }
+    return plain, HashToken(plain), nil
+}
+
+// HashToken はトークンをDB保存・照合用に SHA-256 でハッシュ化します
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+    b := make([]byte, n)
+    if _, err := rand.Read(b); err != nil {
+        return "", err
+    }
+    return base64.RawURLEncoding.EncodeToString(b), nil
+}
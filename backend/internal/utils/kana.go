@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeSearchText は検索で比べられるように文字列をそろえる。
+// NFKC で全角英数字を半角に、半角カナを全角に（濁点も結合）し、ひらがなをカタカナに、英字を小文字にして、空白を取り除く。
+func NormalizeSearchText(s string) string {
+	s = HiraganaToKatakana(norm.NFKC.String(s))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// PhoneDigits は電話番号の数字だけを取り出す（全角数字も数字として扱い、ハイフン・空白・括弧は無視する）
+func PhoneDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, norm.NFKC.String(s))
+}
+
+// HiraganaToKatakana はひらがなをカタカナに変換する（ゝゞ も ヽヾ にする）
+func HiraganaToKatakana(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'ぁ' && r <= 'ゖ') || r == 'ゝ' || r == 'ゞ' {
+			return r + 0x60
+		}
+		return r
+	}, s)
+}
+
+// NormalizeKana はフリガナを正規の形（全角ひらがな）にそろえる。
+// 半角カナ・カタカナはひらがなにし、前後の空白を取り除き、途中の空白（全角を含む）は半角1つにまとめる。
+// ひらがなにできない文字（漢字・英字・記号など。長音記号「ー」と中黒「・」は可）が含まれていれば ok は false。
+func NormalizeKana(s string) (kana string, ok bool) {
+	s = strings.Join(strings.Fields(norm.NFKC.String(s)), " ")
+	ok = true
+	kana = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'ァ' && r <= 'ヶ', r == 'ヽ', r == 'ヾ':
+			r -= 0x60
+		case r >= 'ぁ' && r <= 'ゖ', r == 'ゝ', r == 'ゞ', r == 'ー', r == '・', r == ' ':
+		default:
+			ok = false
+		}
+		return r
+	}, s)
+	return kana, ok
+}
@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// JST は日本標準時。コンテナに tzdata が無くても動くよう固定オフセットで定義する。
+var JST = time.FixedZone("Asia/Tokyo", 9*60*60)
+
+// ParseTimeParam はクエリパラメータの日時を解釈します。
+// RFC3339 ("2025-04-01T10:00:00+09:00") と日付のみ ("2025-04-01"、JSTの0時) を受け付ける。
+func ParseTimeParam(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, JST); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time: %q", s)
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"strings"
+	"sync"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -85,4 +86,19 @@ func CheckPassword(password, encodedHash string) (bool, error) {
 		return true, nil
 	}
 	return false, nil
-}
\ No newline at end of file
+}
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// DummyPasswordHash は存在しないユーザーに対して CheckPassword を実行するためのハッシュを返します。
+// ユーザーの有無で処理時間が変わらないようにし、メールアドレスの存在を推測されないようにします。
+func DummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		b := make([]byte, 16)
+		rand.Read(b)
+		dummyHash, _ = HashPassword(base64.RawStdEncoding.EncodeToString(b))
+	})
+	return dummyHash
+}
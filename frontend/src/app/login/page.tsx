@@ -38,6 +38,7 @@ export default function LoginPage() {
             const data = await response.json();
             if (!response.ok) throw new Error("ログインに失敗しました");
             // --- 成功時の処理 ---
+            localStorage.setItem("refresh_token", data.refresh_token);
             login(data.token);
             // ダッシュボードへ移動
             router.push("/dashboard");
@@ -29,6 +29,8 @@ export default function VisitRegistrationPage() {
         courseId: "",
         memo: "",
     });
+    // 二重送信防止用のキー（登録に成功したら新しくする）
+    const [idempotencyKey, setIdempotencyKey] = useState(() => crypto.randomUUID());
 
     const handleSubmit = async (e: React.FormEvent) => {
         e.preventDefault();
@@ -40,14 +42,12 @@ export default function VisitRegistrationPage() {
             course_id: Number(formData.courseId),
             store_id: Number(formData.storeId),
             memo: formData.memo,
-            visit_count: 1, // 基本は1回消化
-            ticket_id: null, // 必要に応じて拡張
         };
 
         try {
             const res = await authFetch("/visit-registration", {
                 method: "POST",
-                headers: { "Content-Type": "application/json" },
+                headers: { "Content-Type": "application/json", "Idempotency-Key": idempotencyKey },
                 body: JSON.stringify(payload),
             });
 
@@ -58,6 +58,7 @@ export default function VisitRegistrationPage() {
                 // フォームのリセット
                 setSelectedCustomer(null);
                 setFormData({ storeId: "", courseId: "", memo: "" });
+                setIdempotencyKey(crypto.randomUUID());
             } else {
                 const errData = await res.json();
                 alert(`エラー: ${errData.error || "登録に失敗しました"}`);
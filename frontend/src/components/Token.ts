@@ -1,12 +1,37 @@
+// リフレッシュトークンでアクセストークンを再発行する（成功したら true）
+const refreshAccessToken = async (): Promise<boolean> => {
+    const refreshToken = localStorage.getItem("refresh_token");
+    if (!refreshToken) return false;
+    const res = await fetch(`${process.env.NEXT_PUBLIC_API_URL}/api/v0/refresh`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+    });
+    if (!res.ok) {
+        localStorage.removeItem("token");
+        localStorage.removeItem("refresh_token");
+        return false;
+    }
+    const data = await res.json();
+    localStorage.setItem("token", data.token);
+    localStorage.setItem("refresh_token", data.refresh_token);
+    return true;
+};
+
 export const authFetch = async (url: string, options: any = {}) => {
-    const token = localStorage.getItem("token");
     const apiUrl = `${process.env.NEXT_PUBLIC_API_URL}/api/v1${url.startsWith('/') ? '' : '/'}${url}`;
-    return fetch(apiUrl, {
+    const send = () => fetch(apiUrl, {
         ...options,
         headers: {
             ...options.headers,
-            "Authorization": `Bearer ${token}`, // これを自動で追加！
+            "Authorization": `Bearer ${localStorage.getItem("token")}`, // これを自動で追加！
             "Content-Type": "application/json",
         },
     });
-};
\ No newline at end of file
+    const res = await send();
+    // アクセストークンの期限切れ時は一度だけ再発行してリトライ
+    if (res.status === 401 && await refreshAccessToken()) {
+        return send();
+    }
+    return res;
+};
@@ -55,6 +55,7 @@ export function AuthProvider({ children }: { children: ReactNode }) {
 
     const logout = () => {
         localStorage.removeItem("token");
+        localStorage.removeItem("refresh_token");
         setUser(null);
     };
 